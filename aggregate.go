@@ -0,0 +1,173 @@
+package xtjson
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// Agg is the set of aggregate functions, functions can be combined like AggSum|AggAvg
+type Agg int
+
+const (
+	AggCount Agg = 1 << iota
+	AggSum
+	AggAvg
+	AggMin
+	AggMax
+	AggMedian
+	AggP90
+	AggP95
+	AggP99
+)
+
+var aggNames = []struct {
+	agg        Agg
+	name       string
+	percentile float64
+}{
+	{AggCount, "count", 0},
+	{AggSum, "sum", 0},
+	{AggAvg, "avg", 0},
+	{AggMin, "min", 0},
+	{AggMax, "max", 0},
+	{AggMedian, "p50", 50},
+	{AggP90, "p90", 90},
+	{AggP95, "p95", 95},
+	{AggP99, "p99", 99},
+}
+
+// AggregateOptions provides settings for aggregation
+type AggregateOptions struct {
+	// Strict makes aggregation fail on non numeric values instead of skipping them,
+	// missing values are skipped in any case
+	Strict bool
+	// Percentiles adds arbitrary percentiles in range 0-100 to result with keys like p75,
+	// percentiles repeated or requested by Agg like 50 with AggMedian are rejected
+	Percentiles []float64
+}
+
+func percentileKey(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+// numbers collects numeric values referenced by path, following the Numbers conventions
+func (ns Nodes) numbers(path string, strict bool) ([]float64, error) {
+	ret := make([]float64, 0, len(ns))
+	for _, node := range ns {
+		value := node.Path(path)
+		v, err := value.Number()
+		if err == nil {
+			ret = append(ret, v)
+			continue
+		}
+		if strict && errors.Is(err, ErrValueIsNotNumber) {
+			return nil, fmt.Errorf("%w: %s", err, value.SelfPath())
+		}
+	}
+	return ret, nil
+}
+
+// percentile calculates percentile of sorted values using linear interpolation between closest ranks
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// Aggregate calculates aggregate functions over numeric values referenced by path, $ refers to node itself.
+// The result is an object with keys count, sum, avg, min, max, p50, p90, p95, p99 for requested functions,
+// functions which are not defined for the empty set have null values
+func (ns Nodes) Aggregate(path string, agg Agg, opt *AggregateOptions) (*Node, error) {
+	if opt == nil {
+		opt = &AggregateOptions{}
+	}
+	keys := make(map[string]bool)
+	for _, an := range aggNames {
+		if agg&an.agg != 0 {
+			keys[an.name] = true
+		}
+	}
+	for _, p := range opt.Percentiles {
+		if p < 0 || p > 100 || math.IsNaN(p) {
+			return nil, fmt.Errorf("%w: percentile %v", ErrInvalidNodeForOperation, p)
+		}
+		key := percentileKey(p)
+		if keys[key] {
+			return nil, fmt.Errorf("%w: percentile %s is already requested", ErrInvalidNodeForOperation, key)
+		}
+		keys[key] = true
+	}
+	values, err := ns.numbers(path, opt.Strict)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+
+	ret := NewObject()
+	put := func(key string, node *Node) {
+		if err := ret.Set(key, node); err != nil {
+			panic(err)
+		}
+	}
+	// stat returns null for the functions not defined on empty set
+	stat := func(value func() float64) *Node {
+		if len(values) == 0 {
+			return NewNull()
+		}
+		return NewNumber(value())
+	}
+	for _, an := range aggNames {
+		if agg&an.agg == 0 {
+			continue
+		}
+		switch an.agg {
+		case AggCount:
+			put(an.name, NewInt(len(values)))
+		case AggSum:
+			put(an.name, NewNumber(sum))
+		case AggAvg:
+			put(an.name, stat(func() float64 { return sum / float64(len(values)) }))
+		case AggMin:
+			put(an.name, stat(func() float64 { return values[0] }))
+		case AggMax:
+			put(an.name, stat(func() float64 { return values[len(values)-1] }))
+		default:
+			put(an.name, stat(func() float64 { return percentile(values, an.percentile) }))
+		}
+	}
+	for _, p := range opt.Percentiles {
+		put(percentileKey(p), stat(func() float64 { return percentile(values, p) }))
+	}
+	return ret, nil
+}
+
+// Aggregate calculates aggregate functions for each group, the result is an object
+// with group keys in alphabetical order and aggregation objects as values.
+// Keys are written as GroupBy makes them, like {"east":...} for string values
+// or {"1":...} for numbers
+func (g Groups) Aggregate(path string, agg Agg, opt *AggregateOptions) (*Node, error) {
+	keys := make([]string, 0, len(g))
+	for key := range g {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	ret := NewObject()
+	for _, key := range keys {
+		node, err := g[key].Aggregate(path, agg, opt)
+		if err != nil {
+			return nil, err
+		}
+		if err = ret.Set(key, node); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
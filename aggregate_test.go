@@ -0,0 +1,67 @@
+package xtjson
+
+import (
+	"errors"
+	"testing"
+)
+
+const salesJson = `[
+	{"region":"north","amount":10},
+	{"region":"south","amount":4},
+	{"region":"north","amount":30},
+	{"region":"north","amount":"n/a"},
+	{"region":"south"},
+	{"region":"north","amount":20},
+	{"region":"east","amount":null}
+]`
+
+func TestAggregate(t *testing.T) {
+	ns := parseNodes(t, salesJson)
+	result, err := ns.Aggregate("$.amount", AggCount|AggSum|AggAvg|AggMin|AggMax, nil)
+	assertNil(t, err)
+	assertEqual(t, `{"count":4,"sum":64,"avg":16,"min":4,"max":30}`, result.Stringify())
+
+	result, err = ns.Aggregate("$.amount", AggMedian|AggP90, &AggregateOptions{Percentiles: []float64{25, 62.5}})
+	assertNil(t, err)
+	assertEqual(t, `{"p50":15,"p90":27,"p25":8.5,"p62.5":18.75}`, result.Stringify())
+
+	result, err = parseNodes(t, `[1,2,3]`).Aggregate("$", AggSum|AggP99, nil)
+	assertNil(t, err)
+	assertEqual(t, `{"sum":6,"p99":2.98}`, result.Stringify())
+
+	result, err = ns.Aggregate("$.missing", AggCount|AggSum|AggAvg|AggMin|AggMax|AggP95, nil)
+	assertNil(t, err)
+	assertEqual(t, `{"count":0,"sum":0,"avg":null,"min":null,"max":null,"p95":null}`, result.Stringify())
+
+	_, err = ns.Aggregate("$.amount", AggSum, &AggregateOptions{Strict: true})
+	if !errors.Is(err, ErrValueIsNotNumber) {
+		t.Fatal("expected error ErrValueIsNotNumber")
+	}
+	assertEqual(t, "value is not number: $[3].amount", err.Error())
+
+	for _, percentiles := range [][]float64{{101}, {50}, {90.0}, {25, 25}} {
+		_, err = ns.Aggregate("$.amount", AggSum|AggMedian|AggP90, &AggregateOptions{Percentiles: percentiles})
+		if !errors.Is(err, ErrInvalidNodeForOperation) {
+			t.Fatalf("expected error ErrInvalidNodeForOperation for %v", percentiles)
+		}
+	}
+	result, err = ns.Aggregate("$.amount", AggSum, &AggregateOptions{Percentiles: []float64{50}})
+	assertNil(t, err)
+	assertEqual(t, `{"sum":64,"p50":15}`, result.Stringify())
+}
+
+func TestGroupsAggregate(t *testing.T) {
+	ns := parseNodes(t, salesJson)
+	result, err := ns.GroupBy("$.region").Aggregate("$.amount", AggCount|AggSum|AggMax, nil)
+	assertNil(t, err)
+	assertEqual(t, `{"east":{"count":0,"sum":0,"max":null},"north":{"count":3,"sum":60,"max":30},"south":{"count":1,"sum":4,"max":4}}`, result.Stringify())
+
+	result, err = ns.GroupBy("$.amount").Aggregate("$.amount", AggCount, nil)
+	assertNil(t, err)
+	assertEqual(t, `{"":{"count":0},"10":{"count":1},"20":{"count":1},"30":{"count":1},"4":{"count":1},"n/a":{"count":0},"null":{"count":0}}`, result.Stringify())
+
+	_, err = ns.GroupBy("$.region").Aggregate("$.amount", AggSum, &AggregateOptions{Strict: true})
+	if !errors.Is(err, ErrValueIsNotNumber) {
+		t.Fatal("expected error ErrValueIsNotNumber")
+	}
+}
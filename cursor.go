@@ -0,0 +1,232 @@
+package xtjson
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// walkCursor keeps path, json pointer and level of the current walker node,
+// marks store lengths of path and pointer before the last segments were added
+type walkCursor struct {
+	path    []byte
+	pointer []byte
+	marks   [][2]int
+	depth   int
+}
+
+// reset builds the cursor for node from the root of the tree
+func (c *walkCursor) reset(node *Node) {
+	var chain []*Node
+	for ; node.parent != nil; node = node.parent {
+		chain = append(chain, node)
+	}
+	c.path = append(c.path[:0], '$')
+	c.pointer = c.pointer[:0]
+	c.marks = c.marks[:0]
+	c.depth = 0
+	for _, node := range slices.Backward(chain) {
+		c.push(node)
+	}
+}
+
+func (c *walkCursor) push(node *Node) {
+	c.marks = append(c.marks, [2]int{len(c.path), len(c.pointer)})
+	c.path = append(c.path, node.pathSegment()...)
+	c.pointer = append(c.pointer, '/')
+	c.pointer = append(c.pointer, node.pointerToken()...)
+	c.depth++
+}
+
+func (c *walkCursor) pop() {
+	if len(c.marks) == 0 {
+		return
+	}
+	mark := c.marks[len(c.marks)-1]
+	c.marks = c.marks[:len(c.marks)-1]
+	c.path = c.path[:mark[0]]
+	c.pointer = c.pointer[:mark[1]]
+	c.depth--
+}
+
+// set copies precomputed breadth first item position, marks are not used in this mode
+func (c *walkCursor) set(item walkItem) {
+	c.path = append(c.path[:0], item.path...)
+	c.pointer = append(c.pointer[:0], item.pointer...)
+	c.depth = item.level
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// pointerToken returns escaped json pointer reference token of node in its parent
+func (n *Node) pointerToken() string {
+	if n.parent.IsArray() {
+		return strconv.Itoa(n.idx)
+	}
+	return pointerEscaper.Replace(n.key)
+}
+
+// pointerOf returns json pointer of node from the root of the tree
+func pointerOf(node *Node) string {
+	ret := ""
+	for ; node.parent != nil; node = node.parent {
+		ret = "/" + node.pointerToken() + ret
+	}
+	return ret
+}
+
+// relativePointer returns json pointer of node relative to its ancestor root
+func relativePointer(root, node *Node) string {
+	var ret []string
+	for ; node != root; node = node.parent {
+		ret = append(ret, node.pointerToken())
+	}
+	if len(ret) == 0 {
+		return ""
+	}
+	slices.Reverse(ret)
+	return "/" + strings.Join(ret, "/")
+}
+
+// RelativePointer returns json pointer (RFC 6901) of to node relative to from node,
+// empty string is returned when to is not descendant of from or is the same node
+func RelativePointer(from, to *Node) string {
+	if !from.Exists() || !to.Exists() || from != to && !from.IsAncestorOf(to) {
+		return ""
+	}
+	return relativePointer(from, to)
+}
+
+// ResolvePointer returns the node referenced by json pointer (RFC 6901) relative to receiver
+func (n *Node) ResolvePointer(pointer string) (*Node, error) {
+	if !n.Exists() {
+		return nil, ErrNilNode
+	}
+	return resolvePointer(n, pointer)
+}
+
+// resolvePointer returns the node referenced by json pointer relative to root
+func resolvePointer(root *Node, pointer string) (*Node, error) {
+	if pointer == "" {
+		return root, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("%w: %s", ErrBadPath, pointer)
+	}
+	node := root
+	for _, token := range strings.Split(pointer[1:], "/") {
+		switch {
+		case node.IsArray():
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || strconv.Itoa(idx) != token {
+				return nil, fmt.Errorf("%w: %s", ErrBadPath, pointer)
+			}
+			node = node.Idx(idx)
+		case node.IsObject():
+			node = node.Key(pointerUnescaper.Replace(token))
+		default:
+			node = undef
+		}
+		if !node.Exists() {
+			return nil, fmt.Errorf("%w: %s", ErrNodeDoesNotExist, pointer)
+		}
+	}
+	return node, nil
+}
+
+// WalkerFrame is the node waiting in breadth first queue or post order stack of walker
+type WalkerFrame struct {
+	Pointer string `json:"pointer"`
+	Pos     int    `json:"pos,omitempty"`
+}
+
+// WalkerSnapshot is the position of Walker which allows to resume the walk later.
+// Nodes are referenced by json pointers relative to the root, so the snapshot can be
+// encoded and used with the same tree parsed again. Root is not encoded and has to be
+// set after decoding. The tree must not be modified before the walk is resumed.
+type WalkerSnapshot struct {
+	Root      *Node         `json:"-"`
+	DeepLimit int           `json:"deepLimit,omitempty"`
+	Options   WalkOptions   `json:"options"`
+	Next      string        `json:"next,omitempty"` // depth first node returned by next call
+	State     WalkState     `json:"state"`
+	Frames    []WalkerFrame `json:"frames,omitempty"` // breadth first queue or post order stack
+}
+
+// relPointer returns json pointer of node relative to walker root
+func (w *Walker) relPointer(node *Node) string {
+	return relativePointer(w.root, node)
+}
+
+// Snapshot returns the position of walker, Skip can not be applied
+// to the node returned before snapshot after the walk is resumed
+func (w *Walker) Snapshot() *WalkerSnapshot {
+	if w == nil {
+		return nil
+	}
+	ret := &WalkerSnapshot{Root: w.root, Options: w.opt, State: w.nextState}
+	if w.deepLimit > 0 {
+		ret.DeepLimit = w.deepLimit - w.rootLevel
+	}
+	if w.nextState == WalkDone {
+		return ret
+	}
+	switch w.opt.Order {
+	case WalkDepthFirst:
+		ret.Next = w.relPointer(w.next)
+	case WalkBreadthFirst:
+		for _, item := range w.queue {
+			ret.Frames = append(ret.Frames, WalkerFrame{Pointer: w.relPointer(item.node)})
+		}
+		if w.pending != nil {
+			for i := range w.pending.node.children {
+				ret.Frames = append(ret.Frames, WalkerFrame{Pointer: w.relPointer(w.child(w.pending.node, i))})
+			}
+		}
+	default:
+		for _, item := range w.queue {
+			ret.Frames = append(ret.Frames, WalkerFrame{Pointer: w.relPointer(item.node), Pos: item.pos})
+		}
+	}
+	return ret
+}
+
+// NewWalkerFrom creates Walker continuing the walk from snapshot
+func NewWalkerFrom(snapshot *WalkerSnapshot) (*Walker, error) {
+	if snapshot == nil {
+		return nil, ErrNilNode
+	}
+	w, err := NewWalker(snapshot.Root, snapshot.DeepLimit, &snapshot.Options)
+	if err != nil {
+		return nil, err
+	}
+	w.nextState = snapshot.State
+	if w.nextState == WalkDone {
+		w.queue = nil
+		return w, nil
+	}
+	if w.opt.Order == WalkDepthFirst {
+		if w.next, err = resolvePointer(w.root, snapshot.Next); err != nil {
+			return nil, err
+		}
+		return w, nil
+	}
+	w.queue = make([]walkItem, 0, len(snapshot.Frames))
+	for _, frame := range snapshot.Frames {
+		node, err := resolvePointer(w.root, frame.Pointer)
+		if err != nil {
+			return nil, err
+		}
+		item := walkItem{node: node, level: w.rootLevel + strings.Count(frame.Pointer, "/"), pos: frame.Pos}
+		if w.opt.Order == WalkBreadthFirst {
+			item.path, item.pointer = node.SelfPath(), pointerOf(node)
+		}
+		w.queue = append(w.queue, item)
+	}
+	if len(w.queue) > 0 && w.opt.Order != WalkBreadthFirst {
+		w.cursor.reset(w.queue[len(w.queue)-1].node)
+	}
+	return w, nil
+}
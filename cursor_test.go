@@ -0,0 +1,168 @@
+package xtjson
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const cursorJson = `{"a":{"b":1,"c/d":[2,{"e~f":3}]},"g":[],"h":[[4],5]}`
+
+func allOrders() []*WalkOptions {
+	var ret []*WalkOptions
+	for _, order := range []WalkOrder{WalkDepthFirst, WalkBreadthFirst, WalkPostOrder, WalkLeaves} {
+		ret = append(ret, &WalkOptions{Order: order}, &WalkOptions{Order: order, Reverse: true})
+	}
+	return ret
+}
+
+func TestWalkerCursor(t *testing.T) {
+	root, err := ParseString(cursorJson)
+	assertParsed(t, root, err)
+	for _, start := range []*Node{root, root.Path("$.a")} {
+		for _, opt := range allOrders() {
+			for _, deepLimit := range []int{0, 2} {
+				walker, err := NewWalker(start, deepLimit, opt)
+				assertNil(t, err)
+				assertEqual(t, "", walker.Path())
+				for {
+					node, state := walker.Next()
+					if state == WalkDone {
+						break
+					}
+					assertEqual(t, node.SelfPath(), walker.Path())
+					assertEqual(t, pointerOf(node), walker.Pointer())
+					assertEqual(t, node.Level(), walker.Depth())
+				}
+				assertEqual(t, "", walker.Pointer())
+				assertEqual(t, 0, walker.Depth())
+			}
+		}
+	}
+	walker, err := NewWalker(root, 0)
+	assertNil(t, err)
+	for range 6 {
+		walker.Next()
+	}
+	assertEqual(t, "$.a.c/d[1]", walker.Path())
+	assertEqual(t, "/a/c~1d/1", walker.Pointer())
+	assertEqual(t, 3, walker.Depth())
+}
+
+type walkStep struct {
+	node  *Node
+	state WalkState
+}
+
+func walkRest(w *Walker) []walkStep {
+	var ret []walkStep
+	for {
+		node, state := w.Next()
+		if state == WalkDone {
+			return ret
+		}
+		ret = append(ret, walkStep{node, state})
+	}
+}
+
+func TestWalkerSnapshot(t *testing.T) {
+	root, err := ParseString(cursorJson)
+	assertParsed(t, root, err)
+	for _, opt := range allOrders() {
+		for _, deepLimit := range []int{0, 2} {
+			walker, err := NewWalker(root, deepLimit, opt)
+			assertNil(t, err)
+			expected := walkRest(walker)
+			for stop := 0; stop <= len(expected); stop++ {
+				walker, err = NewWalker(root, deepLimit, opt)
+				assertNil(t, err)
+				for range stop {
+					walker.Next()
+				}
+				data, err := json.Marshal(walker.Snapshot())
+				assertNil(t, err)
+
+				// resume on the same tree parsed again
+				copied, err := ParseString(cursorJson)
+				assertParsed(t, copied, err)
+				var snapshot WalkerSnapshot
+				assertNil(t, json.Unmarshal(data, &snapshot))
+				snapshot.Root = copied
+				resumed, err := NewWalkerFrom(&snapshot)
+				assertNil(t, err)
+				actual := walkRest(resumed)
+				assertEqual(t, len(expected)-stop, len(actual))
+				for i, step := range actual {
+					assertEqual(t, expected[stop+i].node.SelfPath(), step.node.SelfPath())
+					assertEqual(t, expected[stop+i].state, step.state)
+				}
+			}
+		}
+	}
+}
+
+func TestWalkerSnapshotResumeCursor(t *testing.T) {
+	root, err := ParseString(cursorJson)
+	assertParsed(t, root, err)
+	walker, err := NewWalker(root.Key("h"), 0, &WalkOptions{Order: WalkPostOrder})
+	assertNil(t, err)
+	walker.Next()
+	resumed, err := NewWalkerFrom(walker.Snapshot())
+	assertNil(t, err)
+	node, _ := resumed.Next()
+	assertEqual(t, "$.h[0]", node.SelfPath())
+	assertEqual(t, "$.h[0]", resumed.Path())
+	assertEqual(t, "/h/0", resumed.Pointer())
+	assertEqual(t, 2, resumed.Depth())
+}
+
+func TestNewWalkerFromErrors(t *testing.T) {
+	root, err := ParseString(cursorJson)
+	assertParsed(t, root, err)
+	_, err = NewWalkerFrom(nil)
+	if !errors.Is(err, ErrNilNode) {
+		t.Fatal("expected error ErrNilNode")
+	}
+	_, err = NewWalkerFrom(&WalkerSnapshot{Next: "/a"})
+	if !errors.Is(err, ErrNilNode) {
+		t.Fatal("expected error ErrNilNode")
+	}
+	_, err = NewWalkerFrom(&WalkerSnapshot{Root: root, Next: "/x"})
+	if !errors.Is(err, ErrNodeDoesNotExist) {
+		t.Fatal("expected error ErrNodeDoesNotExist")
+	}
+	_, err = NewWalkerFrom(&WalkerSnapshot{Root: root, Next: "a"})
+	if !errors.Is(err, ErrBadPath) {
+		t.Fatal("expected error ErrBadPath")
+	}
+	_, err = NewWalkerFrom(&WalkerSnapshot{Root: root, Options: WalkOptions{Order: WalkBreadthFirst}, Frames: []WalkerFrame{{Pointer: "/h/01"}}})
+	if !errors.Is(err, ErrBadPath) {
+		t.Fatal("expected error ErrBadPath")
+	}
+}
+
+func TestResolvePointer(t *testing.T) {
+	root, err := ParseString(cursorJson)
+	assertParsed(t, root, err)
+	node, err := resolvePointer(root, "/a/c~1d/1/e~0f")
+	assertNil(t, err)
+	assertEqual(t, 3.0, node.value)
+	assertEqual(t, "/a/c~1d/1/e~0f", pointerOf(node))
+	_, err = resolvePointer(root, "/a/b/x")
+	if !errors.Is(err, ErrNodeDoesNotExist) {
+		t.Fatal("expected error ErrNodeDoesNotExist")
+	}
+
+	a := root.Key("a")
+	node, err = a.ResolvePointer("/c~1d/1")
+	assertNil(t, err)
+	assertEqual(t, "/c~1d/1", RelativePointer(a, node))
+	assertEqual(t, "/a/c~1d/1", RelativePointer(root, node))
+	assertEqual(t, "", RelativePointer(a, a))
+	assertEqual(t, "", RelativePointer(node, a))
+	assertEqual(t, "", RelativePointer(root, NewNull()))
+	_, err = undef.ResolvePointer("")
+	if !errors.Is(err, ErrNilNode) {
+		t.Fatal("expected error ErrNilNode")
+	}
+}
@@ -0,0 +1,244 @@
+package xtjson
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrFlatConflict = errors.New("conflicting flat keys")
+
+// ArrayNotation defines how array indexes are written in flat keys
+type ArrayNotation int
+
+const (
+	// ArrayBrackets writes indexes like a[0].b, the same way as SelfPath does
+	ArrayBrackets ArrayNotation = iota
+	// ArrayIndexKeys writes indexes as keys like a.0.b, numeric object keys are written as a['0']
+	ArrayIndexKeys
+)
+
+// FlattenOptions defines the format of flat keys. With default options the key is
+// the SelfPath of the value without leading $ and dot, like a.b[0]['c.d'].
+type FlattenOptions struct {
+	Separator string        `json:"separator,omitempty"` // "." when empty, must not contain brackets and quotes
+	Arrays    ArrayNotation `json:"arrays,omitempty"`
+	MaxDepth  int           `json:"maxDepth,omitempty"` // containers deeper are kept as values, 0 means unlimited
+}
+
+func (opt *FlattenOptions) validate() error {
+	if strings.ContainsAny(opt.Separator, "[]'") {
+		return fmt.Errorf("%w: separator %s", ErrBadPath, opt.Separator)
+	}
+	return nil
+}
+
+func (opt *FlattenOptions) separator() string {
+	if opt.Separator == "" {
+		return "."
+	}
+	return opt.Separator
+}
+
+// Flatten returns object which keys are paths of scalars and empty containers of root
+// and values are their copies. Keys are escaped with bracket notation like ['a.b']
+// so Unflatten with the same options restores the tree.
+func Flatten(root *Node, opt *FlattenOptions) (*Node, error) {
+	if opt == nil {
+		opt = &FlattenOptions{}
+	}
+	if err := opt.validate(); err != nil {
+		return nil, err
+	}
+	ret := NewObject()
+	if root.Exists() {
+		if err := flattenInto(ret, root, "", 0, opt); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+func flattenInto(ret *Node, node *Node, prefix string, depth int, opt *FlattenOptions) error {
+	if len(node.children) == 0 || opt.MaxDepth > 0 && depth >= opt.MaxDepth {
+		return ret.Set(prefix, detach(node))
+	}
+	sep := opt.separator()
+	for i, child := range node.children {
+		var segment string
+		switch {
+		case node.IsObject():
+			segment = keySegment(child.key, sep)
+			if opt.Arrays == ArrayIndexKeys && isIndexKey(child.key) {
+				segment = "['" + child.key + "']"
+			}
+		case opt.Arrays == ArrayIndexKeys:
+			segment = sep + strconv.Itoa(i)
+		default:
+			segment = "[" + strconv.Itoa(i) + "]"
+		}
+		if prefix == "" {
+			segment = strings.TrimPrefix(segment, sep)
+		}
+		if err := flattenInto(ret, child, prefix+segment, depth+1, opt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isIndexKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseFlatKey splits flat key to path tokens
+func parseFlatKey(key string, opt *FlattenOptions) ([]pathToken, error) {
+	sep := []rune(opt.separator())
+	rs := []rune(key)
+	var tokens []pathToken
+	for i := 0; i < len(rs); {
+		if rs[i] == '[' {
+			if i+1 < len(rs) && rs[i+1] == '\'' {
+				k, next, err := parseQuotedKey(rs, i+2)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %s in %s", ErrBadPath, err.Error(), key)
+				}
+				tokens = append(tokens, pathToken{key: k})
+				i = next
+				continue
+			}
+			j := i + 1
+			for j < len(rs) && rs[j] != ']' {
+				j++
+			}
+			if j == len(rs) {
+				return nil, fmt.Errorf("%w: unclosed bracket in %s", ErrBadPath, key)
+			}
+			idx, err := strconv.Atoi(string(rs[i+1 : j]))
+			if err != nil || idx < 0 {
+				return nil, fmt.Errorf("%w: invalid index in %s", ErrBadPath, key)
+			}
+			tokens = append(tokens, pathToken{idx: idx, isIdx: true})
+			i = j + 1
+			continue
+		}
+		if i > 0 {
+			if !hasRunePrefix(rs[i:], sep) {
+				return nil, fmt.Errorf("%w: separator expected in %s", ErrBadPath, key)
+			}
+			i += len(sep)
+		}
+		j := i
+		for j < len(rs) && rs[j] != '[' && rs[j] != ']' && !hasRunePrefix(rs[j:], sep) {
+			j++
+		}
+		if j == i {
+			return nil, fmt.Errorf("%w: empty key in %s", ErrBadPath, key)
+		}
+		segment := string(rs[i:j])
+		if opt.Arrays == ArrayIndexKeys && isIndexKey(segment) {
+			idx, err := strconv.Atoi(segment)
+			if err != nil || strconv.Itoa(idx) != segment {
+				return nil, fmt.Errorf("%w: invalid index in %s", ErrBadPath, key)
+			}
+			tokens = append(tokens, pathToken{idx: idx, isIdx: true})
+		} else {
+			tokens = append(tokens, pathToken{key: segment})
+		}
+		i = j
+	}
+	return tokens, nil
+}
+
+func hasRunePrefix(rs, prefix []rune) bool {
+	if len(rs) < len(prefix) {
+		return false
+	}
+	for i, r := range prefix {
+		if rs[i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// Unflatten builds the tree from flat object made by Flatten with the same options.
+// Missing array elements are filled by nulls, keys referencing the same node
+// or a child of scalar are reported as ErrFlatConflict.
+func Unflatten(flat *Node, opt *FlattenOptions) (*Node, error) {
+	if !flat.IsObject() {
+		return nil, fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "unflatten")
+	}
+	if opt == nil {
+		opt = &FlattenOptions{}
+	}
+	if err := opt.validate(); err != nil {
+		return nil, err
+	}
+	if len(flat.children) == 0 {
+		return NewObject(), nil
+	}
+	var root *Node
+	padding := make(map[*Node]bool)
+	for _, entry := range flat.children {
+		tokens, err := parseFlatKey(entry.key, opt)
+		if err != nil {
+			return nil, err
+		}
+		if len(tokens) == 0 {
+			if len(flat.children) > 1 {
+				return nil, fmt.Errorf("%w: root value with other keys", ErrFlatConflict)
+			}
+			return detach(entry), nil
+		}
+		if root == nil {
+			root = tokens[0].newContainer()
+		}
+		if err = unflattenEntry(root, tokens, detach(entry), padding); err != nil {
+			return nil, fmt.Errorf("%w: %s (%s)", ErrFlatConflict, entry.key, err.Error())
+		}
+	}
+	return root, nil
+}
+
+// unflattenEntry sets value at the path of tokens, padding keeps nulls added to fill arrays
+// which may be replaced by later entries unlike nulls coming from the flat object
+func unflattenEntry(root *Node, tokens []pathToken, value *Node, padding map[*Node]bool) error {
+	node := root
+	last := len(tokens) - 1
+	for i, token := range tokens[:last] {
+		next := token.child(node)
+		if !next.Exists() || padding[next] {
+			next = tokens[i+1].newContainer()
+			if err := linkPadded(node, token, next, padding); err != nil {
+				return err
+			}
+		}
+		node = next
+	}
+	if existing := tokens[last].child(node); existing.Exists() && !padding[existing] {
+		return errors.New("value is already set")
+	}
+	return linkPadded(node, tokens[last], value, padding)
+}
+
+func linkPadded(parent *Node, token pathToken, node *Node, padding map[*Node]bool) error {
+	size := len(parent.children)
+	delete(padding, token.child(parent))
+	if err := token.link(parent, node, true); err != nil {
+		return err
+	}
+	for i := size; i < token.idx; i++ {
+		padding[parent.children[i]] = true
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package xtjson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const flattenJson = `{"a":{"b":[1,{"c":true}],"e":{}},"x.y":{"[z]":null,"":"empty","it's":"q"},"0":[[],"s"],"w\\":{"__":1}}`
+
+func TestFlatten(t *testing.T) {
+	root, err := ParseString(flattenJson)
+	assertParsed(t, root, err)
+	flat, err := Flatten(root, nil)
+	assertNil(t, err)
+	assertEqual(t, `{"a.b[0]":1,"a.b[1].c":true,"a.e":{},"['x.y']['[z]']":null,"['x.y']['']":"empty",`+
+		`"['x.y'].it's":"q","0[0]":[],"0[1]":"s","w\\.__":1}`, flat.Stringify())
+
+	// keys are the paths of values without leading $ and dot
+	for _, entry := range flat.Children() {
+		path := "$" + entry.SelfKey()
+		if !strings.HasPrefix(path, "$[") {
+			path = "$." + entry.SelfKey()
+		}
+		node := root.Path(path)
+		assertEqual(t, path, node.SelfPath())
+		assertEqual(t, true, equalNodes(entry, node))
+	}
+	assertEqual(t, "q", root.Path(`$['x.y']['it\'s']`).value.(string))
+	assertEqual(t, "empty", root.Path(`$['x.y']['']`).value.(string))
+
+	ret, err := Unflatten(flat, nil)
+	assertNil(t, err)
+	assertEqual(t, root.Stringify(), ret.Stringify())
+}
+
+func TestFlattenOptions(t *testing.T) {
+	root, err := ParseString(flattenJson)
+	assertParsed(t, root, err)
+	opt := &FlattenOptions{Separator: "__", Arrays: ArrayIndexKeys}
+	flat, err := Flatten(root, opt)
+	assertNil(t, err)
+	assertEqual(t, `{"a__b__0":1,"a__b__1__c":true,"a__e":{},"x.y['[z]']":null,"x.y['']":"empty",`+
+		`"x.y__it's":"q","['0']__0":[],"['0']__1":"s","w\\['__']":1}`, flat.Stringify())
+	ret, err := Unflatten(flat, opt)
+	assertNil(t, err)
+	assertEqual(t, root.Stringify(), ret.Stringify())
+
+	opt = &FlattenOptions{MaxDepth: 2}
+	flat, err = Flatten(root, opt)
+	assertNil(t, err)
+	assertEqual(t, `{"a.b":[1,{"c":true}],"a.e":{},"['x.y']['[z]']":null,"['x.y']['']":"empty",`+
+		`"['x.y'].it's":"q","0[0]":[],"0[1]":"s","w\\.__":1}`, flat.Stringify())
+	ret, err = Unflatten(flat, opt)
+	assertNil(t, err)
+	assertEqual(t, root.Stringify(), ret.Stringify())
+}
+
+func TestFlattenRoot(t *testing.T) {
+	for _, src := range []string{`5`, `"s"`, `null`, `{}`, `[]`, `[1,[2,[3]]]`, `{"":{"":[]}}`} {
+		root, err := ParseString(src)
+		assertParsed(t, root, err)
+		for _, opt := range []*FlattenOptions{nil, {Arrays: ArrayIndexKeys, Separator: "/"}} {
+			flat, err := Flatten(root, opt)
+			assertNil(t, err)
+			ret, err := Unflatten(flat, opt)
+			assertNil(t, err)
+			assertEqual(t, src, ret.Stringify())
+		}
+	}
+	flat, err := Flatten(parseNode(t, `[1,[2]]`), nil)
+	assertNil(t, err)
+	assertEqual(t, `{"[0]":1,"[1][0]":2}`, flat.Stringify())
+	flat, err = Flatten(nil, nil)
+	assertNil(t, err)
+	assertEqual(t, `{}`, flat.Stringify())
+
+	for _, sep := range []string{"[", "]", "'", "a]b"} {
+		_, err = Flatten(parseNode(t, `{"a":{"b":1}}`), &FlattenOptions{Separator: sep})
+		if !errors.Is(err, ErrBadPath) {
+			t.Fatalf("expected error ErrBadPath for separator %s", sep)
+		}
+	}
+}
+
+func parseNode(t *testing.T, src string) *Node {
+	t.Helper()
+	node, err := ParseString(src)
+	assertParsed(t, node, err)
+	return node
+}
+
+func TestUnflatten(t *testing.T) {
+	ret, err := Unflatten(parseNode(t, `{"a[2].b":1,"a[0]":"x","c.d":true}`), nil)
+	assertNil(t, err)
+	assertEqual(t, `{"a":["x",null,{"b":1}],"c":{"d":true}}`, ret.Stringify())
+
+	// padding nulls are replaced, nulls of the flat object are kept
+	ret, err = Unflatten(parseNode(t, `{"a[2]":null,"a[1].b":1,"a[0]":2}`), nil)
+	assertNil(t, err)
+	assertEqual(t, `{"a":[2,{"b":1},null]}`, ret.Stringify())
+
+	cases := []struct {
+		flat string
+		err  error
+	}{
+		{`{"a":1,"a.b":2}`, ErrFlatConflict},
+		{`{"a.b":1,"a":2}`, ErrFlatConflict},
+		{`{"a":null,"a.b":2}`, ErrFlatConflict},
+		{`{"a[0]":null,"a[0].b":1}`, ErrFlatConflict},
+		{`{"a[1]":1,"a[0]":null,"a[0].b":1}`, ErrFlatConflict},
+		{`{"a":1,"['a']":2}`, ErrFlatConflict},
+		{`{"a":1,"[0]":2}`, ErrFlatConflict},
+		{`{"":1,"a":2}`, ErrFlatConflict},
+		{`{"a..b":1}`, ErrBadPath},
+		{`{".a":1}`, ErrBadPath},
+		{`{"a['b":1}`, ErrBadPath},
+		{`{"a[x]":1}`, ErrBadPath},
+		{`{"a]":1}`, ErrBadPath},
+		{`{"a['b']c":1}`, ErrBadPath},
+	}
+	for _, c := range cases {
+		_, err = Unflatten(parseNode(t, c.flat), nil)
+		if !errors.Is(err, c.err) {
+			t.Fatalf("expected error %v for %s, got %v", c.err, c.flat, err)
+		}
+	}
+	_, err = Unflatten(parseNode(t, `{"a.01":1}`), &FlattenOptions{Arrays: ArrayIndexKeys})
+	if !errors.Is(err, ErrBadPath) {
+		t.Fatal("expected error ErrBadPath")
+	}
+	for _, sep := range []string{"]", "'"} {
+		_, err = Unflatten(parseNode(t, `{"a":1}`), &FlattenOptions{Separator: sep})
+		if !errors.Is(err, ErrBadPath) {
+			t.Fatalf("expected error ErrBadPath for separator %s", sep)
+		}
+	}
+	_, err = Unflatten(parseNode(t, `[]`), nil)
+	if !errors.Is(err, ErrInvalidNodeForOperation) {
+		t.Fatal("expected error ErrInvalidNodeForOperation")
+	}
+}
@@ -0,0 +1,103 @@
+package xtjson
+
+// History records mutations of the tree as undo steps. Every mutation made outside
+// of transaction is a step of its own, transaction started by History.Begin
+// becomes one step when committed. New step clears redo steps.
+// As in Tx every recorded add or replace copies the added subtree for Patch,
+// and the copies are kept as long as the steps.
+type History struct {
+	rec  *recorder
+	undo [][]operation
+	redo [][]operation
+	tx   *Tx
+}
+
+// NewHistory starts recording mutations made under root
+func NewHistory(root *Node) (*History, error) {
+	if root == nil || root == undef {
+		return nil, ErrNilNode
+	}
+	h := &History{rec: newRecorder(root)}
+	h.rec.onRecord = func() {
+		if h.tx == nil {
+			h.push(h.rec.take())
+		}
+	}
+	return h, nil
+}
+
+// Close stops recording, recorded steps can still be undone and redone
+func (h *History) Close() {
+	h.rec.sub.stop()
+}
+
+// Begin starts transaction which changes become one undo step,
+// only one transaction can be in progress
+func (h *History) Begin() *Tx {
+	if h.tx != nil {
+		return &Tx{err: ErrTxInProgress}
+	}
+	h.tx = &Tx{rec: h.rec, history: h}
+	return h.tx
+}
+
+func (h *History) push(step []operation) {
+	if len(step) == 0 {
+		return
+	}
+	h.undo = append(h.undo, step)
+	h.redo = nil
+}
+
+// CanUndo tells whether there is a step to undo
+func (h *History) CanUndo() bool {
+	return len(h.undo) > 0
+}
+
+// CanRedo tells whether there is a step to redo
+func (h *History) CanRedo() bool {
+	return len(h.redo) > 0
+}
+
+// Undo reverts the last step
+func (h *History) Undo() error {
+	if h.tx != nil {
+		return ErrTxInProgress
+	}
+	if len(h.undo) == 0 {
+		return ErrHistoryEmpty
+	}
+	step := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	if err := h.rec.revert(step); err != nil {
+		return err
+	}
+	h.redo = append(h.redo, step)
+	return nil
+}
+
+// Redo repeats the last undone step
+func (h *History) Redo() error {
+	if h.tx != nil {
+		return ErrTxInProgress
+	}
+	if len(h.redo) == 0 {
+		return ErrHistoryEmpty
+	}
+	step := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	if err := h.rec.replay(step); err != nil {
+		return err
+	}
+	h.undo = append(h.undo, step)
+	return nil
+}
+
+// Patch returns steps which are not undone as RFC 6902 json patch
+func (h *History) Patch() *Node {
+	var ops []operation
+	for _, step := range h.undo {
+		ops = append(ops, step...)
+	}
+	return patchOf(ops)
+}
@@ -0,0 +1,269 @@
+package xtjson
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// IndexOptions defines what is indexed and how the index follows the tree
+type IndexOptions struct {
+	Values bool // index scalar values to be found by ByValue
+	Live   bool // keep index consistent with modifications of the tree
+}
+
+// Index provides fast lookups of nodes by key, path and value.
+// The index built without Live option is the snapshot of the tree,
+// it becomes stale after the tree is modified.
+// Live index is updated on every Set, Append, Replace and Remove under its root,
+// it is subscribed on the root node and lives as long as the tree unless Close is called.
+// Index methods are safe for concurrent use, the tree itself is not.
+type Index struct {
+	mu       sync.Mutex
+	root     *Node
+	opt      IndexOptions
+	keys     map[string]*indexBucket
+	paths    map[string]*Node
+	values   map[string]*indexBucket
+	entries  map[*Node]indexEntry
+	observer *observer
+}
+
+// indexEntry keeps values the node was indexed with to remove it later
+type indexEntry struct {
+	key      string
+	hasKey   bool
+	path     string
+	valueKey string
+}
+
+// indexBucket is the set of nodes, sorted in document order on demand
+type indexBucket struct {
+	set    map[*Node]struct{}
+	sorted Nodes
+}
+
+func (b *indexBucket) nodes() Nodes {
+	if b.sorted == nil {
+		b.sorted = make(Nodes, 0, len(b.set))
+		for node := range b.set {
+			b.sorted = append(b.sorted, node)
+		}
+		slices.SortFunc(b.sorted, compareOrder)
+	}
+	return slices.Clone(b.sorted)
+}
+
+// compareOrder compares position of nodes in document order, ancestors go first
+func compareOrder(a, b *Node) int {
+	return slices.Compare(orderChain(a), orderChain(b))
+}
+
+func orderChain(node *Node) []int {
+	var ret []int
+	for ; node.parent != nil; node = node.parent {
+		ret = append(ret, node.idx)
+	}
+	slices.Reverse(ret)
+	return ret
+}
+
+// BuildIndex indexes all nodes of the tree starting from root
+func BuildIndex(root *Node, opt *IndexOptions) *Index {
+	if opt == nil {
+		opt = &IndexOptions{}
+	}
+	idx := &Index{
+		root:    root,
+		opt:     *opt,
+		keys:    make(map[string]*indexBucket),
+		paths:   make(map[string]*Node),
+		values:  make(map[string]*indexBucket),
+		entries: make(map[*Node]indexEntry),
+	}
+	if !root.Exists() {
+		return idx
+	}
+	idx.add(root, "$", "$")
+	if opt.Live {
+		idx.observer = observe(root, idx.update)
+	}
+	return idx
+}
+
+// Close stops following the modifications of live index, lookups are still possible
+func (idx *Index) Close() {
+	if idx.observer != nil {
+		idx.observer.stop()
+	}
+}
+
+// Lookup returns all object properties with provided key in document order
+func (idx *Index) Lookup(key string) Nodes {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if b, ok := idx.keys[key]; ok {
+		return b.nodes()
+	}
+	return make(Nodes, 0)
+}
+
+// Path returns the node referenced by json path relative to index root
+func (idx *Index) Path(path string) *Node {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if node, ok := idx.paths[path]; ok {
+		return node
+	}
+	return undef
+}
+
+// ByValue returns scalar nodes located by path and equal to value, which can be
+// nil, bool, string or any go number type. Array indexes in path are matched by [*]
+// so $.items[*].id finds ids of all items. Index must be built with Values option.
+func (idx *Index) ByValue(path string, value any) Nodes {
+	node := valueNode(value)
+	if node == nil || !node.IsScalar() {
+		return make(Nodes, 0)
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if b, ok := idx.values[path+" "+node.Stringify()]; ok {
+		return b.nodes()
+	}
+	return make(Nodes, 0)
+}
+
+// Len returns the number of indexed nodes
+func (idx *Index) Len() int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return len(idx.entries)
+}
+
+func bucketAdd(m map[string]*indexBucket, key string, node *Node) {
+	b, ok := m[key]
+	if !ok {
+		b = &indexBucket{set: make(map[*Node]struct{})}
+		m[key] = b
+	}
+	b.set[node] = struct{}{}
+	b.sorted = nil
+}
+
+func bucketRemove(m map[string]*indexBucket, key string, node *Node) {
+	b, ok := m[key]
+	if !ok {
+		return
+	}
+	delete(b.set, node)
+	b.sorted = nil
+	if len(b.set) == 0 {
+		delete(m, key)
+	}
+}
+
+// add indexes node and its descendants, pattern is the path with [*] instead of indexes
+func (idx *Index) add(node *Node, path, pattern string) {
+	var entry indexEntry
+	if node != idx.root && node.parent.IsObject() {
+		entry.key, entry.hasKey = node.key, true
+		bucketAdd(idx.keys, node.key, node)
+	}
+	entry.path = path
+	idx.paths[path] = node
+	if idx.opt.Values && node.IsScalar() {
+		entry.valueKey = pattern + " " + node.Stringify()
+		bucketAdd(idx.values, entry.valueKey, node)
+	}
+	idx.entries[node] = entry
+	for _, child := range node.children {
+		if node.IsArray() {
+			idx.add(child, path+child.pathSegment(), pattern+"[*]")
+		} else {
+			segment := child.pathSegment()
+			idx.add(child, path+segment, pattern+segment)
+		}
+	}
+}
+
+// remove drops node and its descendants from index
+func (idx *Index) remove(node *Node) {
+	entry, ok := idx.entries[node]
+	if !ok {
+		return
+	}
+	if entry.hasKey {
+		bucketRemove(idx.keys, entry.key, node)
+	}
+	if idx.paths[entry.path] == node {
+		delete(idx.paths, entry.path)
+	}
+	if entry.valueKey != "" {
+		bucketRemove(idx.values, entry.valueKey, node)
+	}
+	delete(idx.entries, node)
+	for _, child := range node.children {
+		idx.remove(child)
+	}
+}
+
+// pathOf builds the path and the pattern of node relative to index root
+func (idx *Index) pathOf(node *Node) (string, string) {
+	var path, pattern []string
+	for ; node != idx.root; node = node.parent {
+		segment := node.pathSegment()
+		path = append(path, segment)
+		if node.parent.IsArray() {
+			segment = "[*]"
+		}
+		pattern = append(pattern, segment)
+	}
+	slices.Reverse(path)
+	slices.Reverse(pattern)
+	return "$" + strings.Join(path, ""), "$" + strings.Join(pattern, "")
+}
+
+// update applies the tree mutation to live index
+func (idx *Index) update(m mutation) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if m.op == opReorder {
+		for _, b := range idx.keys {
+			b.sorted = nil
+		}
+		for _, b := range idx.values {
+			b.sorted = nil
+		}
+		// moved array elements change their paths
+		if m.parent.IsArray() {
+			for _, child := range m.parent.children {
+				idx.reindex(child)
+			}
+		}
+		return
+	}
+	if m.old != nil {
+		idx.remove(m.old)
+	}
+	if m.parent.IsObject() || m.op == opReplace {
+		if m.node != nil {
+			idx.reindex(m.node)
+		}
+		return
+	}
+	// array elements following inserted or removed one are shifted
+	from := m.old
+	if m.op == opAdd {
+		from = m.node
+	}
+	for _, child := range m.parent.children[from.idx:] {
+		idx.reindex(child)
+	}
+}
+
+func (idx *Index) reindex(node *Node) {
+	idx.remove(node)
+	path, pattern := idx.pathOf(node)
+	idx.add(node, path, pattern)
+}
@@ -0,0 +1,134 @@
+package xtjson
+
+import (
+	"sync"
+	"testing"
+)
+
+const indexJson = `{"id":"root","items":[{"id":1,"name":"one"},{"id":2,"name":"two"},{"id":3,"name":"one"}],"meta":{"name":"meta"}}`
+
+func paths(ns Nodes) []string {
+	ret := make([]string, len(ns))
+	for i, node := range ns {
+		ret[i] = node.SelfPath()
+	}
+	return ret
+}
+
+func TestBuildIndex(t *testing.T) {
+	root, err := ParseString(indexJson)
+	assertParsed(t, root, err)
+	idx := BuildIndex(root, &IndexOptions{Values: true})
+
+	assertEqual(t, 14, idx.Len())
+	assertEqual(t, []string{"$.id", "$.items[0].id", "$.items[1].id", "$.items[2].id"}, paths(idx.Lookup("id")))
+	assertEqual(t, 0, len(idx.Lookup("missing")))
+	assertEqual(t, root.Path("$.items[1].name"), idx.Path("$.items[1].name"))
+	assertEqual(t, root, idx.Path("$"))
+	assertEqual(t, false, idx.Path("$.items[5]").Exists())
+
+	assertEqual(t, []string{"$.items[0].name", "$.items[2].name"}, paths(idx.ByValue("$.items[*].name", "one")))
+	assertEqual(t, []string{"$.items[1].id"}, paths(idx.ByValue("$.items[*].id", 2)))
+	assertEqual(t, 0, len(idx.ByValue("$.items[*].id", "2")))
+	assertEqual(t, 0, len(idx.ByValue("$.items[*].id", struct{}{})))
+
+	noValues := BuildIndex(root, nil)
+	assertEqual(t, 0, len(noValues.ByValue("$.items[*].id", 2)))
+
+	empty := BuildIndex(nil, nil)
+	assertEqual(t, 0, empty.Len())
+	assertEqual(t, 0, len(empty.Lookup("id")))
+}
+
+func TestBuildIndexSubtree(t *testing.T) {
+	root, err := ParseString(indexJson)
+	assertParsed(t, root, err)
+	idx := BuildIndex(root.Key("items"), &IndexOptions{Values: true})
+	assertEqual(t, 3, len(idx.Lookup("id")))
+	assertEqual(t, "two", idx.Path("$[1].name").value.(string))
+	assertEqual(t, []string{"$.items[2].id"}, paths(idx.ByValue("$[*].id", 3)))
+}
+
+func TestLiveIndex(t *testing.T) {
+	root, err := ParseString(indexJson)
+	assertParsed(t, root, err)
+	idx := BuildIndex(root, &IndexOptions{Values: true, Live: true})
+	defer idx.Close()
+
+	// removal shifts paths of following array elements
+	assertNil(t, root.Key("items").RemoveIdx(0))
+	assertEqual(t, []string{"$.id", "$.items[0].id", "$.items[1].id"}, paths(idx.Lookup("id")))
+	assertEqual(t, root.Path("$.items[1].id"), idx.Path("$.items[1].id"))
+	assertEqual(t, false, idx.Path("$.items[2]").Exists())
+	assertEqual(t, []string{"$.items[1].name"}, paths(idx.ByValue("$.items[*].name", "one")))
+
+	item, err := ParseString(`{"id":4,"name":"one","tags":{"id":"t"}}`)
+	assertParsed(t, item, err)
+	assertNil(t, root.Key("items").Append(item))
+	assertEqual(t, []string{"$.id", "$.items[0].id", "$.items[1].id", "$.items[2].id", "$.items[2].tags.id"}, paths(idx.Lookup("id")))
+	assertEqual(t, []string{"$.items[1].name", "$.items[2].name"}, paths(idx.ByValue("$.items[*].name", "one")))
+
+	// replacing the property removes old subtree
+	assertNil(t, root.SetString("meta", "none"))
+	assertEqual(t, 3, len(idx.Lookup("name")))
+	assertEqual(t, "none", idx.Path("$.meta").value.(string))
+
+	assertNil(t, root.Path("$.items[0].name").ReplaceByString("one"))
+	assertEqual(t, 3, len(idx.ByValue("$.items[*].name", "one")))
+
+	assertNil(t, root.Path("$.items[2].tags").Remove())
+	assertEqual(t, 4, len(idx.Lookup("id")))
+	assertEqual(t, false, idx.Path("$.items[2].tags.id").Exists())
+
+	assertNil(t, root.SetPathInt("$.extra.id", 9, &SetPathOptions{CreateMissing: true}))
+	assertEqual(t, []string{"$.extra.id"}, paths(idx.ByValue("$.extra.id", 9)))
+
+	// inserted and moved array elements shift paths
+	assertNil(t, root.Key("items").Prepend(NewNull()))
+	assertNil(t, root.Key("items").MoveIdx(3, 1))
+	assertNil(t, root.Key("items").Swap(2, 3))
+	assertEqual(t, []string{"$.id", "$.items[1].id", "$.items[2].id", "$.items[3].id", "$.extra.id"}, paths(idx.Lookup("id")))
+	_, err = root.Key("items").Splice(0, 1)
+	assertNil(t, err)
+	assertNil(t, root.Path("$.items[0]").RenameKey("id", "key"))
+	assertEqual(t, "$.items[0].key", idx.Path("$.items[0].key").SelfPath())
+	assertNil(t, root.Path("$.items[0]").RenameKey("key", "id"))
+
+	assertNil(t, root.SortKeys())
+	assertEqual(t, []string{"$.extra.id", "$.id", "$.items[0].id", "$.items[1].id", "$.items[2].id"}, paths(idx.Lookup("id")))
+
+	// the index built on the modified tree has the same content
+	fresh := BuildIndex(root, &IndexOptions{Values: true})
+	assertEqual(t, fresh.Len(), idx.Len())
+	assertEqual(t, paths(fresh.Lookup("name")), paths(idx.Lookup("name")))
+
+	// subscription is kept by the root, not by the package
+	assertEqual(t, 1, len(root.hooks.observers))
+	idx.Close()
+	idx.Close()
+	assertEqual(t, 0, len(root.hooks.observers))
+	assertNil(t, root.RemoveKey("id"))
+	assertEqual(t, 5, len(idx.Lookup("id")))
+}
+
+func TestLiveIndexConcurrentLookup(t *testing.T) {
+	root, err := ParseString(indexJson)
+	assertParsed(t, root, err)
+	idx := BuildIndex(root, &IndexOptions{Live: true})
+	defer idx.Close()
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				idx.Lookup("id")
+			}
+		}()
+	}
+	for i := range 100 {
+		assertNil(t, root.Key("meta").SetInt("id", i))
+	}
+	wg.Wait()
+	assertEqual(t, 5, len(idx.Lookup("id")))
+}
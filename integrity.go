@@ -0,0 +1,72 @@
+package xtjson
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var ErrInconsistentTree = errors.New("inconsistent tree")
+
+// Validate checks that the tree under receiver is consistent: children know their
+// parent and position, object keymap matches children keys, scalars have no children
+// and no node is linked twice or to its own descendant.
+// The error describes the first problem found with the path of the node.
+func (n *Node) Validate() error {
+	if n == nil || n == undef {
+		return ErrNilNode
+	}
+	visited := make(map[*Node]bool)
+	return validateNode(n, "$", visited)
+}
+
+func validateNode(node *Node, path string, visited map[*Node]bool) error {
+	if visited[node] {
+		return fmt.Errorf("%w: %s %s", ErrInconsistentTree, path, "node is linked more than once")
+	}
+	visited[node] = true
+	var kmap keymap
+	switch v := node.value.(type) {
+	case keymap:
+		kmap = v
+		if len(kmap) != len(node.children) {
+			return fmt.Errorf("%w: %s %s", ErrInconsistentTree, path, "keymap size differs from children count")
+		}
+	case Type:
+		if v != Array && v != Null {
+			return fmt.Errorf("%w: %s %s", ErrInconsistentTree, path, "invalid node type")
+		}
+		if v == Null && len(node.children) > 0 {
+			return fmt.Errorf("%w: %s %s", ErrInconsistentTree, path, "scalar node has children")
+		}
+	default:
+		if len(node.children) > 0 {
+			return fmt.Errorf("%w: %s %s", ErrInconsistentTree, path, "scalar node has children")
+		}
+	}
+	for i, child := range node.children {
+		if child == nil {
+			return fmt.Errorf("%w: %s %s %d", ErrInconsistentTree, path, "nil child at", i)
+		}
+		// segment is built from the position, child links may be broken
+		childPath := path + "[" + strconv.Itoa(i) + "]"
+		if kmap != nil {
+			childPath = path + keySegment(child.key, ".")
+		}
+		if child.parent != node {
+			return fmt.Errorf("%w: %s %s", ErrInconsistentTree, childPath, "parent does not match")
+		}
+		if child.idx != i {
+			return fmt.Errorf("%w: %s %s %d", ErrInconsistentTree, childPath, "idx differs from position", i)
+		}
+		if kmap != nil {
+			if idx, ok := kmap[child.key]; !ok || idx != i {
+				return fmt.Errorf("%w: %s %s", ErrInconsistentTree, childPath, "keymap does not match key")
+			}
+		}
+		if err := validateNode(child, childPath, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package xtjson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLinkCycle(t *testing.T) {
+	root, err := ParseString(`[[{"a":[]}]]`)
+	assertParsed(t, root, err)
+	inner := root.Path("$[0][0].a")
+	err = root.Idx(0).Append(root)
+	if !errors.Is(err, ErrNodeCycle) {
+		t.Fatal("expected error ErrNodeCycle")
+	}
+	err = inner.InsertIdx(0, root)
+	if !errors.Is(err, ErrNodeCycle) {
+		t.Fatal("expected error ErrNodeCycle")
+	}
+	err = root.Path("$[0][0]").Set("b", root)
+	if !errors.Is(err, ErrNodeCycle) {
+		t.Fatal("expected error ErrNodeCycle")
+	}
+	err = root.Append(root)
+	if !errors.Is(err, ErrNodeCycle) {
+		t.Fatal("expected error ErrNodeCycle")
+	}
+	err = root.Idx(0).ReplaceIdx(0, root)
+	if !errors.Is(err, ErrNodeCycle) {
+		t.Fatal("expected error ErrNodeCycle")
+	}
+	_, err = inner.Splice(0, 0, root)
+	if !errors.Is(err, ErrNodeCycle) {
+		t.Fatal("expected error ErrNodeCycle")
+	}
+	err = root.Path("$[0][0]").InsertKeyAt(0, "b", root)
+	if !errors.Is(err, ErrNodeCycle) {
+		t.Fatal("expected error ErrNodeCycle")
+	}
+	assertEqual(t, `[[{"a":[]}]]`, root.Stringify())
+	assertNil(t, root.Validate())
+
+	// detached subtree can be linked anywhere
+	sub := root.Idx(0)
+	assertNil(t, sub.Remove())
+	assertNil(t, sub.Path("$[0].a").Append(NewInt(1)))
+	assertNil(t, root.Append(sub))
+	assertNil(t, root.Validate())
+}
+
+func TestReplaceIdxUnlinks(t *testing.T) {
+	root, err := ParseString(`[1,2]`)
+	assertParsed(t, root, err)
+	old := root.Idx(0)
+	assertNil(t, root.ReplaceIdx(0, NewNull()))
+	assertEqual(t, false, old.Parent().Exists())
+	assertNil(t, root.Append(old))
+	assertEqual(t, `[null,2,1]`, root.Stringify())
+
+	old = root.Idx(1)
+	assertNil(t, old.Replace(NewBool(true)))
+	assertEqual(t, false, old.Parent().Exists())
+	err = root.ReplaceIdx(0, root.Idx(1))
+	if !errors.Is(err, ErrNodeHasParent) {
+		t.Fatal("expected error ErrNodeHasParent")
+	}
+	assertNil(t, root.Validate())
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		breakTree func(root *Node)
+		msg       string
+	}{
+		{func(root *Node) { root.Key("a").children[1].idx = 0 }, "$.a[1] idx differs"},
+		{func(root *Node) { root.Path("$.b").parent = root.Key("a") }, "$.b parent does not match"},
+		{func(root *Node) { root.value.(keymap)["a"] = 1 }, "$.a keymap does not match"},
+		{func(root *Node) { delete(root.value.(keymap), "a") }, "$ keymap size"},
+		{func(root *Node) { root.Path("$.a[0]").children = []*Node{NewNull()} }, "$.a[0] scalar node has children"},
+		{func(root *Node) { root.Key("a").children = append(root.Key("a").children, root.Key("b")) }, "$.a[2] parent"},
+		{func(root *Node) { root.Key("a").children[1] = root.Key("a").children[0] }, "$.a[1] idx differs"},
+		{func(root *Node) { root.Key("a").children[0] = nil }, "$.a nil child at 0"},
+		{func(root *Node) {
+			b := root.Key("b")
+			b.children = []*Node{root}
+			b.value = Array
+			root.parent, root.idx = b, 0
+		}, "$.b[0] node is linked more than once"},
+	}
+	for _, c := range cases {
+		root, err := ParseString(`{"a":[1,{"x":2}],"b":{"c":null}}`)
+		assertParsed(t, root, err)
+		assertNil(t, root.Validate())
+		c.breakTree(root)
+		err = root.Validate()
+		if !errors.Is(err, ErrInconsistentTree) {
+			t.Fatalf("expected error ErrInconsistentTree for %s", c.msg)
+		}
+		if !strings.Contains(err.Error(), c.msg) {
+			t.Fatalf("expected %q in %q", c.msg, err.Error())
+		}
+	}
+	var node *Node
+	if !errors.Is(node.Validate(), ErrNilNode) {
+		t.Fatal("expected error ErrNilNode")
+	}
+}
@@ -0,0 +1,80 @@
+package xtjson
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+)
+
+// All returns iterator over node children, the key is string for object
+// children and int index for array children
+func (n *Node) All() iter.Seq2[any, *Node] {
+	return func(yield func(any, *Node) bool) {
+		if n == nil {
+			return
+		}
+		isObject := n.IsObject()
+		for i, child := range n.children {
+			var key any = i
+			if isObject {
+				key = child.key
+			}
+			if !yield(key, child) {
+				return
+			}
+		}
+	}
+}
+
+// Walk returns iterator over node states produced by Walker without deep limit
+func (n *Node) Walk(opts ...*WalkOptions) iter.Seq2[*Node, WalkState] {
+	return func(yield func(*Node, WalkState) bool) {
+		walker, err := NewWalker(n, 0, opts...)
+		if err != nil {
+			return
+		}
+		for {
+			node, state := walker.Next()
+			if state == WalkDone || !yield(node, state) {
+				return
+			}
+		}
+	}
+}
+
+// SearchSeq returns iterator over nodes matched by matcher, the nodes are found
+// lazily while iterating. Nil options are the same as for Search.
+func (n *Node) SearchSeq(matcher NodeMatcher, opts ...*SearchOptions) iter.Seq[*Node] {
+	opt := &SearchOptions{SkipNested: true}
+	if len(opts) > 0 && opts[0] != nil {
+		opt = opts[0]
+	}
+	return func(yield func(*Node) bool) {
+		limit, found := opt.limit(), 0
+		n.walkMatches(context.Background(), matcher, opt, func(node *Node) bool {
+			found++
+			return yield(node) && (limit <= 0 || found < limit)
+		})
+	}
+}
+
+// ReaderSeq returns iterator over nodes read from reader until io.EOF.
+// Read error is yielded with nil node and stops the iteration.
+func ReaderSeq(r Reader) iter.Seq2[*Node, error] {
+	return func(yield func(*Node, error) bool) {
+		for {
+			node, err := r.Read()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(node, nil) {
+				return
+			}
+		}
+	}
+}
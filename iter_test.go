@@ -0,0 +1,122 @@
+package xtjson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	root, err := ParseString(`{"a":1,"b":[true,null]}`)
+	assertParsed(t, root, err)
+	var keys []any
+	for key, child := range root.All() {
+		keys = append(keys, key)
+		assertEqual(t, root.Key(key.(string)), child)
+	}
+	assertEqual(t, []any{"a", "b"}, keys)
+	keys = nil
+	for idx := range root.Key("b").All() {
+		keys = append(keys, idx)
+	}
+	assertEqual(t, []any{0, 1}, keys)
+	for range root.Key("a").All() {
+		t.Fatal("leaf has no children")
+	}
+	var node *Node
+	for range node.All() {
+		t.Fatal("nil has no children")
+	}
+}
+
+func TestWalkSeq(t *testing.T) {
+	root, err := ParseString(`{"a":[1,{"b":2}],"c":3}`)
+	assertParsed(t, root, err)
+	walker, err := NewWalker(root, 0, &WalkOptions{Order: WalkBreadthFirst})
+	assertNil(t, err)
+	expected := walkRest(walker)
+	var actual []walkStep
+	for node, state := range root.Walk(&WalkOptions{Order: WalkBreadthFirst}) {
+		actual = append(actual, walkStep{node, state})
+	}
+	assertEqual(t, expected, actual)
+
+	count := 0
+	for node, state := range root.Walk() {
+		if state == WalkEnter && node.SelfKey() == "a" {
+			break
+		}
+		count++
+	}
+	assertEqual(t, 1, count)
+	var node *Node
+	for range node.Walk() {
+		t.Fatal("nil walk yields nothing")
+	}
+}
+
+func TestSearchSeq(t *testing.T) {
+	root, err := ParseString(`{"a":[1,{"b":2,"c":[3]}],"d":{"e":4}}`)
+	assertParsed(t, root, err)
+	for _, opt := range []*SearchOptions{nil, {}, {MaxResults: 2}, {Order: WalkPostOrder}, {DeepLimit: 2, Reverse: true}} {
+		for _, matcher := range []NodeMatcher{TypeIs(Number), TypeIs(Object, Array)} {
+			expected, err := root.Search(matcher, opt)
+			assertNil(t, err)
+			actual := make(Nodes, 0)
+			for node := range root.SearchSeq(matcher, opt) {
+				actual = append(actual, node)
+			}
+			assertEqual(t, paths(expected), paths(actual))
+		}
+	}
+	for node := range root.SearchSeq(TypeIs(Number)) {
+		assertEqual(t, "$.a[0]", node.SelfPath())
+		break
+	}
+}
+
+type failingReader struct {
+	reads int
+}
+
+func (r *failingReader) Read() (*Node, error) {
+	r.reads++
+	if r.reads > 2 {
+		return nil, ErrInvalidJson
+	}
+	return NewInt(r.reads), nil
+}
+
+func TestReaderSeq(t *testing.T) {
+	reader, err := NewArrayReader(strings.NewReader(`[1,"x",{"a":null}]`))
+	assertNil(t, err)
+	var values []string
+	for node, err := range ReaderSeq(reader) {
+		assertNil(t, err)
+		values = append(values, node.Stringify())
+	}
+	assertEqual(t, []string{"1", `"x"`, `{"a":null}`}, values)
+
+	dir, err := NewDirReader("./fixtures/dir", "*.json")
+	assertNil(t, err)
+	var names []string
+	for node, err := range ReaderSeq(dir) {
+		assertNil(t, err)
+		names = append(names, node.SelfKey())
+	}
+	assertEqual(t, []string{"one.json", "three.json", "two.json"}, names)
+
+	failing := &failingReader{}
+	count := 0
+	for node, err := range ReaderSeq(failing) {
+		count++
+		if count == 3 {
+			assertNil(t, node)
+			if !errors.Is(err, ErrInvalidJson) {
+				t.Fatal("expected error ErrInvalidJson")
+			}
+		}
+	}
+	assertEqual(t, 3, count)
+	assertEqual(t, 3, failing.reads)
+}
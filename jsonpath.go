@@ -0,0 +1,1274 @@
+package xtjson
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// maxSafeInt is the I-JSON integer bound for indexes and slices
+const maxSafeInt = 1<<53 - 1
+
+type jpSelectorKind int
+
+const (
+	jpName jpSelectorKind = iota
+	jpWildcard
+	jpIndex
+	jpSlice
+	jpFilter
+	jpDeepKey        // legacy ...key
+	jpArrayChildren  // legacy [...]
+	jpObjectChildren // legacy {...}
+)
+
+type jpSelector struct {
+	kind   jpSelectorKind
+	name   string
+	index  int64
+	start  *int64
+	end    *int64
+	step   *int64
+	filter jpLogical
+}
+
+type jpSegment struct {
+	descendant bool
+	selectors  []jpSelector
+}
+
+type jpQuery struct {
+	relative bool
+	segments []jpSegment
+}
+
+// jpContext holds the query argument and the node currently checked by filter
+type jpContext struct {
+	root    *Node
+	current *Node
+}
+
+// singular reports if query can produce at most one node
+func (q *jpQuery) singular() bool {
+	for _, seg := range q.segments {
+		if seg.descendant || len(seg.selectors) != 1 {
+			return false
+		}
+		kind := seg.selectors[0].kind
+		if kind != jpName && kind != jpIndex {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *jpQuery) eval(ctx *jpContext) Nodes {
+	start := ctx.root
+	if q.relative {
+		start = ctx.current
+	}
+	nodes := Nodes{start}
+	for _, seg := range q.segments {
+		if len(nodes) == 0 {
+			break
+		}
+		nodes = seg.apply(nodes, ctx.root)
+	}
+	return nodes
+}
+
+func (seg *jpSegment) apply(nodes Nodes, root *Node) Nodes {
+	ret := make(Nodes, 0)
+	if len(seg.selectors) == 1 && seg.selectors[0].kind == jpDeepKey {
+		found, _ := nodes.SearchKey(seg.selectors[0].name, nil)
+		return append(ret, found...)
+	}
+	for _, node := range nodes {
+		if !seg.descendant {
+			for i := range seg.selectors {
+				ret = seg.selectors[i].apply(node, root, ret)
+			}
+			continue
+		}
+		for _, desc := range descendants(node, nil) {
+			for i := range seg.selectors {
+				ret = seg.selectors[i].apply(desc, root, ret)
+			}
+		}
+	}
+	return ret
+}
+
+// descendants returns node and all its descendants in document order
+func descendants(node *Node, ret Nodes) Nodes {
+	ret = append(ret, node)
+	for _, child := range node.children {
+		ret = descendants(child, ret)
+	}
+	return ret
+}
+
+func (sel *jpSelector) apply(node *Node, root *Node, ret Nodes) Nodes {
+	switch sel.kind {
+	case jpName:
+		if child := node.Key(sel.name); child.Exists() {
+			ret = append(ret, child)
+		}
+	case jpWildcard:
+		if node.IsParent() {
+			ret = append(ret, node.children...)
+		}
+	case jpArrayChildren:
+		if node.IsArray() {
+			ret = append(ret, node.children...)
+		}
+	case jpObjectChildren:
+		if node.IsObject() {
+			ret = append(ret, node.children...)
+		}
+	case jpIndex:
+		if !node.IsArray() {
+			break
+		}
+		idx := sel.index
+		if idx < 0 {
+			idx += int64(len(node.children))
+		}
+		if idx >= 0 && idx < int64(len(node.children)) {
+			ret = append(ret, node.children[idx])
+		}
+	case jpSlice:
+		if node.IsArray() {
+			for _, idx := range sel.sliceIndexes(int64(len(node.children))) {
+				ret = append(ret, node.children[idx])
+			}
+		}
+	case jpFilter:
+		if !node.IsParent() {
+			break
+		}
+		for _, child := range node.children {
+			if sel.filter.test(&jpContext{root: root, current: child}) {
+				ret = append(ret, child)
+			}
+		}
+	}
+	return ret
+}
+
+// sliceIndexes implements slice bounds normalization defined by RFC 9535 2.3.4.2.2
+func (sel *jpSelector) sliceIndexes(length int64) []int64 {
+	step := int64(1)
+	if sel.step != nil {
+		step = *sel.step
+	}
+	if step == 0 {
+		return nil
+	}
+	normalize := func(i int64) int64 {
+		if i >= 0 {
+			return i
+		}
+		return length + i
+	}
+	var ret []int64
+	if step > 0 {
+		start, end := int64(0), length
+		if sel.start != nil {
+			start = normalize(*sel.start)
+		}
+		if sel.end != nil {
+			end = normalize(*sel.end)
+		}
+		lower := min(max(start, 0), length)
+		upper := min(max(end, 0), length)
+		for i := lower; i < upper; i += step {
+			ret = append(ret, i)
+		}
+		return ret
+	}
+	start, end := length-1, -length-1
+	if sel.start != nil {
+		start = normalize(*sel.start)
+	}
+	if sel.end != nil {
+		end = normalize(*sel.end)
+	}
+	upper := min(max(start, -1), length-1)
+	lower := min(max(end, -1), length-1)
+	for i := upper; lower < i; i += step {
+		ret = append(ret, i)
+	}
+	return ret
+}
+
+// jpType is the type of function extension parameters and results
+type jpType int
+
+const (
+	jpValueType jpType = iota
+	jpLogicalType
+	jpNodesType
+)
+
+// jpLogical is the filter expression producing logical result
+type jpLogical interface {
+	test(ctx *jpContext) bool
+}
+
+// jpComparable is the filter expression producing value or nothing
+type jpComparable interface {
+	value(ctx *jpContext) (*Node, bool)
+}
+
+type jpOr []jpLogical
+
+func (e jpOr) test(ctx *jpContext) bool {
+	for _, expr := range e {
+		if expr.test(ctx) {
+			return true
+		}
+	}
+	return false
+}
+
+type jpAnd []jpLogical
+
+func (e jpAnd) test(ctx *jpContext) bool {
+	for _, expr := range e {
+		if !expr.test(ctx) {
+			return false
+		}
+	}
+	return true
+}
+
+type jpNot struct {
+	expr jpLogical
+}
+
+func (e *jpNot) test(ctx *jpContext) bool {
+	return !e.expr.test(ctx)
+}
+
+type jpExists struct {
+	query *jpQuery
+}
+
+func (e *jpExists) test(ctx *jpContext) bool {
+	return len(e.query.eval(ctx)) > 0
+}
+
+type jpLiteral struct {
+	node *Node
+}
+
+func (e *jpLiteral) value(ctx *jpContext) (*Node, bool) {
+	return e.node, true
+}
+
+type jpSingular struct {
+	query *jpQuery
+}
+
+func (e *jpSingular) value(ctx *jpContext) (*Node, bool) {
+	nodes := e.query.eval(ctx)
+	if len(nodes) != 1 {
+		return nil, false
+	}
+	return nodes[0], true
+}
+
+type jpCompare struct {
+	op    string
+	left  jpComparable
+	right jpComparable
+}
+
+func (e *jpCompare) test(ctx *jpContext) bool {
+	left, lok := e.left.value(ctx)
+	right, rok := e.right.value(ctx)
+	switch e.op {
+	case "==":
+		return jpEqual(left, lok, right, rok)
+	case "!=":
+		return !jpEqual(left, lok, right, rok)
+	case "<":
+		return jpLess(left, lok, right, rok)
+	case "<=":
+		return jpLess(left, lok, right, rok) || jpEqual(left, lok, right, rok)
+	case ">":
+		return jpLess(right, rok, left, lok)
+	case ">=":
+		return jpLess(right, rok, left, lok) || jpEqual(left, lok, right, rok)
+	}
+	return false
+}
+
+func jpEqual(left *Node, lok bool, right *Node, rok bool) bool {
+	if !lok || !rok {
+		return lok == rok
+	}
+	return equalNodes(left, right)
+}
+
+func jpLess(left *Node, lok bool, right *Node, rok bool) bool {
+	if !lok || !rok {
+		return false
+	}
+	switch lv := left.value.(type) {
+	case float64:
+		rv, ok := right.value.(float64)
+		return ok && lv < rv
+	case string:
+		rv, ok := right.value.(string)
+		return ok && lv < rv
+	}
+	return false
+}
+
+// jpArg is the function argument converted to the declared parameter type
+type jpArg struct {
+	value   jpComparable
+	logical jpLogical
+	nodes   *jpQuery
+}
+
+type jpFunction struct {
+	params []jpType
+	result jpType
+}
+
+var jpFunctions = map[string]jpFunction{
+	"length": {params: []jpType{jpValueType}, result: jpValueType},
+	"count":  {params: []jpType{jpNodesType}, result: jpValueType},
+	"match":  {params: []jpType{jpValueType, jpValueType}, result: jpLogicalType},
+	"search": {params: []jpType{jpValueType, jpValueType}, result: jpLogicalType},
+	"value":  {params: []jpType{jpNodesType}, result: jpValueType},
+}
+
+type jpCall struct {
+	name   string
+	result jpType
+	args   []jpArg
+	// re is the regexp precompiled from literal pattern argument,
+	// constant is set when pattern is literal even if it is invalid
+	re       *regexp.Regexp
+	constant bool
+}
+
+func (e *jpCall) value(ctx *jpContext) (*Node, bool) {
+	switch e.name {
+	case "length":
+		node, ok := e.args[0].value.value(ctx)
+		if !ok {
+			return nil, false
+		}
+		switch v := node.value.(type) {
+		case string:
+			return NewInt(utf8.RuneCountInString(v)), true
+		}
+		if node.IsParent() {
+			return NewInt(len(node.children)), true
+		}
+		return nil, false
+	case "count":
+		return NewInt(len(e.args[0].nodes.eval(ctx))), true
+	case "value":
+		nodes := e.args[0].nodes.eval(ctx)
+		if len(nodes) != 1 {
+			return nil, false
+		}
+		return nodes[0], true
+	}
+	return nil, false
+}
+
+func (e *jpCall) test(ctx *jpContext) bool {
+	if e.name != "match" && e.name != "search" {
+		return false
+	}
+	node, ok := e.args[0].value.value(ctx)
+	if !ok {
+		return false
+	}
+	s, ok := node.value.(string)
+	if !ok {
+		return false
+	}
+	if e.constant {
+		return e.re != nil && e.re.MatchString(s)
+	}
+	node, ok = e.args[1].value.value(ctx)
+	if !ok {
+		return false
+	}
+	pattern, ok := node.value.(string)
+	if !ok {
+		return false
+	}
+	re, err := iregexp(pattern, e.name == "match")
+	if err != nil {
+		return false
+	}
+	return re.MatchString(s)
+}
+
+// precompile prepares regexp for match and search called with literal pattern
+func (e *jpCall) precompile() {
+	if e.name != "match" && e.name != "search" {
+		return
+	}
+	literal, ok := e.args[1].value.(*jpLiteral)
+	if !ok {
+		return
+	}
+	e.constant = true
+	if pattern, ok := literal.node.value.(string); ok {
+		e.re, _ = iregexp(pattern, e.name == "match")
+	}
+}
+
+// iregexp converts I-Regexp (RFC 9485) to go regexp, full requires the whole string match.
+// Literal patterns are compiled once by precompile, dynamic ones on every call.
+func iregexp(pattern string, full bool) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	inClass := false
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '[':
+			inClass = true
+		case r == ']':
+			inClass = false
+		case r == '.' && !inClass:
+			sb.WriteString(`[^\n\r]`)
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	expr := sb.String()
+	if full {
+		expr = `\A(?:` + expr + `)\z`
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRegexp, pattern)
+	}
+	return re, nil
+}
+
+// jpParser parses JSONPath expressions as defined by RFC 9535
+type jpParser struct {
+	src []rune
+	pos int
+}
+
+// parseJSONPath parses the JSONPath expression, unless strict is set the legacy
+// extensions ...key, [...], {...} and member names like $.1 or $.a-b are supported
+// on the top level in addition to RFC 9535 syntax
+func parseJSONPath(expr string, strict bool) (*jpQuery, error) {
+	p := &jpParser{src: []rune(expr)}
+	if p.peek() != '$' {
+		return nil, p.errorf("query must start with $")
+	}
+	p.pos++
+	q, err := p.parseSegments(false, !strict)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected character %q", p.src[p.pos])
+	}
+	return q, nil
+}
+
+func (p *jpParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s at position %d", ErrBadQuery, fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *jpParser) peek() rune {
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *jpParser) peekAt(offset int) rune {
+	if p.pos+offset >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos+offset]
+}
+
+func (p *jpParser) hasPrefix(s string) bool {
+	for i, r := range []rune(s) {
+		if p.peekAt(i) != r {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *jpParser) skipBlank() {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func isNameFirst(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_' ||
+		r >= 0x80 && r <= 0xD7FF || r >= 0xE000 && r <= 0x10FFFF
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func (p *jpParser) parseSegments(relative bool, legacy bool) (*jpQuery, error) {
+	q := &jpQuery{relative: relative}
+	for {
+		mark := p.pos
+		p.skipBlank()
+		var seg *jpSegment
+		var err error
+		switch {
+		case p.hasPrefix("..."):
+			if !legacy {
+				return nil, p.errorf("deep key search is not allowed in filter")
+			}
+			seg, err = p.parseDeepKey()
+		case p.hasPrefix(".."):
+			p.pos += 2
+			seg, err = p.parseDescendant()
+		case p.peek() == '.':
+			p.pos++
+			seg, err = p.parseShorthand(legacy)
+		case legacy && p.hasPrefix("[...]"):
+			p.pos += 5
+			seg = &jpSegment{selectors: []jpSelector{{kind: jpArrayChildren}}}
+		case p.peek() == '[':
+			seg, err = p.parseBracketed()
+		case legacy && p.hasPrefix("{...}"):
+			p.pos += 5
+			seg = &jpSegment{selectors: []jpSelector{{kind: jpObjectChildren}}}
+		default:
+			p.pos = mark
+			return q, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		q.segments = append(q.segments, *seg)
+	}
+}
+
+func (p *jpParser) parseDeepKey() (*jpSegment, error) {
+	p.pos += 3
+	start := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune(".[]{} \t\n\r", p.src[p.pos]) {
+		p.pos++
+	}
+	if start == p.pos {
+		return nil, p.errorf("deep search key expected")
+	}
+	name := string(p.src[start:p.pos])
+	return &jpSegment{selectors: []jpSelector{{kind: jpDeepKey, name: name}}}, nil
+}
+
+func (p *jpParser) parseDescendant() (*jpSegment, error) {
+	switch {
+	case p.peek() == '*':
+		p.pos++
+		return &jpSegment{descendant: true, selectors: []jpSelector{{kind: jpWildcard}}}, nil
+	case p.peek() == '[':
+		seg, err := p.parseBracketed()
+		if err != nil {
+			return nil, err
+		}
+		seg.descendant = true
+		return seg, nil
+	case isNameFirst(p.peek()):
+		seg, err := p.parseShorthand(false)
+		if err != nil {
+			return nil, err
+		}
+		seg.descendant = true
+		return seg, nil
+	}
+	return nil, p.errorf("invalid descendant segment")
+}
+
+// parseShorthand parses member name after dot, in legacy mode any characters
+// up to the next . [ ] { or } form the name as in Path, like $.1, $.foo-bar or $.a b
+func (p *jpParser) parseShorthand(legacy bool) (*jpSegment, error) {
+	if p.peek() == '*' {
+		p.pos++
+		return &jpSegment{selectors: []jpSelector{{kind: jpWildcard}}}, nil
+	}
+	start := p.pos
+	if legacy {
+		for p.pos < len(p.src) && !strings.ContainsRune(".[]{}", p.src[p.pos]) {
+			p.pos++
+		}
+		if start == p.pos {
+			return nil, p.errorf("member name expected")
+		}
+	} else {
+		if !isNameFirst(p.peek()) {
+			return nil, p.errorf("member name expected")
+		}
+		for p.pos < len(p.src) && (isNameFirst(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+	}
+	name := string(p.src[start:p.pos])
+	return &jpSegment{selectors: []jpSelector{{kind: jpName, name: name}}}, nil
+}
+
+func (p *jpParser) parseBracketed() (*jpSegment, error) {
+	p.pos++
+	seg := &jpSegment{}
+	for {
+		p.skipBlank()
+		sel, err := p.parseSelector()
+		if err != nil {
+			return nil, err
+		}
+		seg.selectors = append(seg.selectors, *sel)
+		p.skipBlank()
+		switch p.peek() {
+		case ',':
+			p.pos++
+			continue
+		case ']':
+			p.pos++
+			return seg, nil
+		}
+		return nil, p.errorf("expected , or ]")
+	}
+}
+
+func (p *jpParser) parseSelector() (*jpSelector, error) {
+	switch r := p.peek(); {
+	case r == '\'' || r == '"':
+		name, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return &jpSelector{kind: jpName, name: name}, nil
+	case r == '*':
+		p.pos++
+		return &jpSelector{kind: jpWildcard}, nil
+	case r == '?':
+		p.pos++
+		p.skipBlank()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return &jpSelector{kind: jpFilter, filter: expr}, nil
+	case r == '-' || isDigit(r) || r == ':':
+		return p.parseIndexOrSlice()
+	}
+	return nil, p.errorf("invalid selector")
+}
+
+func (p *jpParser) parseInt() (*int64, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	if !isDigit(p.peek()) {
+		return nil, p.errorf("integer expected")
+	}
+	if p.peek() == '0' && isDigit(p.peekAt(1)) {
+		return nil, p.errorf("leading zero is not allowed")
+	}
+	for isDigit(p.peek()) {
+		p.pos++
+	}
+	s := string(p.src[start:p.pos])
+	if s == "-0" {
+		return nil, p.errorf("negative zero is not allowed")
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v > maxSafeInt || v < -maxSafeInt {
+		return nil, p.errorf("integer is out of range")
+	}
+	return &v, nil
+}
+
+func (p *jpParser) parseIndexOrSlice() (*jpSelector, error) {
+	sel := &jpSelector{kind: jpSlice}
+	var err error
+	if p.peek() != ':' {
+		if sel.start, err = p.parseInt(); err != nil {
+			return nil, err
+		}
+		mark := p.pos
+		p.skipBlank()
+		if p.peek() != ':' {
+			p.pos = mark
+			return &jpSelector{kind: jpIndex, index: *sel.start}, nil
+		}
+	}
+	p.pos++
+	p.skipBlank()
+	if p.peek() == '-' || isDigit(p.peek()) {
+		if sel.end, err = p.parseInt(); err != nil {
+			return nil, err
+		}
+		p.skipBlank()
+	}
+	if p.peek() == ':' {
+		p.pos++
+		p.skipBlank()
+		if p.peek() == '-' || isDigit(p.peek()) {
+			if sel.step, err = p.parseInt(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sel, nil
+}
+
+func (p *jpParser) parseString() (string, error) {
+	quote := p.peek()
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.pos >= len(p.src) {
+			return "", p.errorf("unterminated string")
+		}
+		r := p.src[p.pos]
+		p.pos++
+		switch {
+		case r == quote:
+			return sb.String(), nil
+		case r < 0x20:
+			return "", p.errorf("control character in string")
+		case r != '\\':
+			sb.WriteRune(r)
+			continue
+		}
+		esc := p.peek()
+		p.pos++
+		switch esc {
+		case 'b':
+			sb.WriteRune('\b')
+		case 'f':
+			sb.WriteRune('\f')
+		case 'n':
+			sb.WriteRune('\n')
+		case 'r':
+			sb.WriteRune('\r')
+		case 't':
+			sb.WriteRune('\t')
+		case '/', '\\':
+			sb.WriteRune(esc)
+		case 'u':
+			r, err := p.parseUnicodeEscape()
+			if err != nil {
+				return "", err
+			}
+			sb.WriteRune(r)
+		default:
+			if esc != quote {
+				return "", p.errorf("invalid escape sequence")
+			}
+			sb.WriteRune(esc)
+		}
+	}
+}
+
+func (p *jpParser) parseHex4() (rune, error) {
+	if p.pos+4 > len(p.src) {
+		return 0, p.errorf("invalid unicode escape")
+	}
+	v, err := strconv.ParseUint(string(p.src[p.pos:p.pos+4]), 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid unicode escape")
+	}
+	p.pos += 4
+	return rune(v), nil
+}
+
+func (p *jpParser) parseUnicodeEscape() (rune, error) {
+	r, err := p.parseHex4()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case utf16.IsSurrogate(r) && r < 0xDC00:
+		if !p.hasPrefix(`\u`) {
+			return 0, p.errorf("invalid surrogate pair")
+		}
+		p.pos += 2
+		low, err := p.parseHex4()
+		if err != nil {
+			return 0, err
+		}
+		r = utf16.DecodeRune(r, low)
+		if r == utf8.RuneError {
+			return 0, p.errorf("invalid surrogate pair")
+		}
+	case utf16.IsSurrogate(r):
+		return 0, p.errorf("invalid surrogate pair")
+	}
+	return r, nil
+}
+
+func (p *jpParser) parseOr() (jpLogical, error) {
+	var ret jpOr
+	for {
+		expr, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, expr)
+		mark := p.pos
+		p.skipBlank()
+		if !p.hasPrefix("||") {
+			p.pos = mark
+			break
+		}
+		p.pos += 2
+		p.skipBlank()
+	}
+	if len(ret) == 1 {
+		return ret[0], nil
+	}
+	return ret, nil
+}
+
+func (p *jpParser) parseAnd() (jpLogical, error) {
+	var ret jpAnd
+	for {
+		expr, err := p.parseBasic()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, expr)
+		mark := p.pos
+		p.skipBlank()
+		if !p.hasPrefix("&&") {
+			p.pos = mark
+			break
+		}
+		p.pos += 2
+		p.skipBlank()
+	}
+	if len(ret) == 1 {
+		return ret[0], nil
+	}
+	return ret, nil
+}
+
+func (p *jpParser) parseParen() (jpLogical, error) {
+	p.pos++
+	p.skipBlank()
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	if p.peek() != ')' {
+		return nil, p.errorf("expected )")
+	}
+	p.pos++
+	return expr, nil
+}
+
+func (p *jpParser) parseBasic() (jpLogical, error) {
+	if p.peek() == '!' {
+		p.pos++
+		p.skipBlank()
+		var expr jpLogical
+		var err error
+		if p.peek() == '(' {
+			expr, err = p.parseParen()
+		} else {
+			expr, err = p.parseTest()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &jpNot{expr}, nil
+	}
+	if p.peek() == '(' {
+		return p.parseParen()
+	}
+	start := p.pos
+	operand, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	mark := p.pos
+	p.skipBlank()
+	op := p.parseCompareOp()
+	if op == "" {
+		p.pos = mark
+		return p.asTest(operand, start)
+	}
+	left, err := p.asComparable(operand, start)
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	start = p.pos
+	operand, err = p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	right, err := p.asComparable(operand, start)
+	if err != nil {
+		return nil, err
+	}
+	return &jpCompare{op: op, left: left, right: right}, nil
+}
+
+func (p *jpParser) parseTest() (jpLogical, error) {
+	start := p.pos
+	operand, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return p.asTest(operand, start)
+}
+
+func (p *jpParser) parseCompareOp() string {
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.hasPrefix(op) {
+			p.pos += len(op)
+			return op
+		}
+	}
+	return ""
+}
+
+// parseOperand parses literal, filter query or function call
+func (p *jpParser) parseOperand() (any, error) {
+	switch r := p.peek(); {
+	case r == '@' || r == '$':
+		p.pos++
+		return p.parseSegments(r == '@', false)
+	case r == '\'' || r == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return &jpLiteral{NewString(s)}, nil
+	case r == '-' || isDigit(r):
+		return p.parseNumber()
+	case r >= 'a' && r <= 'z':
+		start := p.pos
+		for r := p.peek(); r >= 'a' && r <= 'z' || r == '_' || isDigit(r); r = p.peek() {
+			p.pos++
+		}
+		name := string(p.src[start:p.pos])
+		if p.peek() == '(' {
+			return p.parseCall(name, start)
+		}
+		switch name {
+		case "true":
+			return &jpLiteral{NewBool(true)}, nil
+		case "false":
+			return &jpLiteral{NewBool(false)}, nil
+		case "null":
+			return &jpLiteral{NewNull()}, nil
+		}
+		p.pos = start
+		return nil, p.errorf("unknown literal %s", name)
+	}
+	return nil, p.errorf("invalid filter expression")
+}
+
+func (p *jpParser) parseNumber() (*jpLiteral, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	if !isDigit(p.peek()) {
+		return nil, p.errorf("number expected")
+	}
+	if p.peek() == '0' && isDigit(p.peekAt(1)) {
+		return nil, p.errorf("leading zero is not allowed")
+	}
+	for isDigit(p.peek()) {
+		p.pos++
+	}
+	if p.peek() == '.' {
+		p.pos++
+		if !isDigit(p.peek()) {
+			return nil, p.errorf("fraction digits expected")
+		}
+		for isDigit(p.peek()) {
+			p.pos++
+		}
+	}
+	if p.peek() == 'e' || p.peek() == 'E' {
+		p.pos++
+		if p.peek() == '+' || p.peek() == '-' {
+			p.pos++
+		}
+		if !isDigit(p.peek()) {
+			return nil, p.errorf("exponent digits expected")
+		}
+		for isDigit(p.peek()) {
+			p.pos++
+		}
+	}
+	v, err := strconv.ParseFloat(string(p.src[start:p.pos]), 64)
+	if err != nil || math.IsInf(v, 0) {
+		return nil, p.errorf("invalid number")
+	}
+	return &jpLiteral{NewNumber(v)}, nil
+}
+
+func (p *jpParser) parseCall(name string, start int) (*jpCall, error) {
+	fn, ok := jpFunctions[name]
+	if !ok {
+		p.pos = start
+		return nil, p.errorf("unknown function %s", name)
+	}
+	call := &jpCall{name: name, result: fn.result}
+	p.pos++
+	p.skipBlank()
+	for p.peek() != ')' {
+		if len(call.args) > 0 {
+			if p.peek() != ',' {
+				return nil, p.errorf("expected , or )")
+			}
+			p.pos++
+			p.skipBlank()
+		}
+		if len(call.args) >= len(fn.params) {
+			return nil, p.errorf("too many arguments for %s", name)
+		}
+		arg, err := p.parseArg(fn.params[len(call.args)])
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, *arg)
+		p.skipBlank()
+	}
+	if len(call.args) != len(fn.params) {
+		return nil, p.errorf("not enough arguments for %s", name)
+	}
+	p.pos++
+	call.precompile()
+	return call, nil
+}
+
+func (p *jpParser) parseArg(typ jpType) (*jpArg, error) {
+	start := p.pos
+	operand, err := p.parseOperand()
+	if err == nil {
+		mark := p.pos
+		p.skipBlank()
+		if p.peek() == ',' || p.peek() == ')' {
+			p.pos = mark
+			return p.asArg(operand, typ, start)
+		}
+	}
+	// argument is not a single operand, it can be only logical expression
+	p.pos = start
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if typ != jpLogicalType {
+		p.pos = start
+		return nil, p.errorf("logical expression is not allowed as argument")
+	}
+	return &jpArg{logical: expr}, nil
+}
+
+func (p *jpParser) asArg(operand any, typ jpType, start int) (*jpArg, error) {
+	switch typ {
+	case jpValueType:
+		value, err := p.asComparable(operand, start)
+		if err != nil {
+			return nil, err
+		}
+		return &jpArg{value: value}, nil
+	case jpLogicalType:
+		test, err := p.asTest(operand, start)
+		if err != nil {
+			return nil, err
+		}
+		return &jpArg{logical: test}, nil
+	}
+	if q, ok := operand.(*jpQuery); ok {
+		return &jpArg{nodes: q}, nil
+	}
+	p.pos = start
+	return nil, p.errorf("nodes argument expected")
+}
+
+func (p *jpParser) asComparable(operand any, start int) (jpComparable, error) {
+	switch v := operand.(type) {
+	case *jpLiteral:
+		return v, nil
+	case *jpQuery:
+		if v.singular() {
+			return &jpSingular{v}, nil
+		}
+	case *jpCall:
+		if v.result == jpValueType {
+			return v, nil
+		}
+	}
+	p.pos = start
+	return nil, p.errorf("value expected")
+}
+
+func (p *jpParser) asTest(operand any, start int) (jpLogical, error) {
+	switch v := operand.(type) {
+	case *jpQuery:
+		return &jpExists{v}, nil
+	case *jpCall:
+		if v.result == jpLogicalType {
+			return v, nil
+		}
+	}
+	p.pos = start
+	return nil, p.errorf("logical expression expected")
+}
+
+func (q *jpQuery) String() string {
+	var sb strings.Builder
+	if q.relative {
+		sb.WriteRune('@')
+	} else {
+		sb.WriteRune('$')
+	}
+	for _, seg := range q.segments {
+		sb.WriteString(seg.String())
+	}
+	return sb.String()
+}
+
+func (seg *jpSegment) String() string {
+	if len(seg.selectors) == 1 {
+		switch seg.selectors[0].kind {
+		case jpDeepKey, jpArrayChildren, jpObjectChildren:
+			return seg.selectors[0].String()
+		}
+	}
+	items := make([]string, len(seg.selectors))
+	for i := range seg.selectors {
+		items[i] = seg.selectors[i].String()
+	}
+	ret := "[" + strings.Join(items, ",") + "]"
+	if seg.descendant {
+		return ".." + ret
+	}
+	return ret
+}
+
+func (sel *jpSelector) String() string {
+	switch sel.kind {
+	case jpName:
+		return quoteName(sel.name)
+	case jpWildcard:
+		return "*"
+	case jpIndex:
+		return strconv.FormatInt(sel.index, 10)
+	case jpSlice:
+		ret := ""
+		if sel.start != nil {
+			ret += strconv.FormatInt(*sel.start, 10)
+		}
+		ret += ":"
+		if sel.end != nil {
+			ret += strconv.FormatInt(*sel.end, 10)
+		}
+		if sel.step != nil {
+			ret += ":" + strconv.FormatInt(*sel.step, 10)
+		}
+		return ret
+	case jpFilter:
+		return "?" + exprString(sel.filter)
+	case jpDeepKey:
+		return "..." + sel.name
+	case jpArrayChildren:
+		return "[...]"
+	case jpObjectChildren:
+		return "{...}"
+	}
+	return ""
+}
+
+// quoteName returns member name as single quoted JSONPath string literal
+func quoteName(name string) string {
+	q := strconv.Quote(name)
+	q = strings.ReplaceAll(q[1:len(q)-1], `\"`, `"`)
+	return "'" + strings.ReplaceAll(q, "'", `\'`) + "'"
+}
+
+// exprString returns canonical representation of filter expression
+func exprString(expr any) string {
+	switch e := expr.(type) {
+	case jpOr:
+		items := make([]string, len(e))
+		for i, item := range e {
+			items[i] = exprString(item)
+		}
+		return strings.Join(items, " || ")
+	case jpAnd:
+		items := make([]string, len(e))
+		for i, item := range e {
+			items[i] = exprString(item)
+			if _, ok := item.(jpOr); ok {
+				items[i] = "(" + items[i] + ")"
+			}
+		}
+		return strings.Join(items, " && ")
+	case *jpNot:
+		switch e.expr.(type) {
+		case *jpExists, *jpCall:
+			return "!" + exprString(e.expr)
+		}
+		return "!(" + exprString(e.expr) + ")"
+	case *jpExists:
+		return e.query.String()
+	case *jpSingular:
+		return e.query.String()
+	case *jpLiteral:
+		if s, ok := e.node.value.(string); ok {
+			return quoteName(s)
+		}
+		return e.node.Stringify()
+	case *jpCompare:
+		return exprString(e.left) + " " + e.op + " " + exprString(e.right)
+	case *jpCall:
+		items := make([]string, len(e.args))
+		for i, arg := range e.args {
+			switch {
+			case arg.value != nil:
+				items[i] = exprString(arg.value)
+			case arg.logical != nil:
+				items[i] = exprString(arg.logical)
+			default:
+				items[i] = arg.nodes.String()
+			}
+		}
+		return e.name + "(" + strings.Join(items, ", ") + ")"
+	}
+	return ""
+}
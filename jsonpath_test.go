@@ -0,0 +1,124 @@
+package xtjson
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+)
+
+type ctsTest struct {
+	Name            string            `json:"name"`
+	Selector        string            `json:"selector"`
+	Document        json.RawMessage   `json:"document"`
+	Result          json.RawMessage   `json:"result"`
+	Results         []json.RawMessage `json:"results"` // any of results is expected when order is not defined
+	InvalidSelector bool              `json:"invalid_selector"`
+}
+
+// ctsSkips lists compliance tests by name which are not run, an entry matching nothing fails the test
+var ctsSkips = map[string]string{}
+
+// TestJSONPathCompliance runs compliance test vectors in RFC 9535 strict mode
+func TestJSONPathCompliance(t *testing.T) {
+	data, err := os.ReadFile("./testdata/jsonpath/cts.json")
+	assertNil(t, err)
+	var suite struct {
+		Tests []ctsTest `json:"tests"`
+	}
+	assertNil(t, json.Unmarshal(data, &suite))
+
+	matched := map[string]bool{}
+	for _, tc := range suite.Tests {
+		if reason, ok := ctsSkips[tc.Name]; ok {
+			matched[tc.Name] = true
+			t.Logf("skip %s: %s", tc.Name, reason)
+			continue
+		}
+		t.Run(tc.Name, func(t *testing.T) {
+			q, err := parseJSONPath(tc.Selector, true)
+			if tc.InvalidSelector {
+				if !errors.Is(err, ErrBadQuery) {
+					t.Fatalf("selector %q expected to be invalid", tc.Selector)
+				}
+				return
+			}
+			assertNil(t, err)
+			doc, err := ParseBytes(tc.Document)
+			assertParsed(t, doc, err)
+			actual := q.eval(&jpContext{root: doc, current: doc}).ToArray()
+			results := tc.Results
+			if results == nil {
+				results = []json.RawMessage{tc.Result}
+			}
+			for _, result := range results {
+				expected, err := ParseBytes(result)
+				assertParsed(t, expected, err)
+				if equalNodes(expected, actual) {
+					return
+				}
+			}
+			t.Fatalf("selector %q unexpected result: %s", tc.Selector, actual.Stringify())
+		})
+	}
+	for name := range ctsSkips {
+		if !matched[name] {
+			t.Errorf("skip %q matches no test", name)
+		}
+	}
+}
+
+func TestJSONPathSingular(t *testing.T) {
+	for expr, singular := range map[string]bool{
+		"$":          true,
+		"$.a[0]":     true,
+		"$['a'][-1]": true,
+		"$.*":        false,
+		"$..a":       false,
+		"$[0,1]":     false,
+		"$[0:1]":     false,
+	} {
+		q, err := parseJSONPath(expr, false)
+		assertNil(t, err)
+		assertEqual(t, singular, q.singular())
+	}
+}
+
+func TestJSONPathErrorPosition(t *testing.T) {
+	_, err := parseJSONPath("$.a[?@.b = 1]", false)
+	if !errors.Is(err, ErrBadQuery) {
+		t.Fatal("error is expected ErrBadQuery")
+	}
+	assertEqual(t, "bad query syntax: expected , or ] at position 9", err.Error())
+}
+
+func TestIRegexp(t *testing.T) {
+	re, err := iregexp("a.c", true)
+	assertNil(t, err)
+	assertEqual(t, true, re.MatchString("abc"))
+	assertEqual(t, false, re.MatchString("a\rc"))
+	assertEqual(t, false, re.MatchString("xabc"))
+
+	re, err = iregexp("[.]", false)
+	assertNil(t, err)
+	assertEqual(t, true, re.MatchString("a.b"))
+	assertEqual(t, false, re.MatchString("ab"))
+
+	_, err = iregexp("(", false)
+	if !errors.Is(err, ErrInvalidRegexp) {
+		t.Fatal("error is expected ErrInvalidRegexp")
+	}
+}
+
+func TestEqualNodes(t *testing.T) {
+	a, err := ParseString(`{"x":[1,{"y":null}],"z":"s"}`)
+	assertParsed(t, a, err)
+	b, err := ParseString(`{"z":"s","x":[1,{"y":null}]}`)
+	assertParsed(t, b, err)
+	c, err := ParseString(`{"z":"s","x":[{"y":null},1]}`)
+	assertParsed(t, c, err)
+	assertEqual(t, true, equalNodes(a, b))
+	assertEqual(t, false, equalNodes(a, c))
+	assertEqual(t, false, equalNodes(a, nil))
+	assertEqual(t, true, equalNodes(NewInt(1), NewNumber(1.0)))
+}
@@ -3,6 +3,7 @@ package xtjson
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"sort"
 )
 
@@ -12,6 +13,7 @@ var (
 	ErrInvalidKey              = errors.New("invalid key")
 	ErrNoParent                = errors.New("node has no parent")
 	ErrNodeHasParent           = errors.New("node has parent")
+	ErrNodeCycle               = errors.New("node cycle")
 )
 
 // NewArray creates new array node
@@ -54,11 +56,12 @@ func (n *Node) Append(node *Node) error {
 	if !n.IsArray() {
 		return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "append")
 	}
-	if node.parent != nil {
-		return fmt.Errorf("%w %s", ErrNodeHasParent, "attemt to append node linked to another parent")
+	if err := n.checkLink(node, "append"); err != nil {
+		return err
 	}
 	node.idx = n.append(node)
 	node.parent = n
+	notify(mutation{op: opAdd, parent: n, node: node})
 	return nil
 }
 
@@ -92,13 +95,161 @@ func (n *Node) AppendNull() error {
 	return n.Append(node)
 }
 
+// InsertIdx inserts node to array receiver children at position idx shifting following children,
+// idx equal to children length appends the node
+func (n *Node) InsertIdx(idx int, node *Node) error {
+	if !n.IsArray() {
+		return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "insert index")
+	}
+	if err := n.checkLink(node, "insert"); err != nil {
+		return err
+	}
+	if idx < 0 || idx > len(n.children) {
+		return fmt.Errorf("%w %d", ErrInvalidIndex, idx)
+	}
+	n.children = slices.Insert(n.children, idx, node)
+	n.renumber(idx, len(n.children))
+	node.parent = n
+	notify(mutation{op: opAdd, parent: n, node: node})
+	return nil
+}
+
+// checkLink verifies that node can become the child of receiver: it is not nil,
+// it is not linked to another parent and receiver is not node itself or its descendant
+func (n *Node) checkLink(node *Node, op string) error {
+	if node == nil || node == undef {
+		return fmt.Errorf("%w %s %s", ErrInvalidNodeForOperation, op, "nil node")
+	}
+	if node.parent != nil {
+		return fmt.Errorf("%w %s", ErrNodeHasParent, "attemt to "+op+" node linked to another parent")
+	}
+	if n.Root() == node {
+		return fmt.Errorf("%w %s", ErrNodeCycle, "attemt to "+op+" node to itself or its descendant")
+	}
+	return nil
+}
+
+// renumber sets idx of children in range [from, to)
+func (n *Node) renumber(from, to int) {
+	for i := from; i < to; i++ {
+		n.children[i].idx = i
+	}
+}
+
+// InsertString inserts string node at position idx, error returned when receiver is not array node
+func (n *Node) InsertString(idx int, value string) error {
+	return n.InsertIdx(idx, NewString(value))
+}
+
+// InsertBool inserts boolean node at position idx, error returned when receiver is not array node
+func (n *Node) InsertBool(idx int, value bool) error {
+	return n.InsertIdx(idx, NewBool(value))
+}
+
+// InsertNumber inserts numeric node at position idx, error returned when receiver is not array node
+func (n *Node) InsertNumber(idx int, value float64) error {
+	return n.InsertIdx(idx, NewNumber(value))
+}
+
+// InsertInt inserts numeric integer node at position idx, error returned when receiver is not array node
+func (n *Node) InsertInt(idx int, value int) error {
+	return n.InsertIdx(idx, NewInt(value))
+}
+
+// InsertNull inserts null node at position idx, error returned when receiver is not array node
+func (n *Node) InsertNull(idx int) error {
+	return n.InsertIdx(idx, NewNull())
+}
+
+// Prepend inserts node as the first child, error returned when receiver is not array node
+func (n *Node) Prepend(node *Node) error {
+	return n.InsertIdx(0, node)
+}
+
+// MoveIdx moves the child of array node from one position to another shifting children between them
+func (n *Node) MoveIdx(from, to int) error {
+	if !n.IsArray() {
+		return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "move index")
+	}
+	for _, idx := range []int{from, to} {
+		if idx < 0 || idx >= len(n.children) {
+			return fmt.Errorf("%w %d", ErrInvalidIndex, idx)
+		}
+	}
+	if from == to {
+		return nil
+	}
+	before := n.observedOrder()
+	node := n.children[from]
+	n.children = slices.Insert(slices.Delete(n.children, from, from+1), to, node)
+	n.renumber(min(from, to), max(from, to)+1)
+	notify(mutation{op: opReorder, parent: n, before: before})
+	return nil
+}
+
+// Swap exchanges positions of two children of array node
+func (n *Node) Swap(i, j int) error {
+	if !n.IsArray() {
+		return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "swap")
+	}
+	for _, idx := range []int{i, j} {
+		if idx < 0 || idx >= len(n.children) {
+			return fmt.Errorf("%w %d", ErrInvalidIndex, idx)
+		}
+	}
+	if i == j {
+		return nil
+	}
+	before := n.observedOrder()
+	n.children[i], n.children[j] = n.children[j], n.children[i]
+	n.children[i].idx = i
+	n.children[j].idx = j
+	notify(mutation{op: opReorder, parent: n, before: before})
+	return nil
+}
+
+// Splice removes deleteCount children of array node starting from position start and inserts
+// nodes in their place. Removed nodes are unlinked from receiver and returned.
+// Nothing is changed when any of arguments is invalid.
+func (n *Node) Splice(start, deleteCount int, nodes ...*Node) (Nodes, error) {
+	if !n.IsArray() {
+		return nil, fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "splice")
+	}
+	if start < 0 || start > len(n.children) {
+		return nil, fmt.Errorf("%w %d", ErrInvalidIndex, start)
+	}
+	if deleteCount < 0 || start+deleteCount > len(n.children) {
+		return nil, fmt.Errorf("%w %d", ErrInvalidIndex, start+deleteCount)
+	}
+	for i, node := range nodes {
+		if err := n.checkLink(node, "splice"); err != nil {
+			return nil, err
+		}
+		if slices.Contains(nodes[:i], node) {
+			return nil, fmt.Errorf("%w %s", ErrNodeHasParent, "attemt to splice the same node twice")
+		}
+	}
+	removed := slices.Clone(n.children[start : start+deleteCount])
+	for range removed {
+		if err := n.RemoveIdx(start); err != nil {
+			return nil, err
+		}
+	}
+	for i, node := range nodes {
+		if err := n.InsertIdx(start+i, node); err != nil {
+			return nil, err
+		}
+	}
+	return removed, nil
+}
+
 // Set sets node associated with key as a receiver property, error is returned if receiver is nots object
 func (n *Node) Set(key string, node *Node) error {
 	if !n.IsObject() {
 		return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "set")
 	}
-	if node.parent != nil {
-		return fmt.Errorf("%w %s", ErrNodeHasParent, "attemt to set property node linked to another parent")
+	if err := n.checkLink(node, "set property"); err != nil {
+		return err
 	}
 
 	var err error
@@ -107,13 +258,18 @@ func (n *Node) Set(key string, node *Node) error {
 	kmap := n.value.(keymap)
 	idx, ok := kmap[key]
 	if !ok {
-		node.idx, err = n.appendKey(key, node)
-		return err
+		if node.idx, err = n.appendKey(key, node); err != nil {
+			return err
+		}
+		notify(mutation{op: opAdd, parent: n, node: node})
+		return nil
 	}
 	node.idx = idx
+	old := n.children[idx]
 	if err = n.replaceIdx(idx, node); err != nil {
 		panic(err)
 	}
+	notify(mutation{op: opReplace, parent: n, old: old, node: node})
 	return nil
 }
 
@@ -153,15 +309,18 @@ func (n *Node) RemoveIdx(idx int) error {
 		return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "remove index")
 	}
 	lc := len(n.children) - 1
-	if idx > lc {
+	if idx < 0 || idx > lc {
 		return fmt.Errorf("%w %d", ErrInvalidIndex, idx)
 	}
+	old := n.children[idx]
 	for i := idx; i < lc; i++ {
 		node := n.children[i+1]
 		node.idx = i
 		n.children[i] = n.children[i+1]
 	}
 	n.children = n.children[:lc]
+	old.parent = nil
+	notify(mutation{op: opRemove, parent: n, old: old})
 	return nil
 }
 
@@ -179,6 +338,7 @@ func (n *Node) RemoveKey(key string) error {
 	if idx > lc {
 		panic(fmt.Errorf("%w %d", ErrInvalidIndex, idx))
 	}
+	old := n.children[idx]
 	for i := idx; i < lc; i++ {
 		node := n.children[i+1]
 		node.idx = i
@@ -191,6 +351,8 @@ func (n *Node) RemoveKey(key string) error {
 			kmap[key] = i - 1
 		}
 	}
+	old.parent = nil
+	notify(mutation{op: opRemove, parent: n, old: old})
 	return nil
 }
 
@@ -203,7 +365,6 @@ func (n *Node) Remove() error {
 	if parent.IsScalar() {
 		panic("parent is scalar")
 	}
-	n.parent = nil
 	if parent.IsArray() {
 		return parent.RemoveIdx(n.idx)
 	}
@@ -215,6 +376,7 @@ func (n *Node) replaceIdx(idx int, node *Node) error {
 	if idx >= lc {
 		return fmt.Errorf("%w %d", ErrInvalidIndex, idx)
 	}
+	n.children[idx].parent = nil
 	node.idx = idx
 	n.children[idx] = node
 	return nil
@@ -225,8 +387,19 @@ func (n *Node) ReplaceIdx(idx int, node *Node) error {
 	if !n.IsArray() {
 		return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "replace index")
 	}
+	if idx < 0 || idx >= len(n.children) {
+		return fmt.Errorf("%w %d", ErrInvalidIndex, idx)
+	}
+	if err := n.checkLink(node, "replace"); err != nil {
+		return err
+	}
+	old := n.children[idx]
 	node.parent = n
-	return n.replaceIdx(idx, node)
+	if err := n.replaceIdx(idx, node); err != nil {
+		return err
+	}
+	notify(mutation{op: opReplace, parent: n, old: old, node: node})
+	return nil
 }
 
 // Replace replaces the node
@@ -238,7 +411,6 @@ func (n *Node) Replace(node *Node) error {
 	if parent.IsScalar() {
 		panic("parent is scalar")
 	}
-	n.parent = nil
 	if parent.IsArray() {
 		return parent.ReplaceIdx(n.idx, node)
 	}
@@ -270,35 +442,184 @@ func (n *Node) ReplaceByNull() error {
 	return n.Replace(NewNull())
 }
 
+// RenameKey changes the key of object node child keeping its position
+func (n *Node) RenameKey(oldKey, newKey string) error {
+	if !n.IsObject() {
+		return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "rename key")
+	}
+	kmap := n.value.(keymap)
+	idx, ok := kmap[oldKey]
+	if !ok {
+		return fmt.Errorf("%w %s", ErrInvalidKey, oldKey)
+	}
+	if oldKey == newKey {
+		return nil
+	}
+	if _, ok = kmap[newKey]; ok {
+		return fmt.Errorf("%w %s", ErrDuplicateKey, newKey)
+	}
+	delete(kmap, oldKey)
+	kmap[newKey] = idx
+	node := n.children[idx]
+	node.key = newKey
+	notify(mutation{op: opRename, parent: n, node: node, oldKey: oldKey})
+	return nil
+}
+
+// renumberKeys sets idx of object children in range [from, to) updating the keymap
+func (n *Node) renumberKeys(from, to int) {
+	kmap := n.value.(keymap)
+	for i := from; i < to; i++ {
+		n.children[i].idx = i
+		kmap[n.children[i].key] = i
+	}
+}
+
+// InsertKeyAt inserts node with key to object receiver at position idx shifting following properties,
+// idx equal to properties count appends the property
+func (n *Node) InsertKeyAt(idx int, key string, node *Node) error {
+	if !n.IsObject() {
+		return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "insert key")
+	}
+	if err := n.checkLink(node, "insert"); err != nil {
+		return err
+	}
+	if idx < 0 || idx > len(n.children) {
+		return fmt.Errorf("%w %d", ErrInvalidIndex, idx)
+	}
+	if _, ok := n.value.(keymap)[key]; ok {
+		return fmt.Errorf("%w %s", ErrDuplicateKey, key)
+	}
+	node.key = key
+	node.parent = n
+	n.children = slices.Insert(n.children, idx, node)
+	n.renumberKeys(idx, len(n.children))
+	notify(mutation{op: opAdd, parent: n, node: node})
+	return nil
+}
+
+// InsertKeyBefore inserts node with key to object receiver before the property ref
+func (n *Node) InsertKeyBefore(ref, key string, node *Node) error {
+	idx, err := n.keyIdx(ref)
+	if err != nil {
+		return err
+	}
+	return n.InsertKeyAt(idx, key, node)
+}
+
+// InsertKeyAfter inserts node with key to object receiver after the property ref
+func (n *Node) InsertKeyAfter(ref, key string, node *Node) error {
+	idx, err := n.keyIdx(ref)
+	if err != nil {
+		return err
+	}
+	return n.InsertKeyAt(idx+1, key, node)
+}
+
+func (n *Node) keyIdx(key string) (int, error) {
+	if !n.IsObject() {
+		return 0, fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "key position")
+	}
+	idx, ok := n.value.(keymap)[key]
+	if !ok {
+		return 0, fmt.Errorf("%w %s", ErrInvalidKey, key)
+	}
+	return idx, nil
+}
+
+// MoveKey moves the property of object node to position idx shifting properties between them
+func (n *Node) MoveKey(key string, idx int) error {
+	from, err := n.keyIdx(key)
+	if err != nil {
+		return err
+	}
+	if idx < 0 || idx >= len(n.children) {
+		return fmt.Errorf("%w %d", ErrInvalidIndex, idx)
+	}
+	if from == idx {
+		return nil
+	}
+	before := n.observedOrder()
+	node := n.children[from]
+	n.children = slices.Insert(slices.Delete(n.children, from, from+1), idx, node)
+	n.renumberKeys(min(from, idx), max(from, idx)+1)
+	notify(mutation{op: opReorder, parent: n, before: before})
+	return nil
+}
+
 // SortKeys sorts keys alphabetically reordering children nodes
 func (n *Node) SortKeys() error {
+	return n.SortKeysFunc(func(a, b string) bool {
+		return a < b
+	})
+}
+
+// SortKeysFunc sorts keys with less function reordering children nodes,
+// the sort is stable so equal keys keep their order
+func (n *Node) SortKeysFunc(less func(a, b string) bool) error {
 	if !n.IsObject() {
 		return ErrInvalidNodeForOperation
 	}
-	kmap := n.value.(keymap)
-	klen := len(kmap)
-	if klen == 0 {
+	if len(n.children) == 0 {
 		return nil
 	}
+	before := n.observedOrder()
+	sort.SliceStable(n.children, func(i, j int) bool {
+		return less(n.children[i].key, n.children[j].key)
+	})
+	n.renumberKeys(0, len(n.children))
+	notify(mutation{op: opReorder, parent: n, before: before})
+	return nil
+}
+
+// SortKeysByOrder puts keys listed in order first in the same sequence,
+// other keys follow them keeping their relative order
+func (n *Node) SortKeysByOrder(order []string) error {
+	return n.SortKeysFunc(keyOrderLess(order))
+}
 
-	keys := make([]string, 0, klen)
-	for k := range kmap {
-		keys = append(keys, k)
+func keyOrderLess(order []string) func(a, b string) bool {
+	rank := make(map[string]int, len(order))
+	for i, key := range order {
+		if _, ok := rank[key]; !ok {
+			rank[key] = i
+		}
 	}
-	sort.Strings(keys)
-	children := make([]*Node, klen)
-	for i, key := range keys {
-		idx := kmap[key]
-		children[i] = n.children[idx]
-		children[i].idx = i
-		kmap[key] = i
+	return func(a, b string) bool {
+		ra, oka := rank[a]
+		rb, okb := rank[b]
+		if !oka {
+			ra = len(order)
+		}
+		if !okb {
+			rb = len(order)
+		}
+		return ra < rb
 	}
-	n.children = children
-	return nil
 }
 
 // SortTreeKeys sorts keys alphabetically in current and all children nodes
 func (n *Node) SortTreeKeys() error {
+	return n.applyTree(Object, (*Node).SortKeys)
+}
+
+// SortTreeKeysFunc sorts keys with less function in current and all children nodes
+func (n *Node) SortTreeKeysFunc(less func(a, b string) bool) error {
+	return n.applyTree(Object, func(node *Node) error {
+		return node.SortKeysFunc(less)
+	})
+}
+
+// SortTreeKeysByOrder applies keys order in current and all children nodes
+func (n *Node) SortTreeKeysByOrder(order []string) error {
+	less := keyOrderLess(order)
+	return n.applyTree(Object, func(node *Node) error {
+		return node.SortKeysFunc(less)
+	})
+}
+
+// applyTree calls fn for all nodes of type typ after their children were visited
+func (n *Node) applyTree(typ Type, fn func(node *Node) error) error {
 	walker, err := NewWalker(n, 0)
 	if err != nil {
 		return err
@@ -308,10 +629,10 @@ func (n *Node) SortTreeKeys() error {
 		if state == WalkDone {
 			break
 		}
-		if !node.IsObject() || state == WalkEnter {
+		if node.Type() != typ || state == WalkEnter {
 			continue
 		}
-		err = node.SortKeys()
+		err = fn(node)
 		if err != nil {
 			return err
 		}
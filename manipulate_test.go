@@ -108,6 +108,124 @@ func TestAppendNull(t *testing.T) {
 	assertEqual(t, `["a","b",null]`, root.Stringify())
 }
 
+func assertChildren(t *testing.T, node *Node) {
+	t.Helper()
+	for i, child := range node.children {
+		assertEqual(t, i, child.idx)
+		assertEqual(t, node, child.parent)
+	}
+}
+
+func TestInsertIdx(t *testing.T) {
+	root, err := ParseString(`[1,2]`)
+	assertParsed(t, root, err)
+	assertNil(t, root.InsertIdx(1, NewString("a")))
+	assertNil(t, root.InsertIdx(3, NewString("z")))
+	assertNil(t, root.Prepend(NewNull()))
+	assertEqual(t, `[null,1,"a",2,"z"]`, root.Stringify())
+	assertChildren(t, root)
+
+	assertNil(t, root.InsertString(0, "s"))
+	assertNil(t, root.InsertBool(0, true))
+	assertNil(t, root.InsertNumber(0, 1.5))
+	assertNil(t, root.InsertInt(0, 7))
+	assertNil(t, root.InsertNull(0))
+	assertEqual(t, `[null,7,1.5,true,"s",null,1,"a",2,"z"]`, root.Stringify())
+	assertChildren(t, root)
+
+	err = root.InsertIdx(0, root.Idx(1))
+	if !errors.Is(err, ErrNodeHasParent) {
+		t.Fatal("expected error ErrNodeHasParent")
+	}
+	err = root.InsertIdx(11, NewNull())
+	if !errors.Is(err, ErrInvalidIndex) {
+		t.Fatal("expected error ErrInvalidIndex")
+	}
+	err = root.InsertIdx(-1, NewNull())
+	if !errors.Is(err, ErrInvalidIndex) {
+		t.Fatal("expected error ErrInvalidIndex")
+	}
+	err = root.InsertIdx(0, nil)
+	if !errors.Is(err, ErrInvalidNodeForOperation) {
+		t.Fatal("expected error ErrInvalidNodeForOperation")
+	}
+	err = NewObject().Prepend(NewNull())
+	if !errors.Is(err, ErrInvalidNodeForOperation) {
+		t.Fatal("expected error ErrInvalidNodeForOperation")
+	}
+}
+
+func TestMoveIdxAndSwap(t *testing.T) {
+	root, err := ParseString(`["a","b","c","d","e"]`)
+	assertParsed(t, root, err)
+	assertNil(t, root.MoveIdx(0, 3))
+	assertEqual(t, `["b","c","d","a","e"]`, root.Stringify())
+	assertChildren(t, root)
+	assertNil(t, root.MoveIdx(4, 1))
+	assertEqual(t, `["b","e","c","d","a"]`, root.Stringify())
+	assertChildren(t, root)
+	assertNil(t, root.MoveIdx(2, 2))
+	assertNil(t, root.Swap(0, 4))
+	assertEqual(t, `["a","e","c","d","b"]`, root.Stringify())
+	assertChildren(t, root)
+
+	err = root.MoveIdx(0, 5)
+	if !errors.Is(err, ErrInvalidIndex) {
+		t.Fatal("expected error ErrInvalidIndex")
+	}
+	err = root.Swap(-1, 0)
+	if !errors.Is(err, ErrInvalidIndex) {
+		t.Fatal("expected error ErrInvalidIndex")
+	}
+	err = NewObject().Swap(0, 0)
+	if !errors.Is(err, ErrInvalidNodeForOperation) {
+		t.Fatal("expected error ErrInvalidNodeForOperation")
+	}
+}
+
+func TestSplice(t *testing.T) {
+	root, err := ParseString(`[0,1,2,3,4]`)
+	assertParsed(t, root, err)
+	removed, err := root.Splice(1, 2, NewString("a"), NewString("b"), NewString("c"))
+	assertNil(t, err)
+	assertEqual(t, `[0,"a","b","c",3,4]`, root.Stringify())
+	assertChildren(t, root)
+	assertEqual(t, 2, len(removed))
+	assertEqual(t, `1`, removed[0].Stringify())
+	assertEqual(t, false, removed[1].Parent().Exists())
+
+	// removed nodes can be linked again
+	removed, err = root.Splice(6, 0, removed...)
+	assertNil(t, err)
+	assertEqual(t, 0, len(removed))
+	assertEqual(t, `[0,"a","b","c",3,4,1,2]`, root.Stringify())
+
+	removed, err = root.Splice(0, 4)
+	assertNil(t, err)
+	assertEqual(t, 4, len(removed))
+	assertEqual(t, `[3,4,1,2]`, root.Stringify())
+	assertChildren(t, root)
+
+	_, err = root.Splice(2, 3)
+	if !errors.Is(err, ErrInvalidIndex) {
+		t.Fatal("expected error ErrInvalidIndex")
+	}
+	_, err = root.Splice(5, 0)
+	if !errors.Is(err, ErrInvalidIndex) {
+		t.Fatal("expected error ErrInvalidIndex")
+	}
+	node := NewNull()
+	_, err = root.Splice(0, 1, node, node)
+	if !errors.Is(err, ErrNodeHasParent) {
+		t.Fatal("expected error ErrNodeHasParent")
+	}
+	_, err = root.Splice(0, 1, root.Idx(1))
+	if !errors.Is(err, ErrNodeHasParent) {
+		t.Fatal("expected error ErrNodeHasParent")
+	}
+	assertEqual(t, `[3,4,1,2]`, root.Stringify())
+}
+
 func TestSet(t *testing.T) {
 	root, err := ParseString(`{"a":1}`)
 	assertParsed(t, root, err)
@@ -129,7 +247,10 @@ func TestSet(t *testing.T) {
 	assertEqual(t, 1, root.value.(keymap)["k"])
 	assertEqual(t, `{"a":1,"k":"aa"}`, root.Stringify())
 
-	err = root.Set("kk", node)
+	// replaced node is unlinked and can be set again
+	assertNil(t, node.parent)
+	assertNil(t, root.Set("kk", node))
+	err = root.Set("kkk", node2)
 	if !errors.Is(err, ErrNodeHasParent) {
 		t.Fatal("expected error ErrNodeHasParent")
 	}
@@ -351,3 +472,120 @@ func TestSortTreeKeys(t *testing.T) {
 	assertNil(t, err)
 	assertEqual(t, `[1,{"a":"a","b":{"f":"f","n":"n"},"c":"c","d":"d"}]`, root.Stringify())
 }
+
+func assertKeys(t *testing.T, node *Node) {
+	t.Helper()
+	assertChildren(t, node)
+	kmap := node.value.(keymap)
+	assertEqual(t, len(node.children), len(kmap))
+	for i, child := range node.children {
+		assertEqual(t, i, kmap[child.key])
+	}
+}
+
+func TestRenameKey(t *testing.T) {
+	root, err := ParseString(`{"a":1,"b":2,"c":3}`)
+	assertParsed(t, root, err)
+	assertNil(t, root.RenameKey("b", "x"))
+	assertEqual(t, `{"a":1,"x":2,"c":3}`, root.Stringify())
+	assertKeys(t, root)
+	assertEqual(t, undef, root.Key("b"))
+	assertNil(t, root.RenameKey("a", "a"))
+
+	err = root.RenameKey("a", "c")
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Fatal("expected error ErrDuplicateKey")
+	}
+	err = root.RenameKey("b", "y")
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Fatal("expected error ErrInvalidKey")
+	}
+	err = NewArray().RenameKey("a", "b")
+	if !errors.Is(err, ErrInvalidNodeForOperation) {
+		t.Fatal("expected error ErrInvalidNodeForOperation")
+	}
+}
+
+func TestInsertKey(t *testing.T) {
+	root, err := ParseString(`{"a":1,"c":3}`)
+	assertParsed(t, root, err)
+	assertNil(t, root.InsertKeyAt(1, "b", NewInt(2)))
+	assertNil(t, root.InsertKeyAt(0, "first", NewNull()))
+	assertNil(t, root.InsertKeyBefore("c", "bb", NewBool(true)))
+	assertNil(t, root.InsertKeyAfter("c", "last", NewString("z")))
+	assertEqual(t, `{"first":null,"a":1,"b":2,"bb":true,"c":3,"last":"z"}`, root.Stringify())
+	assertKeys(t, root)
+	assertEqual(t, "$.bb", root.Key("bb").SelfPath())
+
+	err = root.InsertKeyAt(0, "a", NewNull())
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Fatal("expected error ErrDuplicateKey")
+	}
+	err = root.InsertKeyAt(7, "x", NewNull())
+	if !errors.Is(err, ErrInvalidIndex) {
+		t.Fatal("expected error ErrInvalidIndex")
+	}
+	err = root.InsertKeyAfter("missing", "x", NewNull())
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Fatal("expected error ErrInvalidKey")
+	}
+	err = root.InsertKeyBefore("a", "x", root.Key("b"))
+	if !errors.Is(err, ErrNodeHasParent) {
+		t.Fatal("expected error ErrNodeHasParent")
+	}
+	err = NewArray().InsertKeyAt(0, "x", NewNull())
+	if !errors.Is(err, ErrInvalidNodeForOperation) {
+		t.Fatal("expected error ErrInvalidNodeForOperation")
+	}
+}
+
+func TestMoveKey(t *testing.T) {
+	root, err := ParseString(`{"a":1,"b":2,"c":3,"d":4}`)
+	assertParsed(t, root, err)
+	assertNil(t, root.MoveKey("a", 2))
+	assertEqual(t, `{"b":2,"c":3,"a":1,"d":4}`, root.Stringify())
+	assertKeys(t, root)
+	assertNil(t, root.MoveKey("d", 0))
+	assertEqual(t, `{"d":4,"b":2,"c":3,"a":1}`, root.Stringify())
+	assertKeys(t, root)
+
+	err = root.MoveKey("d", 4)
+	if !errors.Is(err, ErrInvalidIndex) {
+		t.Fatal("expected error ErrInvalidIndex")
+	}
+	err = root.MoveKey("x", 0)
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Fatal("expected error ErrInvalidKey")
+	}
+}
+
+func TestSortKeysFunc(t *testing.T) {
+	root, err := ParseString(`{"bb":1,"a":2,"ccc":3,"d":4}`)
+	assertParsed(t, root, err)
+	assertNil(t, root.SortKeysFunc(func(a, b string) bool {
+		return len(a) > len(b)
+	}))
+	assertEqual(t, `{"ccc":3,"bb":1,"a":2,"d":4}`, root.Stringify())
+	assertKeys(t, root)
+
+	assertNil(t, root.SortKeysByOrder([]string{"d", "missing", "bb"}))
+	assertEqual(t, `{"d":4,"bb":1,"ccc":3,"a":2}`, root.Stringify())
+	assertKeys(t, root)
+
+	err = NewArray().SortKeysByOrder(nil)
+	if !errors.Is(err, ErrInvalidNodeForOperation) {
+		t.Fatal("expected error ErrInvalidNodeForOperation")
+	}
+}
+
+func TestSortTreeKeysByOrder(t *testing.T) {
+	root, err := ParseString(`[{"name":"a","id":1,"items":[{"price":1,"id":2}]},{"x":0,"id":3}]`)
+	assertParsed(t, root, err)
+	assertNil(t, root.SortTreeKeysByOrder([]string{"id", "name"}))
+	assertEqual(t, `[{"id":1,"name":"a","items":[{"id":2,"price":1}]},{"id":3,"x":0}]`, root.Stringify())
+
+	assertNil(t, root.SortTreeKeysFunc(func(a, b string) bool {
+		return a > b
+	}))
+	assertEqual(t, `[{"name":"a","items":[{"price":1,"id":2}],"id":1},{"x":0,"id":3}]`, root.Stringify())
+}
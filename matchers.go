@@ -0,0 +1,335 @@
+package xtjson
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TypeIs matches nodes of any of provided types
+func TypeIs(types ...Type) NodeMatcher {
+	return NodeMatcherFunc(func(node *Node) bool {
+		t := node.Type()
+		for _, v := range types {
+			if t == v {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// KeyRegexp matches object properties which keys match regular expression
+func KeyRegexp(expr string) (NodeMatcher, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRegexp, err.Error())
+	}
+	return NodeMatcherFunc(func(node *Node) bool {
+		if node == nil || !node.parent.IsObject() {
+			return false
+		}
+		return re.MatchString(node.key)
+	}), nil
+}
+
+// ValueRegexp matches string nodes which values match regular expression
+func ValueRegexp(expr string) (NodeMatcher, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRegexp, err.Error())
+	}
+	return NodeMatcherFunc(func(node *Node) bool {
+		if node == nil {
+			return false
+		}
+		v, ok := node.value.(string)
+		return ok && re.MatchString(v)
+	}), nil
+}
+
+// ValueEquals matches nodes equal to provided value, which can be nil, bool,
+// string, any go number type or *Node compared deeply
+func ValueEquals(value any) NodeMatcher {
+	expected := valueNode(value)
+	return NodeMatcherFunc(func(node *Node) bool {
+		return node.Exists() && expected != nil && equalNodes(expected, node)
+	})
+}
+
+// valueNode converts go scalar value to node, nil is returned for unsupported types
+func valueNode(value any) *Node {
+	switch v := value.(type) {
+	case nil:
+		return NewNull()
+	case *Node:
+		return v
+	case string:
+		return NewString(v)
+	case bool:
+		return NewBool(v)
+	case float64:
+		return NewNumber(v)
+	case float32:
+		return NewNumber(float64(v))
+	case int:
+		return NewInt(v)
+	case int8:
+		return NewInt(int(v))
+	case int16:
+		return NewInt(int(v))
+	case int32:
+		return NewInt(int(v))
+	case int64:
+		return NewNumber(float64(v))
+	case uint:
+		return NewNumber(float64(v))
+	case uint8:
+		return NewInt(int(v))
+	case uint16:
+		return NewInt(int(v))
+	case uint32:
+		return NewNumber(float64(v))
+	case uint64:
+		return NewNumber(float64(v))
+	}
+	return nil
+}
+
+// NumberRange matches numeric nodes within inclusive range
+func NumberRange(min, max float64) NodeMatcher {
+	return NodeMatcherFunc(func(node *Node) bool {
+		v, err := node.Number()
+		return err == nil && v >= min && v <= max
+	})
+}
+
+// HasKeys matches objects containing all provided keys
+func HasKeys(keys ...string) NodeMatcher {
+	return NodeMatcherFunc(func(node *Node) bool {
+		if !node.IsObject() {
+			return false
+		}
+		kmap := node.value.(keymap)
+		for _, key := range keys {
+			if _, ok := kmap[key]; !ok {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Depth matches nodes located on provided deep level
+func Depth(level int) NodeMatcher {
+	return NodeMatcherFunc(func(node *Node) bool {
+		return node.Exists() && node.Level() == level
+	})
+}
+
+// compileKeyGlob converts shell pattern with *, ? and [...] to regular expression matching whole key.
+// Unlike path.Match the / is an ordinary character, keys are not file paths.
+func compileKeyGlob(pattern string, foldCase bool) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("(?s")
+	if foldCase {
+		b.WriteString("i")
+	}
+	b.WriteString(")^")
+	rs := []rune(pattern)
+	for i := 0; i < len(rs); i++ {
+		switch rs[i] {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '\\':
+			i++
+			if i == len(rs) {
+				return nil, errors.New("trailing escape")
+			}
+			b.WriteString(regexp.QuoteMeta(string(rs[i])))
+		case '[':
+			b.WriteString("[")
+			i++
+			if i < len(rs) && (rs[i] == '!' || rs[i] == '^') {
+				b.WriteString("^")
+				i++
+			}
+			start := i
+			for ; i < len(rs) && rs[i] != ']'; i++ {
+				switch rs[i] {
+				case '\\':
+					i++
+					if i == len(rs) {
+						return nil, errors.New("trailing escape")
+					}
+					b.WriteString(regexp.QuoteMeta(string(rs[i])))
+				case '[', '^':
+					b.WriteString(`\` + string(rs[i]))
+				default:
+					b.WriteRune(rs[i])
+				}
+			}
+			if i == len(rs) || i == start {
+				return nil, errors.New("bad character class")
+			}
+			b.WriteString("]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(rs[i])))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+type globToken struct {
+	pattern *regexp.Regexp
+	idx     int
+	isIdx   bool
+	any     bool // * matches exactly one key or index
+	deep    bool // ** matches any number of keys or indexes
+}
+
+// parseGlob splits path pattern like $.items[*].**.id to tokens
+func parseGlob(pattern string) ([]globToken, error) {
+	if len(pattern) == 0 || pattern[0] != '$' {
+		return nil, ErrBadPath
+	}
+	tokens := []globToken{}
+	rs := []rune(pattern[1:])
+	for i := 0; i < len(rs); {
+		switch rs[i] {
+		case '.':
+			j := i + 1
+			for j < len(rs) && rs[j] != '.' && rs[j] != '[' && rs[j] != ']' {
+				j++
+			}
+			key := string(rs[i+1 : j])
+			switch key {
+			case "":
+				return nil, fmt.Errorf("%w: empty key in %s", ErrBadPath, pattern)
+			case "*":
+				tokens = append(tokens, globToken{any: true})
+			case "**":
+				tokens = append(tokens, globToken{deep: true})
+			default:
+				re, err := compileKeyGlob(key, false)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %s", ErrBadPath, pattern)
+				}
+				tokens = append(tokens, globToken{pattern: re})
+			}
+			i = j
+		case '[':
+			j := i + 1
+			for j < len(rs) && rs[j] != ']' {
+				j++
+			}
+			if j == len(rs) {
+				return nil, fmt.Errorf("%w: unclosed bracket in %s", ErrBadPath, pattern)
+			}
+			token := string(rs[i+1 : j])
+			if token == "*" {
+				tokens = append(tokens, globToken{any: true, isIdx: true})
+			} else {
+				idx, err := strconv.Atoi(token)
+				if err != nil || idx < 0 {
+					return nil, fmt.Errorf("%w: invalid index in %s", ErrBadPath, pattern)
+				}
+				tokens = append(tokens, globToken{idx: idx, isIdx: true})
+			}
+			i = j + 1
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrBadPath, pattern)
+		}
+	}
+	return tokens, nil
+}
+
+func (t globToken) match(node *Node) bool {
+	if t.any && !t.isIdx {
+		return true
+	}
+	if t.isIdx {
+		return node.parent.IsArray() && (t.any || node.idx == t.idx)
+	}
+	if !node.parent.IsObject() {
+		return false
+	}
+	return t.pattern.MatchString(node.key)
+}
+
+// matchGlob checks chain of nodes from top to bottom against pattern tokens
+func matchGlob(tokens []globToken, chain []*Node) bool {
+	if len(tokens) == 0 {
+		return len(chain) == 0
+	}
+	if tokens[0].deep {
+		for i := 0; i <= len(chain); i++ {
+			if matchGlob(tokens[1:], chain[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(chain) == 0 || !tokens[0].match(chain[0]) {
+		return false
+	}
+	return matchGlob(tokens[1:], chain[1:])
+}
+
+// PathGlob matches nodes which absolute path matches the pattern.
+// The pattern uses path syntax where * stands for any single key or index,
+// ** for any number of keys or indexes, [*] for any index,
+// and keys can contain shell patterns like $.user_*.id where / is an ordinary character
+func PathGlob(pattern string) (NodeMatcher, error) {
+	tokens, err := parseGlob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return NodeMatcherFunc(func(node *Node) bool {
+		if !node.Exists() {
+			return false
+		}
+		chain := make([]*Node, node.Level())
+		for i := len(chain) - 1; i >= 0; i-- {
+			chain[i] = node
+			node = node.parent
+		}
+		return matchGlob(tokens, chain)
+	}), nil
+}
+
+// And matches nodes matched by all matchers
+func And(matchers ...NodeMatcher) NodeMatcher {
+	return NodeMatcherFunc(func(node *Node) bool {
+		for _, m := range matchers {
+			if !m.Match(node) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Or matches nodes matched by any of matchers
+func Or(matchers ...NodeMatcher) NodeMatcher {
+	return NodeMatcherFunc(func(node *Node) bool {
+		for _, m := range matchers {
+			if m.Match(node) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Not inverts the matcher
+func Not(matcher NodeMatcher) NodeMatcher {
+	return NodeMatcherFunc(func(node *Node) bool {
+		return !matcher.Match(node)
+	})
+}
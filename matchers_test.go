@@ -0,0 +1,116 @@
+package xtjson
+
+import (
+	"errors"
+	"testing"
+)
+
+const matchersJson = `{"id":"root","items":[{"id":1,"name":"one","price":9.5},{"id":2,"name":"two","price":20},{"id":"3","tags":["a","b"]}],"user_a":{"id":10},"user_b":{"id":11},"token":"eyJhbGciOi"}`
+
+func searchAll(t *testing.T, m NodeMatcher) string {
+	t.Helper()
+	root, err := ParseString(matchersJson)
+	assertParsed(t, root, err)
+	ns, err := root.Search(m, &SearchOptions{})
+	assertNil(t, err)
+	return ns.ToArray().Stringify()
+}
+
+func TestTypeIs(t *testing.T) {
+	assertEqual(t, `[1,9.5,2,20,10,11]`, searchAll(t, TypeIs(Number)))
+	assertEqual(t, `[["a","b"]]`, searchAll(t, And(TypeIs(Array, Bool), Depth(3))))
+}
+
+func TestKeyRegexp(t *testing.T) {
+	m, err := KeyRegexp("^user_")
+	assertNil(t, err)
+	assertEqual(t, `[{"id":10},{"id":11}]`, searchAll(t, m))
+
+	// root and array elements have empty keys
+	m, err = KeyRegexp("^$")
+	assertNil(t, err)
+	root := parseNode(t, `[{"":1}]`)
+	assertEqual(t, false, m.Match(root))
+	assertEqual(t, false, m.Match(root.Idx(0)))
+	assertEqual(t, true, m.Match(root.Idx(0).Key("")))
+	assertEqual(t, false, m.Match(undef))
+	_, err = KeyRegexp("(")
+	if !errors.Is(err, ErrInvalidRegexp) {
+		t.Fatal("expected error ErrInvalidRegexp")
+	}
+}
+
+func TestValueRegexp(t *testing.T) {
+	m, err := ValueRegexp("^eyJ")
+	assertNil(t, err)
+	assertEqual(t, `["eyJhbGciOi"]`, searchAll(t, m))
+	_, err = ValueRegexp("[")
+	if !errors.Is(err, ErrInvalidRegexp) {
+		t.Fatal("expected error ErrInvalidRegexp")
+	}
+}
+
+func TestValueEquals(t *testing.T) {
+	assertEqual(t, `[2]`, searchAll(t, ValueEquals(2)))
+	assertEqual(t, `["3"]`, searchAll(t, ValueEquals("3")))
+	assertEqual(t, `[9.5]`, searchAll(t, ValueEquals(float32(9.5))))
+	tags, err := ParseString(`["a","b"]`)
+	assertParsed(t, tags, err)
+	assertEqual(t, `[["a","b"]]`, searchAll(t, ValueEquals(tags)))
+	assertEqual(t, `[]`, searchAll(t, ValueEquals(struct{}{})))
+}
+
+func TestNumberRange(t *testing.T) {
+	assertEqual(t, `[9.5,2,10,11]`, searchAll(t, NumberRange(2, 11)))
+}
+
+func TestHasKeys(t *testing.T) {
+	assertEqual(t, `[{"id":1,"name":"one","price":9.5},{"id":2,"name":"two","price":20}]`, searchAll(t, HasKeys("id", "price")))
+}
+
+func TestDepth(t *testing.T) {
+	assertEqual(t, `["root",[{"id":1,"name":"one","price":9.5},{"id":2,"name":"two","price":20},{"id":"3","tags":["a","b"]}],{"id":10},{"id":11},"eyJhbGciOi"]`, searchAll(t, Depth(1)))
+}
+
+func TestPathGlob(t *testing.T) {
+	m, err := PathGlob("$.items.*.id")
+	assertNil(t, err)
+	assertEqual(t, `[1,2,"3"]`, searchAll(t, m))
+
+	m, err = PathGlob("$.items[1].*")
+	assertNil(t, err)
+	assertEqual(t, `[2,"two",20]`, searchAll(t, m))
+
+	m, err = PathGlob("$.user_*.id")
+	assertNil(t, err)
+	assertEqual(t, `[10,11]`, searchAll(t, m))
+
+	root, err := ParseString(`{"a/b":1,"ab":2,"a":{"b":3}}`)
+	assertParsed(t, root, err)
+	m, err = PathGlob("$.a*")
+	assertNil(t, err)
+	ns, err := root.Search(m, nil)
+	assertNil(t, err)
+	assertEqual(t, `[1,2,{"b":3}]`, ns.ToArray().Stringify())
+
+	m, err = PathGlob("$.**.id")
+	assertNil(t, err)
+	assertEqual(t, `["root",1,2,"3",10,11]`, searchAll(t, m))
+
+	m, err = PathGlob("$.**[*]")
+	assertNil(t, err)
+	assertEqual(t, `[{"id":1,"name":"one","price":9.5},{"id":2,"name":"two","price":20},{"id":"3","tags":["a","b"]},"a","b"]`, searchAll(t, m))
+
+	for _, pattern := range []string{"", "items", "$..id", "$[x]", "$.[", "$.a["} {
+		_, err = PathGlob(pattern)
+		if !errors.Is(err, ErrBadPath) {
+			t.Fatalf("expected error ErrBadPath for %s", pattern)
+		}
+	}
+}
+
+func TestCombinators(t *testing.T) {
+	assertEqual(t, `[1,2,10,11]`, searchAll(t, And(TypeIs(Number), Not(NumberRange(9, 9.9)), Not(NumberRange(20, 20)))))
+	assertEqual(t, `["one",20]`, searchAll(t, Or(ValueEquals("one"), ValueEquals(20))))
+	assertEqual(t, `[]`, searchAll(t, Or()))
+}
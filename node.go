@@ -1,6 +1,11 @@
 package xtjson
 
-import "errors"
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"strings"
+)
 
 var (
 	ErrNodeDoesNotExist  = errors.New("node does not exist")
@@ -36,6 +41,7 @@ type Node struct {
 	key      string
 	value    any
 	children []*Node
+	hooks    *hooks
 }
 
 type keymap map[string]int
@@ -304,3 +310,111 @@ func (n *Node) Int() (int, error) {
 	}
 	return v, nil
 }
+
+// Equal compares node values deeply, object keys order is not significant
+func Equal(a, b *Node) bool {
+	return equalNodes(a, b)
+}
+
+// equalNodes compares node values deeply, object keys order is not significant
+func equalNodes(a, b *Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ta := a.Type()
+	if ta != b.Type() {
+		return false
+	}
+	switch ta {
+	case Array:
+		if len(a.children) != len(b.children) {
+			return false
+		}
+		for i, child := range a.children {
+			if !equalNodes(child, b.children[i]) {
+				return false
+			}
+		}
+		return true
+	case Object:
+		if len(a.children) != len(b.children) {
+			return false
+		}
+		for _, child := range a.children {
+			if !equalNodes(child, b.Key(child.key)) {
+				return false
+			}
+		}
+		return true
+	}
+	return a.value == b.value
+}
+
+var typeRank = map[Type]int{Undefined: 0, Null: 1, Bool: 2, Number: 3, String: 4, Array: 5, Object: 6}
+
+// compareNodes orders nodes by type first: undefined, null, bool, number, string, array, object
+// and then by value. Arrays are compared element by element, objects by sorted keys and values
+func compareNodes(a, b *Node) int {
+	ta, tb := a.Type(), b.Type()
+	if ta != tb {
+		return cmp.Compare(typeRank[ta], typeRank[tb])
+	}
+	switch ta {
+	case Bool:
+		va, vb := a.value.(bool), b.value.(bool)
+		if va == vb {
+			return 0
+		}
+		if vb {
+			return -1
+		}
+		return 1
+	case Number:
+		return cmp.Compare(a.value.(float64), b.value.(float64))
+	case String:
+		return strings.Compare(a.value.(string), b.value.(string))
+	case Array:
+		for i := 0; i < len(a.children) && i < len(b.children); i++ {
+			if c := compareNodes(a.children[i], b.children[i]); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(len(a.children), len(b.children))
+	case Object:
+		ka, kb := a.ChildrenKeys(), b.ChildrenKeys()
+		slices.Sort(ka)
+		slices.Sort(kb)
+		for i := 0; i < len(ka) && i < len(kb); i++ {
+			if c := strings.Compare(ka[i], kb[i]); c != 0 {
+				return c
+			}
+			if c := compareNodes(a.Key(ka[i]), b.Key(kb[i])); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(len(ka), len(kb))
+	}
+	return 0
+}
+
+// Value returns node value converted to go type: nil, bool, float64, string,
+// []any for arrays and map[string]any for objects, nil is returned for undefined node
+func (n *Node) Value() any {
+	switch n.Type() {
+	case String, Bool, Number:
+		return n.value
+	case Array:
+		ret := make([]any, len(n.children))
+		for i, child := range n.children {
+			ret[i] = child.Value()
+		}
+		return ret
+	case Object:
+		ret := make(map[string]any, len(n.children))
+		for _, child := range n.children {
+			ret[child.key] = child.Value()
+		}
+		return ret
+	}
+	return nil
+}
@@ -252,3 +252,14 @@ func TestInt(t *testing.T) {
 	assertEqual(t, ErrNodeDoesNotExist, err)
 	assertEqual(t, 0, v)
 }
+
+func TestEqual(t *testing.T) {
+	a, err := ParseString(`{"a":[1,{"b":null}],"c":"s"}`)
+	assertParsed(t, a, err)
+	b, err := ParseString(`{"c":"s","a":[1.0,{"b":null}]}`)
+	assertParsed(t, b, err)
+	assertEqual(t, true, Equal(a, b))
+	assertEqual(t, false, Equal(a.Key("a"), b.Key("c")))
+	assertEqual(t, false, Equal(a, undef))
+	assertEqual(t, true, Equal(a.Key("x"), b.Key("x")))
+}
@@ -1,7 +1,11 @@
 package xtjson
 
 import (
+	"context"
 	"errors"
+	"slices"
+	"strconv"
+	"strings"
 )
 
 var (
@@ -11,15 +15,27 @@ var (
 // Nodes represents list of nodes
 type Nodes []*Node
 
-// Search runs the Search method on all nodes and combines the output
+// Search runs the Search method on all nodes and combines the output,
+// result limits of options apply to the combined output
 func (ns Nodes) Search(matcher NodeMatcher, opt *SearchOptions) (Nodes, error) {
+	return ns.SearchContext(context.Background(), matcher, opt)
+}
+
+// SearchContext runs the SearchContext method on all nodes and combines the output
+func (ns Nodes) SearchContext(ctx context.Context, matcher NodeMatcher, opt *SearchOptions) (Nodes, error) {
 	ret := make(Nodes, 0)
+	if opt == nil {
+		opt = &SearchOptions{SkipNested: true}
+	}
+	limit := opt.limit()
 	for _, node := range ns {
-		result, err := node.Search(matcher, opt)
-		if err != nil {
+		var err error
+		if ret, err = node.search(ctx, matcher, opt, ret, limit); err != nil {
 			return ret, err
 		}
-		ret = append(ret, result...)
+		if limit > 0 && len(ret) >= limit {
+			break
+		}
 	}
 	return ret, nil
 }
@@ -59,3 +75,201 @@ func (ns Nodes) ToArray(opts ...*Format) *Node {
 	}
 	return root
 }
+
+// SortOrder defines the direction of sorting
+type SortOrder int
+
+const (
+	SortAsc SortOrder = iota
+	SortDesc
+)
+
+// Filter returns nodes matched by provided matcher
+func (ns Nodes) Filter(matcher NodeMatcher) Nodes {
+	ret := make(Nodes, 0)
+	for _, node := range ns {
+		if matcher.Match(node) {
+			ret = append(ret, node)
+		}
+	}
+	return ret
+}
+
+// First returns the first node or node of type Undefined if list is empty
+func (ns Nodes) First() *Node {
+	if len(ns) == 0 {
+		return undef
+	}
+	return ns[0]
+}
+
+// Last returns the last node or node of type Undefined if list is empty
+func (ns Nodes) Last() *Node {
+	if len(ns) == 0 {
+		return undef
+	}
+	return ns[len(ns)-1]
+}
+
+// Take returns up to count first nodes
+func (ns Nodes) Take(count int) Nodes {
+	count = min(max(count, 0), len(ns))
+	ret := make(Nodes, count)
+	copy(ret, ns[:count])
+	return ret
+}
+
+// Skip returns nodes following the first count nodes
+func (ns Nodes) Skip(count int) Nodes {
+	count = min(max(count, 0), len(ns))
+	ret := make(Nodes, len(ns)-count)
+	copy(ret, ns[count:])
+	return ret
+}
+
+// SortBy returns nodes stable sorted by values referenced by path, $ refers to node itself.
+// Values of different types are ordered: missing, null, bool, number, string, array, object
+func (ns Nodes) SortBy(path string, order SortOrder) Nodes {
+	ret := make(Nodes, len(ns))
+	copy(ret, ns)
+	slices.SortStableFunc(ret, func(a, b *Node) int {
+		c := compareNodes(a.Path(path), b.Path(path))
+		if order == SortDesc {
+			return -c
+		}
+		return c
+	})
+	return ret
+}
+
+// Distinct returns nodes with unique values referenced by path keeping the first occurrence
+func (ns Nodes) Distinct(path string) Nodes {
+	ret := make(Nodes, 0)
+	seen := make(map[string]bool)
+	for _, node := range ns {
+		key := valueKey(node.Path(path))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		ret = append(ret, node)
+	}
+	return ret
+}
+
+// groupKey returns raw string value or canonical json representation of other values,
+// the empty string is used for missing values
+func groupKey(node *Node) string {
+	if v, ok := node.value.(string); ok {
+		return v
+	}
+	return valueKey(node)
+}
+
+// valueKey returns json representation of value with object keys in sorted order, so equal
+// values have the same key and string "1" differs from number 1. Missing values have empty key.
+func valueKey(node *Node) string {
+	if !node.Exists() {
+		return ""
+	}
+	var b strings.Builder
+	writeValueKey(&b, node)
+	return b.String()
+}
+
+func writeValueKey(b *strings.Builder, node *Node) {
+	switch node.Type() {
+	case Array:
+		b.WriteByte('[')
+		for i, child := range node.children {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeValueKey(b, child)
+		}
+		b.WriteByte(']')
+	case Object:
+		children := slices.Clone(node.children)
+		slices.SortFunc(children, func(a, b *Node) int { return strings.Compare(a.key, b.key) })
+		b.WriteByte('{')
+		for i, child := range children {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.Quote(child.key))
+			b.WriteByte(':')
+			writeValueKey(b, child)
+		}
+		b.WriteByte('}')
+	default:
+		b.WriteString(stringifyScalar(node))
+	}
+}
+
+// Groups represents nodes split by group key
+type Groups map[string]Nodes
+
+// GroupBy splits nodes to groups by values referenced by path. Group key is the raw string
+// for string values and json representation for others with object keys sorted, so string "1"
+// and number 1 share the group. Missing values are grouped by empty key
+func (ns Nodes) GroupBy(path string) Groups {
+	ret := make(Groups)
+	for _, node := range ns {
+		key := groupKey(node.Path(path))
+		ret[key] = append(ret[key], node)
+	}
+	return ret
+}
+
+// Values returns node values converted to go types
+func (ns Nodes) Values() []any {
+	ret := make([]any, len(ns))
+	for i, node := range ns {
+		ret[i] = node.Value()
+	}
+	return ret
+}
+
+// Strings returns values of string nodes skipping others
+func (ns Nodes) Strings() []string {
+	ret := make([]string, 0, len(ns))
+	for _, node := range ns {
+		if v, err := node.String(); err == nil {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// Numbers returns values of numeric nodes skipping others
+func (ns Nodes) Numbers() []float64 {
+	ret := make([]float64, 0, len(ns))
+	for _, node := range ns {
+		if v, err := node.Number(); err == nil {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// Ints returns values of numeric nodes which can be converted to integer skipping others
+func (ns Nodes) Ints() []int {
+	ret := make([]int, 0, len(ns))
+	for _, node := range ns {
+		if v, err := node.Int(); err == nil {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// Bools returns values of boolean nodes skipping others
+func (ns Nodes) Bools() []bool {
+	ret := make([]bool, 0, len(ns))
+	for _, node := range ns {
+		if v, err := node.Bool(); err == nil {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
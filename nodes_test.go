@@ -0,0 +1,88 @@
+package xtjson
+
+import (
+	"testing"
+)
+
+const nodesJson = `[{"id":1,"tag":"b","price":5},{"id":2,"tag":"a","price":1.5},{"id":3,"tag":"b"},{"id":4,"tag":"a","price":"n/a"},{"id":5,"price":null}]`
+
+func parseNodes(t *testing.T, s string) Nodes {
+	t.Helper()
+	root, err := ParseString(s)
+	assertParsed(t, root, err)
+	return Nodes(root.Children())
+}
+
+func TestNodesFilter(t *testing.T) {
+	ns := parseNodes(t, nodesJson)
+	assertEqual(t, `[{"id":1,"tag":"b","price":5},{"id":3,"tag":"b"}]`, ns.Filter(HasKeys("tag")).Filter(NodeMatcherFunc(func(n *Node) bool {
+		return n.Key("tag").value == "b"
+	})).ToArray().Stringify())
+	assertEqual(t, 0, len(Nodes{}.Filter(HasKeys("tag"))))
+}
+
+func TestNodesFirstLast(t *testing.T) {
+	ns := parseNodes(t, nodesJson)
+	assertInt(t, 1, ns.First().Key("id"))
+	assertInt(t, 5, ns.Last().Key("id"))
+	assertEqual(t, undef, Nodes{}.First())
+	assertEqual(t, undef, Nodes(nil).Last())
+}
+
+func TestNodesTakeSkip(t *testing.T) {
+	ns := parseNodes(t, `[1,2,3,4]`)
+	assertEqual(t, `[1,2]`, ns.Take(2).ToArray().Stringify())
+	assertEqual(t, `[1,2,3,4]`, ns.Take(10).ToArray().Stringify())
+	assertEqual(t, `[]`, ns.Take(-1).ToArray().Stringify())
+	assertEqual(t, `[3,4]`, ns.Skip(2).ToArray().Stringify())
+	assertEqual(t, `[]`, ns.Skip(10).ToArray().Stringify())
+	assertEqual(t, `[2,3]`, ns.Skip(1).Take(2).ToArray().Stringify())
+}
+
+func TestNodesSortBy(t *testing.T) {
+	ns := parseNodes(t, nodesJson)
+	assertEqual(t, []int{3, 5, 2, 1, 4}, ns.SortBy("$.price", SortAsc).Path("$.id").Ints())
+	assertEqual(t, []int{4, 1, 2, 5, 3}, ns.SortBy("$.price", SortDesc).Path("$.id").Ints())
+	assertEqual(t, []int{5, 2, 4, 1, 3}, ns.SortBy("$.tag", SortAsc).Path("$.id").Ints())
+
+	ns = parseNodes(t, `[[1,2],"b",{"a":1},true,[1],null,{"a":0,"b":0},false,2,"a"]`)
+	assertEqual(t, `[null,false,true,2,"a","b",[1],[1,2],{"a":0,"b":0},{"a":1}]`, ns.SortBy("$", SortAsc).ToArray().Stringify())
+}
+
+func TestNodesDistinct(t *testing.T) {
+	ns := parseNodes(t, nodesJson)
+	assertEqual(t, []int{1, 2, 5}, ns.Distinct("$.tag").Path("$.id").Ints())
+	ns = parseNodes(t, `[{"a":[1,{"b":2}]},{"a":[1,{"b":2}]},{"a":[1]},{"a":1},{"a":"1"}]`)
+	assertEqual(t, `[{"a":[1,{"b":2}]},{"a":[1]},{"a":1},{"a":"1"}]`, ns.Distinct("$.a").ToArray().Stringify())
+	ns = parseNodes(t, `[{"a":{"x":1,"y":2}},{"a":{"y":2,"x":1}},{"a":true},{"a":"true"},{"a":1.0},{"a":1},{"b":1},{"b":2}]`)
+	assertEqual(t, `[{"a":{"x":1,"y":2}},{"a":true},{"a":"true"},{"a":1},{"b":1}]`, ns.Distinct("$.a").ToArray().Stringify())
+}
+
+func TestNodesGroupBy(t *testing.T) {
+	ns := parseNodes(t, nodesJson)
+	groups := ns.GroupBy("$.tag")
+	assertEqual(t, 3, len(groups))
+	assertEqual(t, []int{1, 3}, groups["b"].Path("$.id").Ints())
+	assertEqual(t, []int{2, 4}, groups["a"].Path("$.id").Ints())
+	assertEqual(t, []int{5}, groups[""].Path("$.id").Ints())
+
+	groups = ns.GroupBy("$.price")
+	assertEqual(t, []int{5}, groups["null"].Path("$.id").Ints())
+	assertEqual(t, []int{2}, groups["1.5"].Path("$.id").Ints())
+
+	// strings are keyed by raw value, objects by json with sorted keys
+	groups = parseNodes(t, `[{"v":"1"},{"v":1},{"v":"x"},{"v":{"a":1,"b":2}},{"v":{"b":2,"a":1}}]`).GroupBy("$.v")
+	assertEqual(t, 3, len(groups))
+	assertEqual(t, 2, len(groups["1"]))
+	assertEqual(t, 1, len(groups["x"]))
+	assertEqual(t, 2, len(groups[`{"a":1,"b":2}`]))
+}
+
+func TestNodesValues(t *testing.T) {
+	ns := parseNodes(t, `["a",1,2.5,true,null,[1,"x"],{"k":false}]`)
+	assertEqual(t, []any{"a", 1.0, 2.5, true, nil, []any{1.0, "x"}, map[string]any{"k": false}}, ns.Values())
+	assertEqual(t, []string{"a"}, ns.Strings())
+	assertEqual(t, []float64{1, 2.5}, ns.Numbers())
+	assertEqual(t, []int{1}, ns.Ints())
+	assertEqual(t, []bool{true}, ns.Bools())
+}
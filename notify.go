@@ -0,0 +1,122 @@
+package xtjson
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+type mutationOp int
+
+const (
+	opAdd mutationOp = iota
+	opReplace
+	opRemove
+	opReorder // children order changed, old and node are nil, before keeps the previous order
+	opRename  // key of node changed, old is nil
+)
+
+// mutation describes the single change of parent children made by manipulation methods
+type mutation struct {
+	op     mutationOp
+	parent *Node
+	old    *Node  // replaced or removed node
+	node   *Node  // added or replacing node
+	oldKey string // previous key of renamed node
+	before []*Node
+}
+
+// observing counts subscriptions in all trees, mutations skip the walk over
+// ancestors while it is zero, so unobserved trees are modified in constant time.
+// The count is global because observed subtrees can be moved between trees.
+var observing atomic.Int32
+
+type observer struct {
+	node *Node
+	fn   func(mutation)
+}
+
+// hooks keeps observers subscribed to the node and the batch started on the root of the tree.
+// They are allocated on the first subscription and are released together with the node,
+// so a forgotten subscription does not outlive the tree.
+type hooks struct {
+	sync.Mutex
+	active    atomic.Int32
+	observers []*observer
+	batch     *changeBatch
+}
+
+// hooksOf returns hooks of node allocating them when necessary,
+// subscriptions must not be made concurrently with modifications of the tree
+func (n *Node) hooksOf() *hooks {
+	if n.hooks == nil {
+		n.hooks = &hooks{}
+	}
+	return n.hooks
+}
+
+// observe subscribes fn to mutations made anywhere under node
+func observe(node *Node, fn func(mutation)) *observer {
+	o := &observer{node: node, fn: fn}
+	h := node.hooksOf()
+	h.Lock()
+	defer h.Unlock()
+	h.observers = append(h.observers, o)
+	h.active.Add(1)
+	observing.Add(1)
+	return o
+}
+
+// stop unsubscribes observer, it is safe to call it multiple times
+func (o *observer) stop() {
+	h := o.node.hooks
+	h.Lock()
+	defer h.Unlock()
+	if i := slices.Index(h.observers, o); i >= 0 {
+		h.observers = slices.Delete(h.observers, i, i+1)
+		h.active.Add(-1)
+		observing.Add(-1)
+	}
+}
+
+// observed reports whether node or any of its ancestors has observers
+func (n *Node) observed() bool {
+	if observing.Load() == 0 {
+		return false
+	}
+	for node := n; node != nil; node = node.parent {
+		if node.hooks != nil && node.hooks.active.Load() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// observedOrder returns the copy of children order to be passed with reorder mutation,
+// nil is returned when nobody observes changes
+func (n *Node) observedOrder() []*Node {
+	if !n.observed() {
+		return nil
+	}
+	return slices.Clone(n.children)
+}
+
+// notify calls observers of mutated parent and all its ancestors
+func notify(m mutation) {
+	if observing.Load() == 0 {
+		return
+	}
+	var list []*observer
+	for node := m.parent; node != nil; node = node.parent {
+		h := node.hooks
+		if h == nil || h.active.Load() == 0 {
+			continue
+		}
+		h.Lock()
+		list = append(list, h.observers...)
+		h.Unlock()
+	}
+	for _, o := range list {
+		o.fn(m)
+	}
+}
@@ -0,0 +1,203 @@
+package xtjson
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// ChangeOp is the kind of tree mutation
+type ChangeOp int
+
+const (
+	ChangeAdd     ChangeOp = iota // node is added to array or object
+	ChangeReplace                 // node is replaced by another one
+	ChangeRemove                  // node is removed from its parent
+	ChangeReorder                 // children of parent are reordered
+	ChangeRename                  // object property key is changed
+)
+
+var changeOpNames = []string{"add", "replace", "remove", "reorder", "rename"}
+
+func (op ChangeOp) String() string {
+	if op < 0 || int(op) >= len(changeOpNames) {
+		return "unknown"
+	}
+	return changeOpNames[op]
+}
+
+// Change describes the single mutation of the observed tree. Paths are relative to the
+// observed node and reflect the tree right after the mutation.
+type Change struct {
+	Op     ChangeOp
+	Path   string // path of changed node, path of parent for ChangeReorder
+	From   string // previous path of renamed node
+	Parent *Node  // node which children are changed
+	Old    *Node  // replaced or removed node
+	New    *Node  // added, replacing or renamed node
+}
+
+// Observer is the subscription to changes of the tree
+type Observer struct {
+	node   *Node
+	fn     func(Change)
+	batch  func([]Change)
+	sub    *observer
+	closed atomic.Bool
+}
+
+// Observe calls fn for every mutation made anywhere under receiver, fn is called
+// synchronously by goroutine making the mutation. Inside Batch changes are delivered
+// when the batch ends.
+func (n *Node) Observe(fn func(Change)) *Observer {
+	return n.subscribe(&Observer{node: n, fn: fn})
+}
+
+// ObserveBatch works like Observe calling fn once with all changes made inside Batch,
+// changes made outside of batch are delivered one by one
+func (n *Node) ObserveBatch(fn func([]Change)) *Observer {
+	return n.subscribe(&Observer{node: n, batch: fn})
+}
+
+func (n *Node) subscribe(o *Observer) *Observer {
+	if n == nil || n == undef {
+		o.closed.Store(true)
+		return o
+	}
+	o.sub = observe(n, o.update)
+	return o
+}
+
+// Close stops delivering of changes, changes pending in batch are dropped
+func (o *Observer) Close() {
+	if o.closed.Swap(true) || o.sub == nil {
+		return
+	}
+	o.sub.stop()
+}
+
+func (o *Observer) update(m mutation) {
+	change := o.change(m)
+	if b := currentBatch(m.parent); b != nil {
+		b.add(o, change)
+		return
+	}
+	o.deliver([]Change{change})
+}
+
+func (o *Observer) deliver(changes []Change) {
+	if o.closed.Load() {
+		return
+	}
+	if o.batch != nil {
+		o.batch(changes)
+		return
+	}
+	for _, change := range changes {
+		o.fn(change)
+	}
+}
+
+// change converts mutation to the change with paths relative to observed node
+func (o *Observer) change(m mutation) Change {
+	ret := Change{Parent: m.parent, Old: m.old, New: m.node}
+	parentPath := RelativePath(o.node, m.parent)
+	switch m.op {
+	case opAdd:
+		ret.Op = ChangeAdd
+		ret.Path = parentPath + m.node.pathSegment()
+	case opReplace:
+		ret.Op = ChangeReplace
+		ret.Path = parentPath + m.node.pathSegment()
+	case opRemove:
+		// removed node is unlinked already, its idx and key still point to the former place
+		ret.Op = ChangeRemove
+		ret.Path = parentPath + keySegment(m.old.key, ".")
+		if m.parent.IsArray() {
+			ret.Path = parentPath + "[" + strconv.Itoa(m.old.idx) + "]"
+		}
+	case opReorder:
+		ret.Op = ChangeReorder
+		ret.Path = parentPath
+	case opRename:
+		ret.Op = ChangeRename
+		ret.Path = parentPath + m.node.pathSegment()
+		ret.From = parentPath + keySegment(m.oldKey, ".")
+	}
+	return ret
+}
+
+type pendingChange struct {
+	observer *Observer
+	change   Change
+}
+
+type changeBatch struct {
+	sync.Mutex
+	depth   int
+	pending []pendingChange
+}
+
+func (b *changeBatch) add(o *Observer, change Change) {
+	b.Lock()
+	defer b.Unlock()
+	b.pending = append(b.pending, pendingChange{o, change})
+}
+
+// currentBatch returns the batch started on the root of node
+func currentBatch(node *Node) *changeBatch {
+	h := node.Root().hooks
+	if h == nil {
+		return nil
+	}
+	h.Lock()
+	defer h.Unlock()
+	return h.batch
+}
+
+// Batch calls fn and delays delivering of changes made in the tree of receiver until fn
+// returns. Observers created with ObserveBatch receive all the changes in one call.
+// Nested batches are merged into the outer one.
+func (n *Node) Batch(fn func()) {
+	if n == nil || n == undef {
+		fn()
+		return
+	}
+	h := n.Root().hooksOf()
+	h.Lock()
+	b := h.batch
+	if b == nil {
+		b = &changeBatch{}
+		h.batch = b
+	}
+	b.depth++
+	h.Unlock()
+
+	defer func() {
+		h.Lock()
+		b.depth--
+		if b.depth > 0 {
+			h.Unlock()
+			return
+		}
+		h.batch = nil
+		h.Unlock()
+		b.flush()
+	}()
+	fn()
+}
+
+// flush delivers pending changes keeping their order for every observer
+func (b *changeBatch) flush() {
+	var order []*Observer
+	grouped := make(map[*Observer][]Change)
+	for _, p := range b.pending {
+		if _, ok := grouped[p.observer]; !ok {
+			order = append(order, p.observer)
+		}
+		grouped[p.observer] = append(grouped[p.observer], p.change)
+	}
+	for _, o := range order {
+		o.deliver(grouped[o])
+	}
+}
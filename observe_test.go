@@ -0,0 +1,157 @@
+package xtjson
+
+import (
+	"fmt"
+	"testing"
+)
+
+func changeTrace(changes []Change) []string {
+	var ret []string
+	for _, c := range changes {
+		s := c.Op.String() + " " + c.Path
+		if c.From != "" {
+			s += " from " + c.From
+		}
+		if c.Old != nil {
+			s += " old " + c.Old.Stringify()
+		}
+		if c.New != nil {
+			s += " new " + c.New.Stringify()
+		}
+		ret = append(ret, s)
+	}
+	return ret
+}
+
+func TestObserve(t *testing.T) {
+	root, err := ParseString(`{"a":[1,2],"b":{"c":true}}`)
+	assertParsed(t, root, err)
+	var changes []Change
+	o := root.Observe(func(c Change) {
+		changes = append(changes, c)
+	})
+	defer o.Close()
+
+	a := root.Key("a")
+	assertNil(t, a.AppendInt(3))
+	assertNil(t, a.RemoveIdx(0))
+	assertNil(t, root.SetString("b", "x"))
+	assertNil(t, root.SetNull("d"))
+	assertNil(t, root.RemoveKey("d"))
+	assertNil(t, a.Idx(1).ReplaceByString("y"))
+	assertNil(t, root.RenameKey("a", "z"))
+	assertNil(t, root.SortKeys())
+	assertNil(t, root.Key("z").Swap(0, 1))
+	assertEqual(t, []string{
+		"add $.a[2] new 3",
+		"remove $.a[0] old 1",
+		`replace $.b old {"c":true} new "x"`,
+		"add $.d new null",
+		"remove $.d old null",
+		`replace $.a[1] old 3 new "y"`,
+		`rename $.z from $.a new ["y",2]`, // new node is stringified after swap
+		"reorder $",
+		"reorder $.z",
+	}, changeTrace(changes))
+	assertEqual(t, root, changes[0].Parent.Parent())
+
+	// changes of detached nodes are not observed
+	changes = nil
+	node := root.Key("b")
+	assertNil(t, node.Remove())
+	assertNil(t, NewArray().Append(node))
+	assertEqual(t, 1, len(changes))
+
+	o.Close()
+	o.Close()
+	assertNil(t, root.SetInt("e", 1))
+	assertEqual(t, 1, len(changes))
+
+	// mutations skip the walk over ancestors when nothing is observed
+	count := observing.Load()
+	o = root.Observe(func(c Change) {})
+	assertEqual(t, count+1, observing.Load())
+	o.Close()
+	o.Close()
+	assertEqual(t, count, observing.Load())
+}
+
+func TestObserveSubtree(t *testing.T) {
+	root, err := ParseString(`{"a":{"b":[1]},"c":[]}`)
+	assertParsed(t, root, err)
+	var changes []Change
+	o := root.Path("$.a").Observe(func(c Change) {
+		changes = append(changes, c)
+	})
+	defer o.Close()
+	assertNil(t, root.Path("$.a.b").Prepend(NewInt(0)))
+	assertNil(t, root.Key("c").AppendInt(1))
+	assertNil(t, root.Key("a").SetBool("d", true))
+	assertEqual(t, []string{"add $.b[0] new 0", "add $.d new true"}, changeTrace(changes))
+
+	var node *Node
+	o = node.Observe(func(c Change) {
+		t.Fatal("nil node has no changes")
+	})
+	o.Close()
+}
+
+func TestObserveBatch(t *testing.T) {
+	root, err := ParseString(`{"a":[1,2]}`)
+	assertParsed(t, root, err)
+	var batches [][]string
+	var single []string
+	ob := root.ObserveBatch(func(changes []Change) {
+		batches = append(batches, changeTrace(changes))
+	})
+	defer ob.Close()
+	o := root.Key("a").Observe(func(c Change) {
+		single = append(single, c.Path)
+	})
+	defer o.Close()
+
+	a := root.Key("a")
+	assertNil(t, a.AppendInt(3))
+	root.Path("$.a").Batch(func() {
+		assertNil(t, a.AppendInt(4))
+		root.Batch(func() {
+			assertNil(t, a.RemoveIdx(0))
+		})
+		assertEqual(t, 1, len(batches))
+		assertEqual(t, 1, len(single))
+		assertNil(t, root.SetInt("b", 5))
+		assertEqual(t, true, root.hooks.batch != nil)
+	})
+	// batch state lives on the root and is dropped when the outer batch ends
+	assertEqual(t, true, root.hooks.batch == nil)
+	assertEqual(t, [][]string{
+		{"add $.a[2] new 3"},
+		{"add $.a[3] new 4", "remove $.a[0] old 1", "add $.b new 5"},
+	}, batches)
+	assertEqual(t, []string{"$[2]", "$[3]", "$[0]"}, single)
+
+	// empty batch delivers nothing
+	root.Batch(func() {})
+	assertEqual(t, 2, len(batches))
+}
+
+func TestObserveWithIndex(t *testing.T) {
+	root, err := ParseString(`{"items":[{"id":1}]}`)
+	assertParsed(t, root, err)
+	idx := BuildIndex(root, &IndexOptions{Live: true})
+	defer idx.Close()
+	var found []int
+	o := root.Observe(func(c Change) {
+		// live index is updated immediately, observers are called after the batch
+		found = append(found, len(idx.Lookup("id")))
+	})
+	defer o.Close()
+	root.Batch(func() {
+		for i := range 3 {
+			item := NewObject()
+			assertNil(t, item.SetInt("id", i+2))
+			assertNil(t, root.Key("items").Append(item))
+		}
+	})
+	assertEqual(t, fmt.Sprint([]int{4, 4, 4}), fmt.Sprint(found))
+}
@@ -0,0 +1,210 @@
+package xtjson
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// parallelSegment is the part of search result in document order,
+// it is either matched node above split depth or the subtree searched by worker
+type parallelSegment struct {
+	node *Node
+	unit bool
+	ret  Nodes
+	err  error
+}
+
+func parallelWorkers(workers int) int {
+	if workers <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return workers
+}
+
+// SearchParallel works like Search distributing subtrees between workers.
+// Nodes on SplitDepth level of options are searched concurrently, nodes above it are
+// matched by calling goroutine. Results are merged in document order and are the same
+// as Search returns. Zero or negative workers means GOMAXPROCS. Search orders
+// other than depth first are not distributed and run by calling goroutine.
+// The matcher is called from multiple goroutines and must be safe for concurrent use,
+// the tree may be read by others but must not be modified until the call returns.
+func (n *Node) SearchParallel(matcher NodeMatcher, opt *SearchOptions, workers int) (Nodes, error) {
+	if n == nil {
+		return make(Nodes, 0), ErrNilNode
+	}
+	if opt == nil {
+		opt = &SearchOptions{SkipNested: true}
+	}
+	workers = parallelWorkers(workers)
+	depth := max(opt.SplitDepth, 1)
+	if workers == 1 || opt.DeepLimit > 0 && opt.DeepLimit <= depth || opt.Order != WalkDepthFirst || opt.Reverse {
+		return n.Search(matcher, opt)
+	}
+
+	segments, units, err := n.splitSearch(matcher, opt, depth)
+	if err != nil {
+		return make(Nodes, 0), err
+	}
+	unitOpt := *opt
+	if opt.DeepLimit > 0 {
+		unitOpt.DeepLimit -= depth
+	}
+	limit := opt.limit()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	done := make([]bool, len(segments))
+	prefix, found := 0, 0
+	// complete marks segment as finished and stops workers when results
+	// of finished segments prefix reach the limit
+	complete := func(i int) {
+		mu.Lock()
+		defer mu.Unlock()
+		done[i] = true
+		for prefix < len(segments) && done[prefix] {
+			found += len(segments[prefix].ret)
+			prefix++
+		}
+		if limit > 0 && found >= limit {
+			cancel()
+		}
+	}
+	for i, seg := range segments {
+		if !seg.unit {
+			complete(i)
+		}
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(units)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				seg := segments[i]
+				if seg.err = ctx.Err(); seg.err == nil {
+					seg.ret, seg.err = seg.node.search(ctx, matcher, &unitOpt, make(Nodes, 0), limit)
+				}
+				complete(i)
+			}
+		}()
+	}
+	for _, i := range units {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	ret := make(Nodes, 0)
+	for _, seg := range segments {
+		if limit > 0 && len(ret) >= limit {
+			break
+		}
+		if seg.err != nil {
+			return ret, seg.err
+		}
+		ret = append(ret, seg.ret...)
+	}
+	if limit > 0 && len(ret) > limit {
+		ret = ret[:limit]
+	}
+	return ret, nil
+}
+
+// splitSearch matches nodes above split depth and collects subtrees on split depth,
+// units contains indexes of subtree segments
+func (n *Node) splitSearch(matcher NodeMatcher, opt *SearchOptions, depth int) ([]*parallelSegment, []int, error) {
+	walker, err := NewWalker(n, depth)
+	if err != nil {
+		return nil, nil, err
+	}
+	base := n.Level()
+	var segments []*parallelSegment
+	var units []int
+	for {
+		node, state := walker.Next()
+		if state == WalkDone {
+			break
+		}
+		if state == WalkExit {
+			continue
+		}
+		if node.Level()-base == depth {
+			units = append(units, len(segments))
+			segments = append(segments, &parallelSegment{node: node, unit: true})
+			continue
+		}
+		if matcher.Match(node) {
+			segments = append(segments, &parallelSegment{node: node, ret: Nodes{node}})
+			if state == WalkEnter && opt.SkipNested {
+				walker.Skip()
+			}
+		}
+	}
+	return segments, units, nil
+}
+
+// WalkParallel calls fn for receiver and all its descendants distributing top level
+// children subtrees between workers. The order of calls is not defined, walk is
+// stopped on the first error returned by fn. Zero or negative workers means GOMAXPROCS.
+// Calls of fn run concurrently, so fn must synchronize access to shared state
+// and must not modify the tree, other readers are allowed.
+func (n *Node) WalkParallel(fn func(node *Node) error, workers int) error {
+	if n == nil {
+		return ErrNilNode
+	}
+	if err := fn(n); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	jobs := make(chan *Node)
+	var wg sync.WaitGroup
+	for range min(parallelWorkers(workers), len(n.children)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for child := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				if err := walkFunc(ctx, child, fn); err != nil {
+					cancel(err)
+				}
+			}
+		}()
+	}
+	for _, child := range n.children {
+		jobs <- child
+	}
+	close(jobs)
+	wg.Wait()
+	return context.Cause(ctx)
+}
+
+// walkFunc calls fn for all nodes of subtree checking the context cancellation periodically
+func walkFunc(ctx context.Context, root *Node, fn func(node *Node) error) error {
+	walker, err := NewWalker(root, 0)
+	if err != nil {
+		return err
+	}
+	for visited := 1; ; visited++ {
+		if visited%searchCheckInterval == 0 && ctx.Err() != nil {
+			return nil
+		}
+		node, state := walker.Next()
+		if state == WalkDone {
+			return nil
+		}
+		if state == WalkExit {
+			continue
+		}
+		if err = fn(node); err != nil {
+			return err
+		}
+	}
+}
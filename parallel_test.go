@@ -0,0 +1,108 @@
+package xtjson
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func parallelTree(t *testing.T) *Node {
+	t.Helper()
+	root := NewObject()
+	items := NewArray()
+	for i := range 300 {
+		item := NewObject()
+		assertNil(t, item.SetInt("id", i))
+		sub := NewArray()
+		for j := range 5 {
+			entry := NewObject()
+			assertNil(t, entry.SetInt("id", i*10+j))
+			assertNil(t, sub.Append(entry))
+		}
+		assertNil(t, item.Set("sub", sub))
+		assertNil(t, items.Append(item))
+	}
+	assertNil(t, root.SetString("id", "root"))
+	assertNil(t, root.Set("items", items))
+	return root
+}
+
+func TestSearchParallel(t *testing.T) {
+	root := parallelTree(t)
+	for _, opt := range []*SearchOptions{
+		nil,
+		{},
+		{SplitDepth: 2},
+		{SplitDepth: 3, DeepLimit: 5},
+		{DeepLimit: 2, SplitDepth: 2},
+		{MaxResults: 7, SplitDepth: 2},
+		{StopOnFirst: true},
+		{MaxResults: 1000, SplitDepth: 4},
+	} {
+		for _, m := range []NodeMatcher{TypeIs(Number), &keyMatcher{"id"}, TypeIs(Object), HasKeys("sub")} {
+			expected, err := root.Search(m, opt)
+			assertNil(t, err)
+			for _, workers := range []int{0, 1, 3, 16} {
+				actual, err := root.SearchParallel(m, opt, workers)
+				assertNil(t, err)
+				assertEqual(t, len(expected), len(actual))
+				for i := range expected {
+					if expected[i] != actual[i] {
+						t.Fatalf("result %d differs for %+v: %s, %s", i, opt, expected[i].SelfPath(), actual[i].SelfPath())
+					}
+				}
+			}
+		}
+	}
+
+	var nilNode *Node
+	_, err := nilNode.SearchParallel(TypeIs(Number), nil, 2)
+	if !errors.Is(err, ErrNilNode) {
+		t.Fatal("expected error ErrNilNode")
+	}
+}
+
+func TestSearchParallelStopsEarly(t *testing.T) {
+	root := parallelTree(t)
+	var visited atomic.Int32
+	ns, err := root.SearchParallel(NodeMatcherFunc(func(n *Node) bool {
+		visited.Add(1)
+		return n.IsNumber()
+	}), &SearchOptions{StopOnFirst: true, SplitDepth: 2}, 4)
+	assertNil(t, err)
+	assertEqual(t, `[0]`, ns.ToArray().Stringify())
+	assertEqual(t, true, visited.Load() < 2000)
+}
+
+func TestWalkParallel(t *testing.T) {
+	root := parallelTree(t)
+	var count, sum atomic.Int64
+	err := root.WalkParallel(func(n *Node) error {
+		count.Add(1)
+		if v, err := n.Int(); err == nil {
+			sum.Add(int64(v))
+		}
+		return nil
+	}, 8)
+	assertNil(t, err)
+	// root, id, items, 300 items with id, sub and 5 entries with id
+	assertEqual(t, int64(3+300*13), count.Load())
+	// item ids 0..299 and entry ids i*10+j
+	assertEqual(t, int64(44850+50*44850+300*10), sum.Load())
+
+	errStop := errors.New("stop")
+	err = root.WalkParallel(func(n *Node) error {
+		if n.SelfKey() == "id" && n.IsNumber() {
+			return errStop
+		}
+		return nil
+	}, 4)
+	if !errors.Is(err, errStop) {
+		t.Fatal("expected error errStop")
+	}
+
+	var nilNode *Node
+	if !errors.Is(nilNode.WalkParallel(func(n *Node) error { return nil }, 2), ErrNilNode) {
+		t.Fatal("expected error ErrNilNode")
+	}
+}
@@ -0,0 +1,258 @@
+package xtjson
+
+import (
+	"fmt"
+	"strings"
+)
+
+// selField is the field of selection expression like items[...](id,price)
+type selField struct {
+	name     string
+	wildcard []string // [...] or {...} applied to the field value
+	sub      []*selField
+}
+
+type selParser struct {
+	expr string
+	pos  int
+}
+
+func (p *selParser) errorf(msg string) error {
+	return fmt.Errorf("%w: %s at position %d in %q", ErrBadQuery, msg, p.pos, p.expr)
+}
+
+func (p *selParser) skipSpaces() {
+	for p.pos < len(p.expr) && (p.expr[p.pos] == ' ' || p.expr[p.pos] == '\t' || p.expr[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+// parseList parses comma separated fields until the end or closing parenthesis
+func (p *selParser) parseList() ([]*selField, error) {
+	var ret []*selField
+	for {
+		field, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, field)
+		p.skipSpaces()
+		if p.pos == len(p.expr) || p.expr[p.pos] != ',' {
+			return ret, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *selParser) parseField() (*selField, error) {
+	p.skipSpaces()
+	start := p.pos
+	for p.pos < len(p.expr) && !strings.ContainsRune(",()[{} \t\n", rune(p.expr[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start {
+		return nil, p.errorf("field name expected")
+	}
+	field := &selField{name: p.expr[start:p.pos]}
+	for {
+		p.skipSpaces()
+		rest := p.expr[p.pos:]
+		switch {
+		case strings.HasPrefix(rest, "[...]"), strings.HasPrefix(rest, "{...}"):
+			field.wildcard = append(field.wildcard, rest[:5])
+			p.pos += 5
+			continue
+		case strings.HasPrefix(rest, "("):
+			p.pos++
+			sub, err := p.parseList()
+			if err != nil {
+				return nil, err
+			}
+			if p.pos == len(p.expr) || p.expr[p.pos] != ')' {
+				return nil, p.errorf("closing parenthesis expected")
+			}
+			p.pos++
+			field.sub = sub
+		}
+		return field, nil
+	}
+}
+
+func parseSelection(expr string) ([]*selField, error) {
+	p := &selParser{expr: expr}
+	ret, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos != len(p.expr) {
+		return nil, p.errorf("unexpected character")
+	}
+	return ret, nil
+}
+
+// selection collects nodes selected by paths or selection expressions,
+// keep contains containers which are preserved in projection even if nothing is selected inside
+type selection struct {
+	selected map[*Node]bool
+	keep     map[*Node]bool
+}
+
+func newSelection(root *Node, exprs []string) (*selection, error) {
+	if len(exprs) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrBadQuery, "no fields")
+	}
+	s := &selection{selected: make(map[*Node]bool), keep: make(map[*Node]bool)}
+	for _, expr := range exprs {
+		if strings.HasPrefix(strings.TrimSpace(expr), "$") {
+			nodes, err := root.Query(strings.TrimSpace(expr))
+			if err != nil {
+				return nil, err
+			}
+			for _, node := range nodes {
+				s.selected[node] = true
+			}
+			continue
+		}
+		fields, err := parseSelection(expr)
+		if err != nil {
+			return nil, err
+		}
+		s.apply(root, fields)
+	}
+	return s, nil
+}
+
+// apply selects fields of object node, for arrays fields are selected in every element
+func (s *selection) apply(node *Node, fields []*selField) {
+	if node.IsArray() {
+		for _, child := range node.children {
+			s.keep[child] = true
+			s.apply(child, fields)
+		}
+		return
+	}
+	if !node.IsObject() {
+		return
+	}
+	for _, field := range fields {
+		child := node.Key(field.name)
+		if !child.Exists() {
+			continue
+		}
+		targets := Nodes{child}
+		for _, wildcard := range field.wildcard {
+			var next Nodes
+			for _, target := range targets {
+				if wildcard == "[...]" && target.IsArray() || wildcard == "{...}" && target.IsObject() {
+					s.keep[target] = true
+					next = append(next, target.children...)
+				}
+			}
+			targets = next
+		}
+		for _, target := range targets {
+			if field.sub == nil {
+				s.selected[target] = true
+				continue
+			}
+			// nested selection selects nothing in scalars
+			if target.IsScalar() {
+				continue
+			}
+			s.keep[target] = true
+			s.apply(target, field.sub)
+		}
+	}
+}
+
+// Project returns the copy of root containing only selected nodes with their ancestors.
+// Fields are json paths starting with $ evaluated by Query, for example $.items[...].id,
+// or GraphQL like selections such as "id,name,items(id,price)" where nested selection
+// applied to array is applied to each its element. Wildcards [...] and {...} select
+// all array elements or object properties like items[...](id) or meta{...}(value).
+func Project(root *Node, fields ...string) (*Node, error) {
+	if !root.Exists() {
+		return nil, ErrNilNode
+	}
+	s, err := newSelection(root, fields)
+	if err != nil {
+		return nil, err
+	}
+	for node := range s.selected {
+		for ; node != root && node != nil && !s.keep[node]; node = node.parent {
+			s.keep[node] = true
+		}
+	}
+	s.keep[root] = true
+	return s.project(root)
+}
+
+func (s *selection) project(node *Node) (*Node, error) {
+	if s.selected[node] || node.IsScalar() {
+		return detach(node), nil
+	}
+	ret := NewArray()
+	if node.IsObject() {
+		ret = NewObject()
+	}
+	for _, child := range node.children {
+		if !s.selected[child] && !s.keep[child] {
+			continue
+		}
+		copied, err := s.project(child)
+		if err != nil {
+			return nil, err
+		}
+		if err = ret.link(child.key, copied); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// Omit returns the copy of root without the nodes selected the same way as in Project
+func Omit(root *Node, fields ...string) (*Node, error) {
+	if !root.Exists() {
+		return nil, ErrNilNode
+	}
+	s, err := newSelection(root, fields)
+	if err != nil {
+		return nil, err
+	}
+	if s.selected[root] {
+		return nil, fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "root can not be omitted")
+	}
+	return s.omit(root)
+}
+
+func (s *selection) omit(node *Node) (*Node, error) {
+	if node.IsScalar() {
+		return detach(node), nil
+	}
+	ret := NewArray()
+	if node.IsObject() {
+		ret = NewObject()
+	}
+	for _, child := range node.children {
+		if s.selected[child] {
+			continue
+		}
+		copied, err := s.omit(child)
+		if err != nil {
+			return nil, err
+		}
+		if err = ret.link(child.key, copied); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// link appends node to array or sets it by key to object
+func (n *Node) link(key string, node *Node) error {
+	if n.IsArray() {
+		return n.Append(node)
+	}
+	return n.Set(key, node)
+}
@@ -0,0 +1,83 @@
+package xtjson
+
+import (
+	"errors"
+	"testing"
+)
+
+const projectJson = `{"id":1,"name":"n","secret":"s","items":[{"id":2,"price":3,"tax":1},{"id":4,"note":"x"},{"other":true}],` +
+	`"meta":{"a":{"value":1,"x":2},"b":{"value":3}},"tags":["t1","t2"]}`
+
+func TestProject(t *testing.T) {
+	root, err := ParseString(projectJson)
+	assertParsed(t, root, err)
+	cases := []struct {
+		fields   []string
+		expected string
+	}{
+		{[]string{"id,name,items(id,price)"}, `{"id":1,"name":"n","items":[{"id":2,"price":3},{"id":4},{}]}`},
+		{[]string{" id , items ( id ) ", "tags"}, `{"id":1,"items":[{"id":2},{"id":4},{}],"tags":["t1","t2"]}`},
+		{[]string{"items[...](note)"}, `{"items":[{},{"note":"x"},{}]}`},
+		{[]string{"meta{...}(value)"}, `{"meta":{"a":{"value":1},"b":{"value":3}}}`},
+		{[]string{"meta(a(x)),missing,id(x)"}, `{"meta":{"a":{"x":2}}}`},
+		{[]string{"$.items[...].id", "$.name"}, `{"name":"n","items":[{"id":2},{"id":4}]}`},
+		{[]string{"$.meta{...}.value"}, `{"meta":{"a":{"value":1},"b":{"value":3}}}`},
+		{[]string{"$.items[?@.price > 1]", "$.tags[1]"}, `{"items":[{"id":2,"price":3,"tax":1}],"tags":["t2"]}`},
+		{[]string{"$"}, projectJson},
+		{[]string{"$.missing"}, `{}`},
+	}
+	for _, c := range cases {
+		ret, err := Project(root, c.fields...)
+		assertNil(t, err)
+		assertEqual(t, c.expected, ret.Stringify())
+		assertNil(t, ret.Validate())
+	}
+	assertEqual(t, projectJson, root.Stringify())
+
+	arr, err := ParseString(`[{"a":1,"b":2},{"a":3}]`)
+	assertParsed(t, arr, err)
+	ret, err := Project(arr, "a")
+	assertNil(t, err)
+	assertEqual(t, `[{"a":1},{"a":3}]`, ret.Stringify())
+}
+
+func TestOmit(t *testing.T) {
+	root, err := ParseString(projectJson)
+	assertParsed(t, root, err)
+	ret, err := Omit(root, "secret,meta,items(tax,note)")
+	assertNil(t, err)
+	assertEqual(t, `{"id":1,"name":"n","items":[{"id":2,"price":3},{"id":4},{"other":true}],"tags":["t1","t2"]}`, ret.Stringify())
+
+	ret, err = Omit(root, "$.items[...].id", "$.meta{...}.value", "$.tags[0]")
+	assertNil(t, err)
+	assertEqual(t, `{"id":1,"name":"n","secret":"s","items":[{"price":3,"tax":1},{"note":"x"},{"other":true}],`+
+		`"meta":{"a":{"x":2},"b":{}},"tags":["t2"]}`, ret.Stringify())
+	assertNil(t, ret.Validate())
+	assertEqual(t, projectJson, root.Stringify())
+
+	_, err = Omit(root, "$")
+	if !errors.Is(err, ErrInvalidNodeForOperation) {
+		t.Fatal("expected error ErrInvalidNodeForOperation")
+	}
+}
+
+func TestProjectErrors(t *testing.T) {
+	root, err := ParseString(projectJson)
+	assertParsed(t, root, err)
+	for _, fields := range [][]string{{}, {""}, {"id,"}, {"items(id"}, {"items(id))"}, {"a b"}, {"$.items[?"}} {
+		_, err = Project(root, fields...)
+		if !errors.Is(err, ErrBadQuery) {
+			t.Fatalf("expected error ErrBadQuery for %v", fields)
+		}
+		_, err = Omit(root, fields...)
+		if !errors.Is(err, ErrBadQuery) {
+			t.Fatalf("expected error ErrBadQuery for %v", fields)
+		}
+	}
+	_, err = Project(nil, "id")
+	if !errors.Is(err, ErrNilNode) {
+		t.Fatal("expected error ErrNilNode")
+	}
+	_, err = parseSelection("items(id")
+	assertEqual(t, `bad query syntax: closing parenthesis expected at position 8 in "items(id"`, err.Error())
+}
@@ -1,7 +1,10 @@
 package xtjson
 
 import (
+	"context"
 	"errors"
+	"strconv"
+	"strings"
 )
 
 var (
@@ -23,27 +26,69 @@ func (f NodeMatcherFunc) Match(node *Node) bool {
 
 // SearchOptions
 type SearchOptions struct {
-	DeepLimit  int
-	SkipNested bool
+	DeepLimit   int
+	SkipNested  bool
+	MaxResults  int  // stop search when the number of results is reached, 0 means unlimited
+	StopOnFirst bool // stop search on the first matched node, same as MaxResults 1
+	SplitDepth  int  // level of subtrees distributed between workers by SearchParallel, 0 means 1
+	// Order of results, SkipNested has no effect with WalkPostOrder and WalkLeaves
+	Order   WalkOrder
+	Reverse bool // siblings are searched from the last one to the first one
+}
+
+// searchCheckInterval is the number of visited nodes between context cancellation checks
+const searchCheckInterval = 1024
+
+// limit returns the maximum number of results, 0 means unlimited
+func (opt *SearchOptions) limit() int {
+	if opt.StopOnFirst {
+		return 1
+	}
+	return max(opt.MaxResults, 0)
 }
 
 // Search returns all nodes matched by provided matcher
 func (n *Node) Search(matcher NodeMatcher, opt *SearchOptions) (Nodes, error) {
-	ret := make(Nodes, 0)
-	if n == nil {
-		return ret, ErrNilNode
-	}
+	return n.SearchContext(context.Background(), matcher, opt)
+}
+
+// SearchContext works like Search checking the context cancellation periodically,
+// on cancellation the nodes found so far are returned with context error
+func (n *Node) SearchContext(ctx context.Context, matcher NodeMatcher, opt *SearchOptions) (Nodes, error) {
 	if opt == nil {
 		opt = &SearchOptions{
 			DeepLimit:  0,
 			SkipNested: true,
 		}
 	}
-	walker, err := NewWalker(n, opt.DeepLimit)
+	return n.search(ctx, matcher, opt, make(Nodes, 0), opt.limit())
+}
+
+// search appends matched nodes to ret until limit of its length is reached
+func (n *Node) search(ctx context.Context, matcher NodeMatcher, opt *SearchOptions, ret Nodes, limit int) (Nodes, error) {
+	err := n.walkMatches(ctx, matcher, opt, func(node *Node) bool {
+		ret = append(ret, node)
+		return limit <= 0 || len(ret) < limit
+	})
+	return ret, err
+}
+
+// walkMatches passes nodes matched by matcher to yield until it returns false,
+// the context is checked before the walk, periodically during it and after it
+func (n *Node) walkMatches(ctx context.Context, matcher NodeMatcher, opt *SearchOptions, yield func(*Node) bool) error {
+	if n == nil {
+		return ErrNilNode
+	}
+	walker, err := NewWalker(n, opt.DeepLimit, &WalkOptions{Order: opt.Order, Reverse: opt.Reverse})
 	if err != nil {
-		return ret, err
+		return err
 	}
-	for {
+	for visited := 0; ; visited++ {
+		if visited%searchCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+		}
 		node, state := walker.Next()
 		if state == WalkDone {
 			break
@@ -52,13 +97,16 @@ func (n *Node) Search(matcher NodeMatcher, opt *SearchOptions) (Nodes, error) {
 			continue
 		}
 		if matcher.Match(node) {
-			ret = append(ret, node)
+			if !yield(node) {
+				break
+			}
 			if state == WalkEnter && opt.SkipNested {
 				walker.Skip()
 			}
 		}
 	}
-	return ret, nil
+	// cancellation since the last check is reported as well
+	return ctx.Err()
 }
 
 type keyMatcher struct {
@@ -130,111 +178,105 @@ func (n *Node) QueryPipe(steps ...string) (Nodes, error) {
 	return nodes, nil
 }
 
-func parseQuery(path string) ([]string, error) {
-	if len(path) == 0 || path[0] != '$' {
-		return nil, ErrBadQuery
-	}
-	steps := []string{}
-	step := ""
-	buf := ""
-
-	for _, v := range path {
-		switch buf {
-		case "":
-			switch v {
-			case '.', '[', '{':
-				buf += string(v)
-				if len(step) > 3 && step[0:3] == "..." {
-					steps = append(steps, step)
-					step = ""
-				}
-			default:
-				step += string(v)
-			}
+// CompiledQuery is the parsed JSONPath expression, which can be evaluated many times.
+// It is immutable and safe for concurrent use by multiple goroutines
+type CompiledQuery struct {
+	expr  string
+	query *jpQuery
+}
 
-		case ".", "[", "{":
-			switch v {
-			case '.':
-				buf += string(v)
-			case '}', ']':
-				return nil, ErrBadQuery
-			default:
-				step += buf + string(v)
-				buf = ""
-			}
+// CompileQuery parses and validates JSONPath expression once,
+// the syntax is the same as Query accepts
+func CompileQuery(expr string) (*CompiledQuery, error) {
+	q, err := parseJSONPath(expr, false)
+	if err != nil {
+		return nil, err
+	}
+	return &CompiledQuery{expr: expr, query: q}, nil
+}
 
-		case "..", "[.", "{.":
-			switch v {
-			case '.':
-				buf += "."
-			default:
-				return nil, ErrBadQuery
-			}
+// MustCompileQuery is like CompileQuery but panics if expression is invalid
+func MustCompileQuery(expr string) *CompiledQuery {
+	cq, err := CompileQuery(expr)
+	if err != nil {
+		panic(err)
+	}
+	return cq
+}
 
-		case "...":
-			switch v {
-			case '.', '[', '{', ']', '}':
-				return nil, ErrBadQuery
-			default:
-				steps = append(steps, step)
-				step = buf + string(v)
-				buf = ""
-			}
+// Eval applies the query to node, the empty list is returned when nothing matches
+func (cq *CompiledQuery) Eval(node *Node) Nodes {
+	if cq == nil || node == nil {
+		return make(Nodes, 0)
+	}
+	return cq.query.eval(&jpContext{root: node, current: node})
+}
 
-		case "[..", "{..":
-			switch v {
-			case '.':
-				buf += "."
-			default:
-				return nil, ErrBadQuery
-			}
+// String returns the source expression of query
+func (cq *CompiledQuery) String() string {
+	return cq.expr
+}
 
-		case "[...":
-			switch v {
-			case ']':
-				steps = append(steps, step, "[...]")
-				step = ""
-				buf = ""
-			default:
-				return nil, ErrBadQuery
+// Explain returns human readable plan of query steps
+func (cq *CompiledQuery) Explain() string {
+	var sb strings.Builder
+	sb.WriteString("query " + cq.expr + "\n")
+	sb.WriteString("  0: root $\n")
+	for i, seg := range cq.query.segments {
+		var step string
+		switch seg.selectors[0].kind {
+		case jpDeepKey:
+			step = "deep key search " + quoteName(seg.selectors[0].name) + " skipping nested matches"
+		case jpArrayChildren:
+			step = "array children"
+		case jpObjectChildren:
+			step = "object children"
+		default:
+			kind := "child"
+			if seg.descendant {
+				kind = "descendant"
 			}
-
-		case "{...":
-			switch v {
-			case '}':
-				steps = append(steps, step, "{...}")
-				step = ""
-				buf = ""
-			default:
-				return nil, ErrBadQuery
+			items := make([]string, len(seg.selectors))
+			for j := range seg.selectors {
+				items[j] = explainSelector(&seg.selectors[j])
 			}
-		default:
-			return nil, ErrBadQuery
+			step = kind + " " + strings.Join(items, ", ")
 		}
+		sb.WriteString("  " + strconv.Itoa(i+1) + ": " + step + "\n")
 	}
-	if len(step) > 0 {
-		steps = append(steps, step)
-	}
-	ret := []string{}
-	for _, step := range steps {
-		if step == "$" || step == "" {
-			continue
-		}
-		if step[0] != '$' {
-			step = "$" + step
-		}
-		ret = append(ret, step)
+	return sb.String()
+}
+
+func explainSelector(sel *jpSelector) string {
+	switch sel.kind {
+	case jpName:
+		return "name " + sel.String()
+	case jpWildcard:
+		return "wildcard"
+	case jpIndex:
+		return "index " + sel.String()
+	case jpSlice:
+		return "slice " + sel.String()
+	case jpFilter:
+		return "filter " + exprString(sel.filter)
 	}
-	return ret, nil
+	return sel.String()
 }
 
-// Query extracts nodes using the combination of Path syntax with extensions
-// deeo keysearch ...key, array [...] or object {...} cildren
-// use QueryPipe instead when keys can contain dots or brackets
+// Query extracts nodes using JSONPath expression as defined by RFC 9535,
+// including wildcards, recursive descent, slices, unions and filters with
+// length, count, match, search and value functions.
+// The legacy extensions deep keysearch ...key, array [...] and object {...}
+// children are supported as well, member names after dot can contain any
+// characters except . [ ] { } like $.1 or $.foo-bar. Use CompileQuery when the same query
+// is applied many times
 func (n *Node) Query(path string) (Nodes, error) {
-	steps, err := parseQuery(path)
+	if n == nil {
+		return nil, ErrNilNode
+	}
+	cq, err := CompileQuery(path)
 	if err != nil {
 		return nil, err
 	}
-	return n.QueryPipe(steps...)
+	return cq.Eval(n), nil
 }
@@ -1,7 +1,9 @@
 package xtjson
 
 import (
+	"context"
 	"errors"
+	"sync"
 	"testing"
 )
 
@@ -23,6 +25,84 @@ func TestSearch(t *testing.T) {
 	assertEqual(t, `[{"a":3,"b":4},{"a":4,"b":5}]`, ns.ToArray().Stringify())
 }
 
+func TestSearchLimits(t *testing.T) {
+	root, err := ParseString(`[{"id":1},{"id":2},{"id":3,"sub":{"id":4}}]`)
+	assertParsed(t, root, err)
+
+	visited := 0
+	counting := NodeMatcherFunc(func(n *Node) bool {
+		visited++
+		return n.SelfKey() == "id"
+	})
+	ns, err := root.Search(counting, &SearchOptions{StopOnFirst: true})
+	assertNil(t, err)
+	assertEqual(t, `[1]`, ns.ToArray().Stringify())
+	assertEqual(t, 3, visited)
+
+	ns, err = root.Search(counting, &SearchOptions{MaxResults: 3})
+	assertNil(t, err)
+	assertEqual(t, `[1,2,3]`, ns.ToArray().Stringify())
+
+	ns, err = root.SearchKey("id", &SearchOptions{MaxResults: -1})
+	assertNil(t, err)
+	assertEqual(t, `[1,2,3,4]`, ns.ToArray().Stringify())
+
+	ns, err = Nodes(root.Children()).SearchKey("id", &SearchOptions{MaxResults: 2})
+	assertNil(t, err)
+	assertEqual(t, `[1,2]`, ns.ToArray().Stringify())
+	ns, err = Nodes(root.Children()).SearchKey("id", &SearchOptions{StopOnFirst: true, MaxResults: 3})
+	assertNil(t, err)
+	assertEqual(t, `[1]`, ns.ToArray().Stringify())
+}
+
+func TestSearchContext(t *testing.T) {
+	root := NewArray()
+	for i := range 5000 {
+		assertNil(t, root.AppendInt(i))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ns, err := root.SearchContext(ctx, TypeIs(Number), nil)
+	assertNil(t, err)
+	assertEqual(t, 5000, len(ns))
+
+	matched := 0
+	ns, err = root.SearchContext(ctx, NodeMatcherFunc(func(n *Node) bool {
+		if matched++; matched == 100 {
+			cancel()
+		}
+		return n.IsNumber()
+	}), nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("expected error context.Canceled")
+	}
+	assertEqual(t, true, len(ns) >= 100 && len(ns) < 5000)
+
+	_, err = Nodes{root}.SearchContext(ctx, TypeIs(Number), nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("expected error context.Canceled")
+	}
+
+	// small trees are checked before the walk and after it
+	small, err := ParseString(`[1,2,3]`)
+	assertParsed(t, small, err)
+	ns, err = small.SearchContext(ctx, TypeIs(Number), nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("expected error context.Canceled")
+	}
+	assertEqual(t, 0, len(ns))
+	ctx, cancel = context.WithCancel(context.Background())
+	ns, err = small.SearchContext(ctx, NodeMatcherFunc(func(n *Node) bool {
+		if n.IsNumber() {
+			cancel()
+		}
+		return n.IsNumber()
+	}), nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("expected error context.Canceled")
+	}
+	assertEqual(t, `[1,2,3]`, ns.ToArray().Stringify())
+}
+
 func TestSearchKey(t *testing.T) {
 	root, err := ParseString(`{"ka":"va", "kb":{"kkb1":"kkv1", "ka": 25}, "kc":123}`)
 	assertNil(t, err)
@@ -68,29 +148,38 @@ func TestQueryPipe(t *testing.T) {
 }
 
 func TestParseQuery(t *testing.T) {
-	_, err := parseQuery("")
+	_, err := parseJSONPath("", false)
 	if !errors.Is(err, ErrBadQuery) {
 		t.Fatal("error is expected ErrBadQuery")
 	}
-	_, err = parseQuery("abc")
+	_, err = parseJSONPath("abc", false)
 	if !errors.Is(err, ErrBadQuery) {
 		t.Fatal("error is expected ErrBadQuery")
 	}
 
-	steps, err := parseQuery("$.foo")
+	q, err := parseJSONPath("$.foo", false)
 	assertNil(t, err)
-	assertEqual(t, []string{"$.foo"}, steps)
+	assertEqual(t, "$['foo']", q.String())
 
-	steps, err = parseQuery("$.foo...boo")
+	q, err = parseJSONPath("$.foo...boo", false)
 	assertNil(t, err)
-	assertEqual(t, []string{"$.foo", "$...boo"}, steps)
+	assertEqual(t, "$['foo']...boo", q.String())
 
-	steps, err = parseQuery("$...boo[35].x")
+	q, err = parseJSONPath("$...boo[35].x", false)
 	assertNil(t, err)
-	assertEqual(t, []string{"$...boo", "$[35].x"}, steps)
-	steps, err = parseQuery("$[12]...boo.aaa{...}.x[10]")
+	assertEqual(t, "$...boo[35]['x']", q.String())
+	q, err = parseJSONPath("$[12]...boo.aaa{...}.x[10]", false)
 	assertNil(t, err)
-	assertEqual(t, []string{"$[12]", "$...boo", "$.aaa", "${...}", "$.x[10]"}, steps)
+	assertEqual(t, "$[12]...boo['aaa']{...}['x'][10]", q.String())
+
+	_, err = parseJSONPath("$...", false)
+	if !errors.Is(err, ErrBadQuery) {
+		t.Fatal("error is expected ErrBadQuery")
+	}
+	_, err = parseJSONPath("$[?@...a]", false)
+	if !errors.Is(err, ErrBadQuery) {
+		t.Fatal("error is expected ErrBadQuery")
+	}
 }
 
 func TestQuery(t *testing.T) {
@@ -110,4 +199,126 @@ func TestQuery(t *testing.T) {
 	assertNil(t, err)
 	assertEqual(t, 2, len(ns))
 	assertEqual(t, `["kkv1",222]`, ns.ToArray().Stringify())
+
+	ns, err = root.Query("$..kkb1")
+	assertNil(t, err)
+	assertEqual(t, `["kkv1",222]`, ns.ToArray().Stringify())
+
+	ns, err = root.Query("$..*[?@ > 1]")
+	assertNil(t, err)
+	assertEqual(t, `[25,2,3,222]`, ns.ToArray().Stringify())
+
+	ns, err = root.Query("$[?@.ka == 25].kkb1")
+	assertNil(t, err)
+	assertEqual(t, `["kkv1"]`, ns.ToArray().Stringify())
+
+	ns, err = root.Query("$.kc[-1:0:-1]")
+	assertNil(t, err)
+	assertEqual(t, `[3,2]`, ns.ToArray().Stringify())
+
+	ns, err = root.Query("$['ka','kc'][0]")
+	assertNil(t, err)
+	assertEqual(t, `[1]`, ns.ToArray().Stringify())
+
+	_, err = root.Query("$.kc[")
+	if !errors.Is(err, ErrBadQuery) {
+		t.Fatal("error is expected ErrBadQuery")
+	}
+}
+
+func TestQueryLegacyNames(t *testing.T) {
+	root, err := ParseString(`{"foo-bar":{"x:y":[1,{"a-b":2}]},"foo":3}`)
+	assertNil(t, err)
+	ns, err := root.Query("$.foo-bar")
+	assertNil(t, err)
+	assertEqual(t, `[{"x:y":[1,{"a-b":2}]}]`, ns.ToArray().Stringify())
+
+	ns, err = root.Query("$.foo-bar.x:y[1].a-b")
+	assertNil(t, err)
+	assertEqual(t, `[2]`, ns.ToArray().Stringify())
+
+	ns, err = root.Query("$.foo-bar{...}")
+	assertNil(t, err)
+	assertEqual(t, `[[1,{"a-b":2}]]`, ns.ToArray().Stringify())
+
+	root, err = ParseString(`{"1":"one","-foo":2,"a b":{"c":3},"x":[4]}`)
+	assertNil(t, err)
+	for path, expected := range map[string]string{
+		"$.1":     `["one"]`,
+		"$.-foo":  `[2]`,
+		"$.a b.c": `[3]`,
+		"$.x.0":   `[]`,
+	} {
+		ns, err = root.Query(path)
+		assertNil(t, err)
+		assertEqual(t, expected, ns.ToArray().Stringify())
+	}
+
+	// names in filters and strict queries follow RFC 9535
+	_, err = root.Query("$[?@.foo-bar]")
+	if !errors.Is(err, ErrBadQuery) {
+		t.Fatal("error is expected ErrBadQuery")
+	}
+	for _, path := range []string{"$.-foo", "$.1", "$.a b", "$...a", "${...}"} {
+		_, err = parseJSONPath(path, true)
+		if !errors.Is(err, ErrBadQuery) {
+			t.Fatalf("error is expected ErrBadQuery for %s", path)
+		}
+	}
+}
+
+func TestCompileQuery(t *testing.T) {
+	cq, err := CompileQuery("$.items[?@.price < 10 && @.tag == 'x'].id")
+	assertNil(t, err)
+	assertEqual(t, "$.items[?@.price < 10 && @.tag == 'x'].id", cq.String())
+
+	docs := []string{
+		`{"items":[{"id":1,"price":5,"tag":"x"},{"id":2,"price":15,"tag":"x"}]}`,
+		`{"items":[{"id":3,"price":1,"tag":"y"},{"id":4,"price":2,"tag":"x"}]}`,
+		`{"other":true}`,
+	}
+	expected := []string{`[1]`, `[4]`, `[]`}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j, doc := range docs {
+				root, err := ParseString(doc)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if actual := cq.Eval(root).ToArray().Stringify(); actual != expected[j] {
+					t.Errorf("expected: %s, actual: %s", expected[j], actual)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	_, err = CompileQuery("$.items[?@.price <]")
+	if !errors.Is(err, ErrBadQuery) {
+		t.Fatal("error is expected ErrBadQuery")
+	}
+	assertEqual(t, 0, len(cq.Eval(nil)))
+}
+
+func TestCompiledQueryExplain(t *testing.T) {
+	cq := MustCompileQuery("$.store..book[0,-1:][?@.price < 10 || !@.isbn]...title{...}")
+	assertEqual(t, `query $.store..book[0,-1:][?@.price < 10 || !@.isbn]...title{...}
+  0: root $
+  1: child name 'store'
+  2: descendant name 'book'
+  3: child index 0, slice -1:
+  4: child filter @['price'] < 10 || !@['isbn']
+  5: deep key search 'title' skipping nested matches
+  6: object children
+`, cq.Explain())
+
+	cq = MustCompileQuery(`$[?match(@.a, "it's") && (count(@.*) > 1 || value(@..b) == null)]`)
+	assertEqual(t, `query $[?match(@.a, "it's") && (count(@.*) > 1 || value(@..b) == null)]
+  0: root $
+  1: child filter match(@['a'], 'it\'s') && (count(@[*]) > 1 || value(@..['b']) == null)
+`, cq.Explain())
 }
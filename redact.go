@@ -0,0 +1,223 @@
+package xtjson
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var ErrBadRedactRule = errors.New("bad redact rule")
+
+// RedactAction defines what happens with the value matched by redact rule
+type RedactAction int
+
+const (
+	RedactReplace RedactAction = iota // replace by Replacement, "***" when empty
+	RedactHash                        // replace by hex sha256 of Salt and the value
+	RedactMask                        // replace all characters except Keep last ones by *
+	RedactRemove                      // remove the node from its parent
+)
+
+// RedactRule matches nodes by object key, path and value, all provided criteria must match.
+// Non string values are hashed and masked as their json text. Value is matched against strings
+// and json text of numbers, only matched parts are replaced, hashed or masked, for example
+// a card number inside a message, the result is always a string.
+type RedactRule struct {
+	Key         string       `json:"key,omitempty"`   // case insensitive shell pattern like *token*, / is an ordinary character
+	Path        string       `json:"path,omitempty"`  // path pattern relative to redacted root as in PathGlob like $.users[*].password
+	Value       string       `json:"value,omitempty"` // regular expression matched against strings and numbers
+	Action      RedactAction `json:"action,omitempty"`
+	Replacement string       `json:"replacement,omitempty"`
+	Salt        string       `json:"salt,omitempty"`
+	Keep        int          `json:"keep,omitempty"` // characters left by RedactMask, 4 when 0
+}
+
+// Redaction is the report entry about redacted node
+type Redaction struct {
+	Path   string       `json:"path"` // path of the node in original tree
+	Rule   int          `json:"rule"` // index of the matched rule
+	Action RedactAction `json:"action"`
+}
+
+type redactRule struct {
+	*RedactRule
+	key  *regexp.Regexp
+	path []globToken
+	re   *regexp.Regexp
+}
+
+func compileRedactRule(rule *RedactRule) (*redactRule, error) {
+	if rule.Key == "" && rule.Path == "" && rule.Value == "" {
+		return nil, fmt.Errorf("%w: %s", ErrBadRedactRule, "no key, path or value")
+	}
+	if rule.Action < RedactReplace || rule.Action > RedactRemove {
+		return nil, fmt.Errorf("%w: action %d", ErrBadRedactRule, rule.Action)
+	}
+	if rule.Keep < 0 {
+		return nil, fmt.Errorf("%w: keep %d", ErrBadRedactRule, rule.Keep)
+	}
+	ret := &redactRule{RedactRule: rule}
+	if rule.Key != "" {
+		re, err := compileKeyGlob(rule.Key, true)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %s", ErrBadRedactRule, rule.Key)
+		}
+		ret.key = re
+	}
+	if rule.Path != "" {
+		tokens, err := parseGlob(rule.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrBadRedactRule, err.Error())
+		}
+		ret.path = tokens
+	}
+	if rule.Value != "" {
+		re, err := regexp.Compile(rule.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w: %s", ErrBadRedactRule, ErrInvalidRegexp, err.Error())
+		}
+		ret.re = re
+	}
+	return ret, nil
+}
+
+// match checks node against the rule, path pattern is matched from root
+func (r *redactRule) match(node, root *Node) bool {
+	if r.key != nil {
+		if !node.parent.IsObject() || !r.key.MatchString(node.key) {
+			return false
+		}
+	}
+	if r.path != nil {
+		var chain []*Node
+		for n := node; n != root; n = n.parent {
+			chain = append(chain, n)
+		}
+		slices.Reverse(chain)
+		if !matchGlob(r.path, chain) {
+			return false
+		}
+	}
+	if r.re != nil {
+		v, ok := valueText(node)
+		return ok && r.re.MatchString(v)
+	}
+	return true
+}
+
+// valueText returns the text value rules are matched against, json text for numbers
+func valueText(node *Node) (string, bool) {
+	if node.IsNumber() {
+		return node.Stringify(), true
+	}
+	v, ok := node.value.(string)
+	return v, ok
+}
+
+// redactText returns the replacement of matched text
+func (r *redactRule) redactText(s string) string {
+	switch r.Action {
+	case RedactHash:
+		sum := sha256.Sum256([]byte(r.Salt + s))
+		return hex.EncodeToString(sum[:])
+	case RedactMask:
+		keep := r.Keep
+		if keep == 0 {
+			keep = 4
+		}
+		rs := []rune(s)
+		masked := max(len(rs)-keep, 0)
+		if masked == 0 {
+			masked = len(rs)
+		}
+		return strings.Repeat("*", masked) + string(rs[masked:])
+	}
+	if r.Replacement == "" {
+		return "***"
+	}
+	return r.Replacement
+}
+
+// redact returns the redacted copy of node
+func (r *redactRule) redact(node *Node) *Node {
+	if r.re != nil {
+		v, _ := valueText(node)
+		return NewString(r.re.ReplaceAllStringFunc(v, r.redactText))
+	}
+	if r.Action == RedactReplace {
+		return NewString(r.redactText(""))
+	}
+	s, ok := node.value.(string)
+	if !ok {
+		s = node.Stringify()
+	}
+	return NewString(r.redactText(s))
+}
+
+type redactor struct {
+	root   *Node
+	rules  []*redactRule
+	report []Redaction
+}
+
+// Redact returns the copy of root where values matched by rules are redacted and the report
+// about redacted nodes in the order of appearance. The first matched rule is applied,
+// children of redacted containers are not checked. The root can not be removed.
+func Redact(root *Node, rules []RedactRule) (*Node, []Redaction, error) {
+	if !root.Exists() {
+		return nil, nil, ErrNilNode
+	}
+	r := &redactor{root: root}
+	for i := range rules {
+		rule, err := compileRedactRule(&rules[i])
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w (rule %d)", err, i)
+		}
+		r.rules = append(r.rules, rule)
+	}
+	ret, removed, err := r.redact(root, "$")
+	if err != nil {
+		return nil, nil, err
+	}
+	if removed {
+		return nil, nil, fmt.Errorf("%w %s", ErrNoParent, "root can not be removed")
+	}
+	return ret, r.report, nil
+}
+
+func (r *redactor) redact(node *Node, path string) (*Node, bool, error) {
+	for i, rule := range r.rules {
+		if !rule.match(node, r.root) {
+			continue
+		}
+		r.report = append(r.report, Redaction{Path: path, Rule: i, Action: rule.Action})
+		if rule.Action == RedactRemove {
+			return nil, true, nil
+		}
+		return rule.redact(node), false, nil
+	}
+	if node.IsScalar() {
+		return detach(node), false, nil
+	}
+	ret := NewArray()
+	if node.IsObject() {
+		ret = NewObject()
+	}
+	for _, child := range node.children {
+		redacted, removed, err := r.redact(child, path+child.pathSegment())
+		if err != nil {
+			return nil, false, err
+		}
+		if removed {
+			continue
+		}
+		if err := ret.link(child.key, redacted); err != nil {
+			return nil, false, err
+		}
+	}
+	return ret, false, nil
+}
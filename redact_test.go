@@ -0,0 +1,132 @@
+package xtjson
+
+import (
+	"errors"
+	"testing"
+)
+
+const redactJson = `{"user":{"name":"Bob","Password":"secret","apiToken":"t1","card":4111111111111111},` +
+	`"session":{"ACCESS_TOKEN":"x","id":7},"log":["paid by 4111 1111 1111 1234","auth eyJhbGciOi.eyJzdWIiOi.c2ln ok"],` +
+	`"items":[{"ssn":"123-45-6789","v":1},{"ssn":"987-65-4321","v":2}],"debug":{"trace":[1,2]}}`
+
+func TestRedact(t *testing.T) {
+	root, err := ParseString(redactJson)
+	assertParsed(t, root, err)
+	rules := []RedactRule{
+		{Key: "password"},
+		{Key: "*token*", Action: RedactRemove},
+		{Key: "card", Action: RedactMask},
+		{Value: `\b(?:\d{4} ?){3}\d{4}\b`, Action: RedactMask, Keep: 2},
+		{Value: `eyJ[\w-]*\.[\w-]*\.[\w-]*`, Replacement: "<jwt>"},
+		{Path: "$.items[*].ssn", Action: RedactHash, Salt: "s"},
+		{Key: "debug", Replacement: "[removed]"},
+	}
+	ret, report, err := Redact(root, rules)
+	assertNil(t, err)
+	assertEqual(t, `{"user":{"name":"Bob","Password":"***","card":"************1111"},"session":{"id":7},`+
+		`"log":["paid by *****************34","auth <jwt> ok"],`+
+		`"items":[{"ssn":"de55c3544024c7a32139be1aa32537133d13a28c5d777a96e0ff35f5e2521251","v":1},`+
+		`{"ssn":"52b648256588c965dc10fb69a786d793ef8d0f5f9300fcc1eedc8325371e95c1","v":2}],"debug":"[removed]"}`, ret.Stringify())
+	assertEqual(t, []Redaction{
+		{"$.user.Password", 0, RedactReplace},
+		{"$.user.apiToken", 1, RedactRemove},
+		{"$.user.card", 2, RedactMask},
+		{"$.session.ACCESS_TOKEN", 1, RedactRemove},
+		{"$.log[0]", 3, RedactMask},
+		{"$.log[1]", 4, RedactReplace},
+		{"$.items[0].ssn", 5, RedactHash},
+		{"$.items[1].ssn", 5, RedactHash},
+		{"$.debug", 6, RedactReplace},
+	}, report)
+	assertNil(t, ret.Validate())
+	assertEqual(t, redactJson, root.Stringify())
+}
+
+func TestRedactText(t *testing.T) {
+	cases := []struct {
+		rule     RedactRule
+		value    string
+		expected string
+	}{
+		{RedactRule{}, "abc", "***"},
+		{RedactRule{Replacement: "-"}, "abc", "-"},
+		{RedactRule{Action: RedactMask}, "abcdef", "**cdef"},
+		{RedactRule{Action: RedactMask}, "abcd", "****"},
+		{RedactRule{Action: RedactMask, Keep: 1}, "пароль", "*****ь"},
+		{RedactRule{Action: RedactHash}, "abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+	}
+	for _, c := range cases {
+		rule := &redactRule{RedactRule: &c.rule}
+		assertEqual(t, c.expected, rule.redactText(c.value))
+	}
+}
+
+func TestRedactRoot(t *testing.T) {
+	root, err := ParseString(`{"a":[{"k":1}]}`)
+	assertParsed(t, root, err)
+	ret, report, err := Redact(root, []RedactRule{{Path: "$"}})
+	assertNil(t, err)
+	assertEqual(t, `"***"`, ret.Stringify())
+	assertEqual(t, []Redaction{{"$", 0, RedactReplace}}, report)
+
+	// rules are checked against paths relative to the redacted root, the same as in the report
+	ret, report, err = Redact(root.Path("$.a"), []RedactRule{{Path: "$[0].k", Action: RedactHash}, {Path: "$.a[0].k"}})
+	assertNil(t, err)
+	assertEqual(t, `[{"k":"6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b"}]`, ret.Stringify())
+	assertEqual(t, []Redaction{{"$[0].k", 0, RedactHash}}, report)
+	ret, report, err = Redact(root.Path("$.a"), []RedactRule{{Path: "$.a[0].k"}})
+	assertNil(t, err)
+	assertEqual(t, `[{"k":1}]`, ret.Stringify())
+	assertEqual(t, 0, len(report))
+
+	ret, report, err = Redact(root, []RedactRule{{Key: "x"}})
+	assertNil(t, err)
+	assertEqual(t, root.Stringify(), ret.Stringify())
+	assertEqual(t, 0, len(report))
+
+	_, _, err = Redact(root, []RedactRule{{Path: "$", Action: RedactRemove}})
+	if !errors.Is(err, ErrNoParent) {
+		t.Fatal("expected error ErrNoParent")
+	}
+	_, _, err = Redact(nil, nil)
+	if !errors.Is(err, ErrNilNode) {
+		t.Fatal("expected error ErrNilNode")
+	}
+}
+
+func TestRedactGlob(t *testing.T) {
+	root, err := ParseString(`{"auth/token":"abc","a/b":1,"ab":2,"x":{"c/d":"e"},"pin":1234,"n":"1234"}`)
+	assertParsed(t, root, err)
+	ret, report, err := Redact(root, []RedactRule{
+		{Key: "*TOKEN*"},
+		{Path: "$.a*"},
+		{Key: "c?d", Action: RedactRemove},
+		{Key: "p[!x]n", Value: `^\d{4}$`, Action: RedactMask, Keep: 1},
+		{Value: `^\d+$`, Replacement: "#"},
+	})
+	assertNil(t, err)
+	assertEqual(t, `{"auth/token":"***","a/b":"***","ab":"***","x":{},"pin":"***4","n":"#"}`, ret.Stringify())
+	assertEqual(t, []Redaction{
+		{"$.auth/token", 0, RedactReplace},
+		{"$.a/b", 1, RedactReplace},
+		{"$.ab", 1, RedactReplace},
+		{"$.x.c/d", 2, RedactRemove},
+		{"$.pin", 3, RedactMask},
+		{"$.n", 4, RedactReplace},
+	}, report)
+}
+
+func TestRedactErrors(t *testing.T) {
+	root, err := ParseString(redactJson)
+	assertParsed(t, root, err)
+	for _, rule := range []RedactRule{{}, {Key: "["}, {Key: "a\\"}, {Key: "[]"}, {Path: "a"}, {Value: "("}, {Key: "a", Action: 9}, {Key: "a", Keep: -1}} {
+		_, _, err = Redact(root, []RedactRule{{Key: "a"}, rule})
+		if !errors.Is(err, ErrBadRedactRule) {
+			t.Fatalf("expected error ErrBadRedactRule for %v, got %v", rule, err)
+		}
+	}
+	_, _, err = Redact(root, []RedactRule{{Value: "("}})
+	if !errors.Is(err, ErrInvalidRegexp) {
+		t.Fatal("expected error ErrInvalidRegexp")
+	}
+}
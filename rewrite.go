@@ -0,0 +1,89 @@
+package xtjson
+
+import "fmt"
+
+// Action tells Rewrite what to do with visited node
+type Action int
+
+const (
+	ActionKeep         Action = iota // keep the node and visit its children
+	ActionReplace                    // replace the node by returned one, its children are not visited
+	ActionRemove                     // remove the node from its parent
+	ActionSkipChildren               // keep the node and do not visit its children
+)
+
+// RewriteFunc is called by Rewrite for visited node with its path relative to the root,
+// replacement is used only with ActionReplace
+type RewriteFunc func(node *Node, path string) (replacement *Node, action Action)
+
+// Rewrite walks the tree depth first and applies actions returned by fn, it is named
+// Rewrite and not Transform as the latter is the jq-like expression language.
+// Paths passed to fn reflect the changes already made, so array indexes
+// following removed elements are shifted. The root after rewriting is returned,
+// it differs from provided one when the root itself is replaced and it is nil when
+// the root is removed. Only the subtree root linked to a parent can be removed,
+// it is detached from the parent.
+func Rewrite(root *Node, fn RewriteFunc) (*Node, error) {
+	if root == nil || root == undef {
+		return nil, ErrNilNode
+	}
+	replacement, action := fn(root, "$")
+	switch action {
+	case ActionRemove:
+		if root.parent == nil {
+			return nil, fmt.Errorf("%w %s", ErrNoParent, "root can not be removed")
+		}
+		return nil, root.Remove()
+	case ActionReplace:
+		if err := checkReplacement(replacement); err != nil {
+			return nil, err
+		}
+		if root.parent != nil {
+			if err := root.Replace(replacement); err != nil {
+				return nil, err
+			}
+		}
+		return replacement, nil
+	case ActionSkipChildren:
+		return root, nil
+	}
+	return root, rewriteChildren(root, "$", fn)
+}
+
+func checkReplacement(node *Node) error {
+	if node == nil || node == undef {
+		return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "replace by nil node")
+	}
+	if node.parent != nil {
+		return fmt.Errorf("%w %s", ErrNodeHasParent, "attempt to replace by node linked to another parent")
+	}
+	return nil
+}
+
+func rewriteChildren(parent *Node, path string, fn RewriteFunc) error {
+	for i := 0; i < len(parent.children); {
+		child := parent.children[i]
+		childPath := path + child.pathSegment()
+		replacement, action := fn(child, childPath)
+		switch action {
+		case ActionRemove:
+			if err := child.Remove(); err != nil {
+				return err
+			}
+			continue
+		case ActionReplace:
+			if err := checkReplacement(replacement); err != nil {
+				return err
+			}
+			if err := child.Replace(replacement); err != nil {
+				return err
+			}
+		case ActionKeep:
+			if err := rewriteChildren(child, childPath, fn); err != nil {
+				return err
+			}
+		}
+		i++
+	}
+	return nil
+}
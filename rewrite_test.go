@@ -0,0 +1,105 @@
+package xtjson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	root, err := ParseString(`{"user":{"name":"Bob","password":"x"},"items":[1,null,2,null,null,3],"tags":["A","b"],"raw":{"keep":"AS IS"}}`)
+	assertParsed(t, root, err)
+	var visited []string
+	ret, err := Rewrite(root, func(n *Node, path string) (*Node, Action) {
+		visited = append(visited, path)
+		switch {
+		case n.SelfKey() == "password":
+			return NewString("***"), ActionReplace
+		case n.IsNull():
+			return nil, ActionRemove
+		case n.SelfKey() == "raw":
+			return nil, ActionSkipChildren
+		case n.IsString():
+			return NewString(strings.ToLower(n.value.(string))), ActionReplace
+		}
+		return nil, ActionKeep
+	})
+	assertNil(t, err)
+	assertEqual(t, root, ret)
+	assertEqual(t, `{"user":{"name":"bob","password":"***"},"items":[1,2,3],"tags":["a","b"],"raw":{"keep":"AS IS"}}`, root.Stringify())
+	// paths reflect shifted indexes after removals
+	assertEqual(t, []string{"$", "$.user", "$.user.name", "$.user.password", "$.items", "$.items[0]", "$.items[1]", "$.items[1]",
+		"$.items[2]", "$.items[2]", "$.items[2]", "$.tags", "$.tags[0]", "$.tags[1]", "$.raw"}, visited)
+}
+
+func TestRewriteRoot(t *testing.T) {
+	root, err := ParseString(`{"a":{"b":1}}`)
+	assertParsed(t, root, err)
+
+	ret, err := Rewrite(root, func(n *Node, path string) (*Node, Action) {
+		return nil, ActionSkipChildren
+	})
+	assertNil(t, err)
+	assertEqual(t, root, ret)
+
+	// replacing the subtree root links replacement to the parent
+	ret, err = Rewrite(root.Key("a"), func(n *Node, path string) (*Node, Action) {
+		return NewInt(5), ActionReplace
+	})
+	assertNil(t, err)
+	assertEqual(t, `{"a":5}`, root.Stringify())
+	assertEqual(t, root, ret.Parent())
+
+	// removing the subtree root detaches it from the parent
+	assertNil(t, root.SetInt("c", 2))
+	c := root.Key("c")
+	ret, err = Rewrite(c, func(n *Node, path string) (*Node, Action) {
+		return nil, ActionRemove
+	})
+	assertNil(t, err)
+	assertEqual(t, (*Node)(nil), ret)
+	assertEqual(t, `{"a":5}`, root.Stringify())
+	assertEqual(t, false, c.Parent().Exists())
+
+	ret, err = Rewrite(root, func(n *Node, path string) (*Node, Action) {
+		return NewNull(), ActionReplace
+	})
+	assertNil(t, err)
+	assertEqual(t, `null`, ret.Stringify())
+
+	_, err = Rewrite(root, func(n *Node, path string) (*Node, Action) {
+		return nil, ActionRemove
+	})
+	if !errors.Is(err, ErrNoParent) {
+		t.Fatal("expected error ErrNoParent")
+	}
+	_, err = Rewrite(nil, func(n *Node, path string) (*Node, Action) {
+		return nil, ActionKeep
+	})
+	if !errors.Is(err, ErrNilNode) {
+		t.Fatal("expected error ErrNilNode")
+	}
+}
+
+func TestRewriteInvalidReplacement(t *testing.T) {
+	root, err := ParseString(`{"a":1,"b":2}`)
+	assertParsed(t, root, err)
+	_, err = Rewrite(root, func(n *Node, path string) (*Node, Action) {
+		if path == "$.a" {
+			return nil, ActionReplace
+		}
+		return nil, ActionKeep
+	})
+	if !errors.Is(err, ErrInvalidNodeForOperation) {
+		t.Fatal("expected error ErrInvalidNodeForOperation")
+	}
+	_, err = Rewrite(root, func(n *Node, path string) (*Node, Action) {
+		if path == "$.a" {
+			return root.Key("b"), ActionReplace
+		}
+		return nil, ActionKeep
+	})
+	if !errors.Is(err, ErrNodeHasParent) {
+		t.Fatal("expected error ErrNodeHasParent")
+	}
+}
@@ -0,0 +1,217 @@
+package schema
+
+import (
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// formats contains checkers of formats asserted with Options.AssertFormat, unknown formats are ignored
+var formats = map[string]func(string) bool{
+	"date-time":             isDateTime,
+	"date":                  isDate,
+	"time":                  isTime,
+	"duration":              durationRe.MatchString,
+	"email":                 isEmail,
+	"hostname":              isHostname,
+	"ipv4":                  isIPv4,
+	"ipv6":                  isIPv6,
+	"uri":                   isURI,
+	"uri-reference":         isURIReference,
+	"iri":                   isURI,
+	"iri-reference":         isURIReference,
+	"uri-template":          isURITemplate,
+	"uuid":                  uuidRe.MatchString,
+	"regex":                 isRegex,
+	"json-pointer":          isJSONPointer,
+	"relative-json-pointer": isRelativeJSONPointer,
+}
+
+// varspec is RFC 6570 variable name with optional prefix length or explode modifier
+const varspec = `(?:[A-Za-z0-9_]|%[0-9A-Fa-f]{2})(?:\.?(?:[A-Za-z0-9_]|%[0-9A-Fa-f]{2}))*(?::[1-9][0-9]{0,3}|\*)?`
+
+var (
+	durationRe   = regexp.MustCompile(`^P(?:\d+W|(?:\d+Y(?:\d+M)?(?:\d+D)?|\d+M(?:\d+D)?|\d+D)(?:T(?:\d+H(?:\d+M)?(?:\d+S)?|\d+M(?:\d+S)?|\d+S))?|T(?:\d+H(?:\d+M)?(?:\d+S)?|\d+M(?:\d+S)?|\d+S))$`)
+	uuidRe       = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	timeRe       = regexp.MustCompile(`^(\d{2}):(\d{2}):(\d{2})(\.\d+)?([zZ]|([+-])(\d{2}):(\d{2}))$`)
+	labelRe      = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+	atomRe       = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+/=?^_`{|}~-]+$")
+	quotedRe     = regexp.MustCompile(`^"(?:[^"\\\x00-\x1f]|\\[\x20-\x7e])*"$`)
+	expressionRe = regexp.MustCompile(`^[+#./;?&=,!@|]?` + varspec + `(?:,` + varspec + `)*$`)
+)
+
+// isDate checks RFC 3339 full-date
+func isDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
+
+// isTime checks RFC 3339 full-time, leap second is allowed at 23:59:60 UTC
+func isTime(s string) bool {
+	m := timeRe.FindStringSubmatch(s)
+	if m == nil {
+		return false
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute, _ := strconv.Atoi(m[2])
+	second, _ := strconv.Atoi(m[3])
+	if hour > 23 || minute > 59 || second > 60 {
+		return false
+	}
+	if m[6] != "" {
+		offHour, _ := strconv.Atoi(m[7])
+		offMinute, _ := strconv.Atoi(m[8])
+		if offHour > 23 || offMinute > 59 {
+			return false
+		}
+		if second == 60 {
+			// leap second is checked in UTC
+			offset := offHour*60 + offMinute
+			if m[6] == "+" {
+				offset = -offset
+			}
+			utc := ((hour*60+minute+offset)%1440 + 1440) % 1440
+			return utc == 23*60+59
+		}
+	}
+	return second < 60 || hour == 23 && minute == 59
+}
+
+// isDateTime checks RFC 3339 date-time
+func isDateTime(s string) bool {
+	date, t, ok := strings.Cut(s, "T")
+	if !ok {
+		date, t, ok = strings.Cut(s, "t")
+	}
+	return ok && isDate(date) && isTime(t)
+}
+
+// isEmail checks RFC 5321 mailbox, the local part is dot-atom or quoted string
+// and the domain is host name or address literal like [127.0.0.1] or [IPv6:::1]
+func isEmail(s string) bool {
+	i := strings.LastIndexByte(s, '@')
+	if i < 0 {
+		return false
+	}
+	local, domain := s[:i], s[i+1:]
+	if !quotedRe.MatchString(local) {
+		for _, atom := range strings.Split(local, ".") {
+			if !atomRe.MatchString(atom) {
+				return false
+			}
+		}
+	}
+	if literal, ok := strings.CutPrefix(domain, "["); ok {
+		literal, ok = strings.CutSuffix(literal, "]")
+		if v6, isV6 := strings.CutPrefix(literal, "IPv6:"); isV6 {
+			return ok && isIPv6(v6)
+		}
+		return ok && isIPv4(literal)
+	}
+	return isHostname(domain)
+}
+
+// isHostname checks RFC 1123 host name
+func isHostname(s string) bool {
+	s = strings.TrimSuffix(s, ".")
+	if s == "" || len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if !labelRe.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+func isIPv4(s string) bool {
+	addr, err := netip.ParseAddr(s)
+	return err == nil && addr.Is4()
+}
+
+func isIPv6(s string) bool {
+	addr, err := netip.ParseAddr(s)
+	return err == nil && addr.Is6() && addr.Zone() == ""
+}
+
+// isURI checks RFC 3986 uri, non ASCII characters are accepted for iri format
+func isURI(s string) bool {
+	u, ok := parseURI(s)
+	return ok && u.IsAbs()
+}
+
+func isURIReference(s string) bool {
+	_, ok := parseURI(s)
+	return ok
+}
+
+func parseURI(s string) (*url.URL, bool) {
+	u, err := url.Parse(s)
+	if err != nil || strings.ContainsAny(s, " \\") {
+		return nil, false
+	}
+	// IPv6 address must be enclosed in brackets
+	if !strings.HasPrefix(u.Host, "[") && strings.Contains(u.Hostname(), ":") {
+		return nil, false
+	}
+	return u, true
+}
+
+// isURITemplate checks RFC 6570 uri template
+func isURITemplate(s string) bool {
+	for {
+		i := strings.IndexAny(s, "{}")
+		literal := s
+		if i >= 0 {
+			literal = s[:i]
+		}
+		if strings.ContainsAny(literal, " \"'<>\\^`|") {
+			return false
+		}
+		if i < 0 {
+			return true
+		}
+		if s[i] == '}' {
+			return false
+		}
+		end := strings.IndexAny(s[i+1:], "{}")
+		if end < 0 || s[i+1+end] != '}' || !expressionRe.MatchString(s[i+1:i+1+end]) {
+			return false
+		}
+		s = s[i+end+2:]
+	}
+}
+
+func isRegex(s string) bool {
+	_, err := compilePattern(s)
+	return err == nil
+}
+
+// isJSONPointer checks RFC 6901 json pointer
+func isJSONPointer(s string) bool {
+	if s != "" && s[0] != '/' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '~' && (i+1 == len(s) || s[i+1] != '0' && s[i+1] != '1') {
+			return false
+		}
+	}
+	return true
+}
+
+// isRelativeJSONPointer checks relative json pointer like 1/a or 0#
+func isRelativeJSONPointer(s string) bool {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i == 0 || i > 1 && s[0] == '0' {
+		return false
+	}
+	return s[i:] == "#" || isJSONPointer(s[i:])
+}
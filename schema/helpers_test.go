@@ -0,0 +1,29 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/webzak/xtjson"
+)
+
+func assertEqual(t *testing.T, expected, actual any) {
+	t.Helper()
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %v, Actual: %v", expected, actual)
+	}
+}
+
+func assertNil(t *testing.T, value error) {
+	t.Helper()
+	if value != nil {
+		t.Fatalf("value: %v expected to be nil", value)
+	}
+}
+
+func parseNode(t *testing.T, src string) *xtjson.Node {
+	t.Helper()
+	node, err := xtjson.ParseString(src)
+	assertNil(t, err)
+	return node
+}
@@ -0,0 +1,87 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ecmaSpace is the content of character class matching ECMA 262 white space and line terminators
+const ecmaSpace = `\t\n\v\f\r \x{a0}\x{1680}\x{2000}-\x{200a}\x{2028}\x{2029}\x{202f}\x{205f}\x{3000}\x{feff}`
+
+// pattern is the regular expression compiled from ECMA 262 source which is kept for messages
+type pattern struct {
+	*regexp.Regexp
+	source string
+}
+
+func (p *pattern) String() string {
+	return p.source
+}
+
+// compilePattern compiles ECMA 262 regular expression translating escapes
+// which have different meaning or are not supported by regexp package
+func compilePattern(source string) (*pattern, error) {
+	translated, err := translatePattern(source)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(translated)
+	if err != nil {
+		return nil, err
+	}
+	return &pattern{Regexp: re, source: source}, nil
+}
+
+func translatePattern(source string) (string, error) {
+	var b strings.Builder
+	rs := []rune(source)
+	inClass := false
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		switch {
+		case r == '[' && !inClass:
+			inClass = true
+		case r == ']' && inClass:
+			inClass = false
+		case r == '\\' && i+1 < len(rs):
+			i++
+			switch e := rs[i]; {
+			case e == 'c' && i+1 < len(rs) && isASCIILetter(rs[i+1]):
+				i++
+				fmt.Fprintf(&b, `\x{%02x}`, rs[i]%32)
+			case e == 'u' && i+4 < len(rs) && isHex(rs[i+1:i+5]):
+				fmt.Fprintf(&b, `\x{%s}`, string(rs[i+1:i+5]))
+				i += 4
+			case e == 's' && inClass:
+				b.WriteString(ecmaSpace)
+			case e == 's':
+				b.WriteString("[" + ecmaSpace + "]")
+			case e == 'S' && !inClass:
+				b.WriteString("[^" + ecmaSpace + "]")
+			case e == 'a':
+				return "", errors.New(`\a is not ECMA 262 escape`)
+			default:
+				b.WriteRune('\\')
+				b.WriteRune(e)
+			}
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), nil
+}
+
+func isASCIILetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+func isHex(rs []rune) bool {
+	for _, r := range rs {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,26 @@
+package schema
+
+import "testing"
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		source string
+		match  string
+		expect bool
+	}{
+		{`^\s$`, " ", true},
+		{`^[\s]$`, "\u00a0", true},
+		{`^\S$`, " ", false},
+		{`^\cJ$`, "\n", true},
+		{`^\u00e9$`, "é", true},
+		{`^\d$`, "٣", false},
+	}
+	for _, tt := range tests {
+		p, err := compilePattern(tt.source)
+		assertNil(t, err)
+		assertEqual(t, tt.expect, p.MatchString(tt.match))
+		assertEqual(t, tt.source, p.String())
+	}
+	_, err := compilePattern(`\a`)
+	assertEqual(t, true, err != nil)
+}
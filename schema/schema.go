@@ -0,0 +1,596 @@
+// Package schema validates xtjson trees against JSON Schema draft 2020-12.
+//
+// References are resolved within the schema document only: $ref and $dynamicRef can
+// point to json pointers, $anchor and $dynamicAnchor names and embedded resources
+// identified by $id. Format is an annotation unless Options.AssertFormat is set.
+// Patterns are ECMA 262 regular expressions translated to the regexp syntax.
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/webzak/xtjson"
+)
+
+var (
+	ErrInvalidSchema = errors.New("invalid schema")
+	ErrUnresolvedRef = errors.New("unresolved reference")
+)
+
+// defaultBase is the base URI of schema without $id
+const defaultBase = "xtjson:///schema.json"
+
+var anchorRe = regexp.MustCompile(`^[A-Za-z_][-A-Za-z0-9._]*$`)
+
+// Options of schema compilation
+type Options struct {
+	AssertFormat bool `json:"assertFormat,omitempty"` // format keyword fails validation of invalid strings
+}
+
+// Schema is the compiled schema, it is immutable and safe for concurrent use
+type Schema struct {
+	root *schema
+	opt  Options
+}
+
+// resource is the schema document or embedded schema identified by $id
+type resource struct {
+	uri            string
+	node           *xtjson.Node
+	dynamicAnchors map[string]*schema
+}
+
+type namedSchema struct {
+	name   string
+	schema *schema
+}
+
+type patternSchema struct {
+	pattern *pattern
+	schema  *schema
+}
+
+type namedList struct {
+	keyword string // dependentRequired or dependencies
+	name    string
+	names   []string
+}
+
+// schema is the compiled schema object or boolean schema
+type schema struct {
+	node    *xtjson.Node
+	res     *resource
+	boolean *bool
+
+	ref       string
+	refTarget *schema
+	dynRef    string
+	dynTarget *schema
+	dynAnchor string // set when $dynamicRef initially resolves to schema with the same $dynamicAnchor
+	hasAnchor string // $dynamicAnchor of this schema
+
+	types         []string
+	enum          []*xtjson.Node
+	constant      *xtjson.Node
+	multipleOf    *big.Rat
+	maximum       *float64
+	minimum       *float64
+	exclMaximum   *float64
+	exclMinimum   *float64
+	maxLength     int
+	minLength     int
+	pattern       *pattern
+	format        string
+	maxItems      int
+	minItems      int
+	uniqueItems   bool
+	maxContains   int
+	minContains   int
+	maxProperties int
+	minProperties int
+	required      []string
+	dependentReq  []namedList
+
+	allOf                 []*schema
+	anyOf                 []*schema
+	oneOf                 []*schema
+	not                   *schema
+	ifSchema              *schema
+	thenSchema            *schema
+	elseSchema            *schema
+	dependentSchemas      []namedSchema
+	dependencies          []namedSchema // schemas of pre 2019-09 dependencies keyword
+	prefixItems           []*schema
+	items                 *schema
+	contains              *schema
+	properties            []namedSchema
+	patternProperties     []patternSchema
+	additionalProperties  *schema
+	propertyNames         *schema
+	unevaluatedItems      *schema
+	unevaluatedProperties *schema
+}
+
+type compiler struct {
+	root      *xtjson.Node
+	resources map[string]*resource
+	anchors   map[string]*schema
+	compiled  map[*xtjson.Node]*schema
+	pending   []*schema
+}
+
+// Compile compiles schema document, the schema must not be modified while compiled schema is used
+func Compile(schemaNode *xtjson.Node, opts ...*Options) (*Schema, error) {
+	if !schemaNode.Exists() {
+		return nil, xtjson.ErrNilNode
+	}
+	c := &compiler{
+		root:      schemaNode,
+		resources: make(map[string]*resource),
+		anchors:   make(map[string]*schema),
+		compiled:  make(map[*xtjson.Node]*schema),
+	}
+	root, err := c.compile(schemaNode, &resource{uri: defaultBase, node: schemaNode})
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := c.resources[defaultBase]; !ok {
+		c.resources[defaultBase] = root.res
+	}
+	for len(c.pending) > 0 {
+		s := c.pending[0]
+		c.pending = c.pending[1:]
+		if err = c.resolveRefs(s); err != nil {
+			return nil, err
+		}
+	}
+	ret := &Schema{root: root}
+	if len(opts) > 0 && opts[0] != nil {
+		ret.opt = *opts[0]
+	}
+	return ret, nil
+}
+
+func (c *compiler) errorf(node *xtjson.Node, format string, args ...any) error {
+	return fmt.Errorf("%w: %s at #%s", ErrInvalidSchema, fmt.Sprintf(format, args...), xtjson.RelativePointer(c.root, node))
+}
+
+// resolveURI resolves reference against base uri
+func resolveURI(base, ref string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return b.ResolveReference(r).String(), nil
+}
+
+// splitFragment splits uri to the resource uri and decoded fragment
+func splitFragment(uri string) (string, string, error) {
+	base, fragment, _ := strings.Cut(uri, "#")
+	fragment, err := url.PathUnescape(fragment)
+	return base, fragment, err
+}
+
+func (c *compiler) compile(node *xtjson.Node, res *resource) (*schema, error) {
+	if s, ok := c.compiled[node]; ok {
+		return s, nil
+	}
+	s := &schema{node: node, res: res, maxLength: -1, minLength: -1, maxItems: -1, minItems: -1,
+		maxContains: -1, minContains: -1, maxProperties: -1, minProperties: -1}
+	if node.IsBool() {
+		v, _ := node.Bool()
+		s.boolean = &v
+		c.compiled[node] = s
+		return s, nil
+	}
+	if !node.IsObject() {
+		return nil, c.errorf(node, "schema must be object or boolean")
+	}
+	if id := node.Key("$id"); id.Exists() {
+		v, err := id.String()
+		if err != nil {
+			return nil, c.errorf(id, "$id must be string")
+		}
+		uri, err := resolveURI(res.uri, v)
+		if err != nil {
+			return nil, c.errorf(id, "invalid $id %q", v)
+		}
+		uri, fragment, _ := strings.Cut(uri, "#")
+		if fragment != "" {
+			return nil, c.errorf(id, "$id %q must not contain fragment", v)
+		}
+		res = &resource{uri: uri, node: node}
+		s.res = res
+		c.resources[uri] = res
+	}
+	c.compiled[node] = s
+	if err := c.compileAnchors(s); err != nil {
+		return nil, err
+	}
+	if err := c.compileAssertions(s); err != nil {
+		return nil, err
+	}
+	if err := c.compileApplicators(s); err != nil {
+		return nil, err
+	}
+	for _, keyword := range []string{"$ref", "$dynamicRef"} {
+		ref := node.Key(keyword)
+		if !ref.Exists() {
+			continue
+		}
+		v, err := ref.String()
+		if err != nil {
+			return nil, c.errorf(ref, "%s must be string", keyword)
+		}
+		uri, err := resolveURI(res.uri, v)
+		if err != nil {
+			return nil, c.errorf(ref, "invalid %s %q", keyword, v)
+		}
+		if keyword == "$ref" {
+			s.ref = uri
+		} else {
+			s.dynRef = uri
+		}
+	}
+	if s.ref != "" || s.dynRef != "" {
+		c.pending = append(c.pending, s)
+	}
+	return s, nil
+}
+
+func (c *compiler) compileAnchors(s *schema) error {
+	for _, keyword := range []string{"$anchor", "$dynamicAnchor"} {
+		anchor := s.node.Key(keyword)
+		if !anchor.Exists() {
+			continue
+		}
+		name, err := anchor.String()
+		if err != nil || !anchorRe.MatchString(name) {
+			return c.errorf(anchor, "invalid %s", keyword)
+		}
+		c.anchors[s.res.uri+"#"+name] = s
+		if keyword == "$dynamicAnchor" {
+			if s.res.dynamicAnchors == nil {
+				s.res.dynamicAnchors = make(map[string]*schema)
+			}
+			s.res.dynamicAnchors[name] = s
+			s.hasAnchor = name
+		}
+	}
+	return nil
+}
+
+// number returns the value of numeric keyword
+func (c *compiler) number(s *schema, keyword string) (*float64, error) {
+	node := s.node.Key(keyword)
+	if !node.Exists() {
+		return nil, nil
+	}
+	v, err := node.Number()
+	if err != nil {
+		return nil, c.errorf(node, "%s must be number", keyword)
+	}
+	return &v, nil
+}
+
+// count returns the value of non negative integer keyword or -1 if it is not set
+func (c *compiler) count(s *schema, keyword string) (int, error) {
+	node := s.node.Key(keyword)
+	if !node.Exists() {
+		return -1, nil
+	}
+	v, err := node.Int()
+	if err != nil || v < 0 {
+		return 0, c.errorf(node, "%s must be non negative integer", keyword)
+	}
+	return v, nil
+}
+
+func (c *compiler) stringList(node *xtjson.Node, keyword string) ([]string, error) {
+	if !node.IsArray() {
+		return nil, c.errorf(node, "%s must be array of strings", keyword)
+	}
+	var ret []string
+	for _, child := range node.Children() {
+		v, err := child.String()
+		if err != nil {
+			return nil, c.errorf(child, "%s must be array of strings", keyword)
+		}
+		ret = append(ret, v)
+	}
+	return ret, nil
+}
+
+// rational converts number to exact rational using its shortest decimal representation
+func rational(v float64) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(v, 'g', -1, 64))
+	return r
+}
+
+var schemaTypes = map[string]bool{"null": true, "boolean": true, "object": true, "array": true,
+	"number": true, "integer": true, "string": true}
+
+func (c *compiler) compileAssertions(s *schema) error {
+	node := s.node
+	if t := node.Key("type"); t.Exists() {
+		if t.IsString() {
+			v, _ := t.String()
+			s.types = []string{v}
+		} else {
+			types, err := c.stringList(t, "type")
+			if err != nil {
+				return err
+			}
+			s.types = types
+		}
+		for _, v := range s.types {
+			if !schemaTypes[v] {
+				return c.errorf(t, "unknown type %q", v)
+			}
+		}
+	}
+	if enum := node.Key("enum"); enum.Exists() {
+		if !enum.IsArray() {
+			return c.errorf(enum, "enum must be array")
+		}
+		s.enum = enum.Children()
+	}
+	if constant := node.Key("const"); constant.Exists() {
+		s.constant = constant
+	}
+	m, err := c.number(s, "multipleOf")
+	if err != nil {
+		return err
+	}
+	if m != nil {
+		if *m <= 0 {
+			return c.errorf(node.Key("multipleOf"), "multipleOf must be greater than 0")
+		}
+		s.multipleOf = rational(*m)
+	}
+	if s.maximum, err = c.number(s, "maximum"); err != nil {
+		return err
+	}
+	if s.minimum, err = c.number(s, "minimum"); err != nil {
+		return err
+	}
+	if s.exclMaximum, err = c.number(s, "exclusiveMaximum"); err != nil {
+		return err
+	}
+	if s.exclMinimum, err = c.number(s, "exclusiveMinimum"); err != nil {
+		return err
+	}
+	counts := []struct {
+		keyword string
+		dst     *int
+	}{{"maxLength", &s.maxLength}, {"minLength", &s.minLength}, {"maxItems", &s.maxItems}, {"minItems", &s.minItems},
+		{"maxContains", &s.maxContains}, {"minContains", &s.minContains},
+		{"maxProperties", &s.maxProperties}, {"minProperties", &s.minProperties}}
+	for _, count := range counts {
+		if *count.dst, err = c.count(s, count.keyword); err != nil {
+			return err
+		}
+	}
+	if pattern := node.Key("pattern"); pattern.Exists() {
+		v, err := pattern.String()
+		if err != nil {
+			return c.errorf(pattern, "pattern must be string")
+		}
+		if s.pattern, err = compilePattern(v); err != nil {
+			return c.errorf(pattern, "invalid pattern %q", v)
+		}
+	}
+	if format := node.Key("format"); format.Exists() {
+		if s.format, err = format.String(); err != nil {
+			return c.errorf(format, "format must be string")
+		}
+	}
+	if unique := node.Key("uniqueItems"); unique.Exists() {
+		if s.uniqueItems, err = unique.Bool(); err != nil {
+			return c.errorf(unique, "uniqueItems must be boolean")
+		}
+	}
+	if required := node.Key("required"); required.Exists() {
+		if s.required, err = c.stringList(required, "required"); err != nil {
+			return err
+		}
+	}
+	if dependent := node.Key("dependentRequired"); dependent.Exists() {
+		if !dependent.IsObject() {
+			return c.errorf(dependent, "dependentRequired must be object")
+		}
+		for _, child := range dependent.Children() {
+			names, err := c.stringList(child, "dependentRequired")
+			if err != nil {
+				return err
+			}
+			s.dependentReq = append(s.dependentReq, namedList{keyword: "dependentRequired", name: child.SelfKey(), names: names})
+		}
+	}
+	return nil
+}
+
+// subschema compiles the schema in keyword, nil is returned when keyword is absent
+func (c *compiler) subschema(s *schema, keyword string) (*schema, error) {
+	node := s.node.Key(keyword)
+	if !node.Exists() {
+		return nil, nil
+	}
+	return c.compile(node, s.res)
+}
+
+// subschemas compiles non empty array of schemas in keyword
+func (c *compiler) subschemas(s *schema, keyword string) ([]*schema, error) {
+	node := s.node.Key(keyword)
+	if !node.Exists() {
+		return nil, nil
+	}
+	if !node.IsArray() || node.ChildrenLength() == 0 {
+		return nil, c.errorf(node, "%s must be non empty array", keyword)
+	}
+	var ret []*schema
+	for _, child := range node.Children() {
+		sub, err := c.compile(child, s.res)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, sub)
+	}
+	return ret, nil
+}
+
+// namedSubschemas compiles object which values are schemas
+func (c *compiler) namedSubschemas(s *schema, keyword string) ([]namedSchema, error) {
+	node := s.node.Key(keyword)
+	if !node.Exists() {
+		return nil, nil
+	}
+	if !node.IsObject() {
+		return nil, c.errorf(node, "%s must be object", keyword)
+	}
+	var ret []namedSchema
+	for _, child := range node.Children() {
+		sub, err := c.compile(child, s.res)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, namedSchema{name: child.SelfKey(), schema: sub})
+	}
+	return ret, nil
+}
+
+// compileDependencies compiles dependencies keyword of earlier drafts which values are
+// either lists of required properties like in dependentRequired or schemas like in dependentSchemas
+func (c *compiler) compileDependencies(s *schema) error {
+	node := s.node.Key("dependencies")
+	if !node.Exists() {
+		return nil
+	}
+	if !node.IsObject() {
+		return c.errorf(node, "dependencies must be object")
+	}
+	for _, child := range node.Children() {
+		if child.IsArray() {
+			names, err := c.stringList(child, "dependencies")
+			if err != nil {
+				return err
+			}
+			s.dependentReq = append(s.dependentReq, namedList{keyword: "dependencies", name: child.SelfKey(), names: names})
+			continue
+		}
+		sub, err := c.compile(child, s.res)
+		if err != nil {
+			return err
+		}
+		s.dependencies = append(s.dependencies, namedSchema{name: child.SelfKey(), schema: sub})
+	}
+	return nil
+}
+
+func (c *compiler) compileApplicators(s *schema) error {
+	var err error
+	lists := []struct {
+		keyword string
+		dst     *[]*schema
+	}{{"allOf", &s.allOf}, {"anyOf", &s.anyOf}, {"oneOf", &s.oneOf}, {"prefixItems", &s.prefixItems}}
+	for _, list := range lists {
+		if *list.dst, err = c.subschemas(s, list.keyword); err != nil {
+			return err
+		}
+	}
+	singles := []struct {
+		keyword string
+		dst     **schema
+	}{{"not", &s.not}, {"if", &s.ifSchema}, {"then", &s.thenSchema}, {"else", &s.elseSchema},
+		{"items", &s.items}, {"contains", &s.contains}, {"additionalProperties", &s.additionalProperties},
+		{"propertyNames", &s.propertyNames}, {"unevaluatedItems", &s.unevaluatedItems},
+		{"unevaluatedProperties", &s.unevaluatedProperties}}
+	for _, single := range singles {
+		if *single.dst, err = c.subschema(s, single.keyword); err != nil {
+			return err
+		}
+	}
+	if s.properties, err = c.namedSubschemas(s, "properties"); err != nil {
+		return err
+	}
+	if s.dependentSchemas, err = c.namedSubschemas(s, "dependentSchemas"); err != nil {
+		return err
+	}
+	if err = c.compileDependencies(s); err != nil {
+		return err
+	}
+	patterns, err := c.namedSubschemas(s, "patternProperties")
+	if err != nil {
+		return err
+	}
+	for _, p := range patterns {
+		re, err := compilePattern(p.name)
+		if err != nil {
+			return c.errorf(s.node.Key("patternProperties"), "invalid pattern %q", p.name)
+		}
+		s.patternProperties = append(s.patternProperties, patternSchema{pattern: re, schema: p.schema})
+	}
+	if defs := s.node.Key("$defs"); defs.Exists() {
+		if !defs.IsObject() {
+			return c.errorf(defs, "$defs must be object")
+		}
+		for _, def := range defs.Children() {
+			if _, err = c.compile(def, s.res); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lookup returns the schema identified by absolute uri
+func (c *compiler) lookup(uri string) (*schema, error) {
+	base, fragment, err := splitFragment(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnresolvedRef, uri)
+	}
+	if fragment != "" && fragment[0] != '/' {
+		if s, ok := c.anchors[base+"#"+fragment]; ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("%w: %s", ErrUnresolvedRef, uri)
+	}
+	res, ok := c.resources[base]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnresolvedRef, uri)
+	}
+	node, err := res.node.ResolvePointer(fragment)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnresolvedRef, uri)
+	}
+	return c.compile(node, res)
+}
+
+func (c *compiler) resolveRefs(s *schema) error {
+	var err error
+	if s.ref != "" {
+		if s.refTarget, err = c.lookup(s.ref); err != nil {
+			return err
+		}
+	}
+	if s.dynRef != "" {
+		if s.dynTarget, err = c.lookup(s.dynRef); err != nil {
+			return err
+		}
+		// the dynamic scope is searched only when the initial target has the same dynamic anchor
+		if _, fragment, _ := splitFragment(s.dynRef); fragment != "" && fragment == s.dynTarget.hasAnchor {
+			s.dynAnchor = fragment
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/webzak/xtjson"
+)
+
+const orderSchema = `{
+	"$defs": {"price": {"type": "number", "exclusiveMinimum": 0}},
+	"type": "object",
+	"required": ["id", "items"],
+	"properties": {
+		"id": {"type": "string", "pattern": "^o-"},
+		"email": {"type": "string", "format": "email"},
+		"items": {"type": "array", "minItems": 1, "items": {
+			"type": "object",
+			"properties": {"sku": {"type": "string"}, "price": {"$ref": "#/$defs/price"}},
+			"required": ["sku"],
+			"additionalProperties": false
+		}}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	s, err := Compile(parseNode(t, orderSchema))
+	assertNil(t, err)
+	assertEqual(t, true, s.IsValid(parseNode(t, `{"id":"o-1","email":"x","items":[{"sku":"a","price":1.5}]}`)))
+
+	doc := parseNode(t, `{"id":"x-1","items":[{"sku":"a","price":0},{"price":"1","a.b":1}]}`)
+	errs := s.Validate(doc)
+	assertEqual(t, []ValidationError{
+		{"$.id", "/id", "/properties/id/pattern", `string does not match pattern "^o-"`},
+		{"$.items[0].price", "/items/0/price", "/properties/items/items/properties/price/$ref/exclusiveMinimum", "0 is not greater than 0"},
+		{"$.items[1]", "/items/1", "/properties/items/items/required", `required property "sku" is missing`},
+		{"$.items[1].price", "/items/1/price", "/properties/items/items/properties/price/$ref/type", "expected number, got string"},
+		{"$.items[1]['a.b']", "/items/1/a.b", "/properties/items/items/additionalProperties", "false schema does not allow any value"},
+	}, errs)
+	assertEqual(t, `$.id: string does not match pattern "^o-" (/properties/id/pattern)`, errs[0].Error())
+
+	// paths are relative to the validated node
+	errs = s.Validate(parseNode(t, `{"order":{"items":[]}}`).Key("order"))
+	assertEqual(t, []ValidationError{
+		{"$", "", "/required", `required property "id" is missing`},
+		{"$.items", "/items", "/properties/items/minItems", "array length 0 is less than 1"},
+	}, errs)
+
+	assertEqual(t, 1, len(s.Validate(nil)))
+}
+
+func TestValidateFormat(t *testing.T) {
+	schemaNode := parseNode(t, `{"properties":{"email":{"format":"email"},"at":{"format":"date-time"},"x":{"format":"unknown"}}}`)
+	doc := parseNode(t, `{"email":"not email","at":"2024-02-30T10:00:00Z","x":"?"}`)
+	s, err := Compile(schemaNode)
+	assertNil(t, err)
+	assertEqual(t, true, s.IsValid(doc))
+
+	s, err = Compile(schemaNode, &Options{AssertFormat: true})
+	assertNil(t, err)
+	assertEqual(t, []ValidationError{
+		{"$.email", "/email", "/properties/email/format", "string is not valid email"},
+		{"$.at", "/at", "/properties/at/format", "string is not valid date-time"},
+	}, s.Validate(doc))
+}
+
+func TestValidateCombinators(t *testing.T) {
+	s, err := Compile(parseNode(t, `{"anyOf":[{"type":"string"},{"type":"integer"}],"oneOf":[{"minimum":1},{"maximum":10}],"not":{"const":5}}`))
+	assertNil(t, err)
+	assertEqual(t, []ValidationError{
+		{"$", "", "/anyOf", "value does not match any schema"},
+		{"$", "", "/oneOf", "value matches 2 schemas, exactly one expected"},
+	}, s.Validate(xtjson.NewNumber(2.5)))
+	assertEqual(t, []ValidationError{
+		{"$", "", "/oneOf", "value matches 2 schemas, exactly one expected"},
+		{"$", "", "/not", "value must not match schema"},
+	}, s.Validate(xtjson.NewInt(5)))
+	assertEqual(t, true, s.IsValid(xtjson.NewInt(11)))
+}
+
+func TestValidateLoop(t *testing.T) {
+	s, err := Compile(parseNode(t, `{"$defs":{"a":{"$ref":"#/$defs/b"},"b":{"$ref":"#/$defs/a"}},"$ref":"#/$defs/a"}`))
+	assertNil(t, err)
+	assertEqual(t, []ValidationError{{"$", "", "/$ref/$ref/$ref", "infinite reference loop"}}, s.Validate(xtjson.NewNull()))
+}
+
+func TestCompileErrors(t *testing.T) {
+	cases := []struct {
+		schema string
+		err    error
+	}{
+		{`1`, ErrInvalidSchema},
+		{`{"type":"str"}`, ErrInvalidSchema},
+		{`{"type":1}`, ErrInvalidSchema},
+		{`{"minLength":-1}`, ErrInvalidSchema},
+		{`{"maxItems":1.5}`, ErrInvalidSchema},
+		{`{"multipleOf":0}`, ErrInvalidSchema},
+		{`{"pattern":"("}`, ErrInvalidSchema},
+		{`{"patternProperties":{"(":{}}}`, ErrInvalidSchema},
+		{`{"required":[1]}`, ErrInvalidSchema},
+		{`{"enum":1}`, ErrInvalidSchema},
+		{`{"allOf":[]}`, ErrInvalidSchema},
+		{`{"properties":{"a":"b"}}`, ErrInvalidSchema},
+		{`{"$defs":{"a":[]}}`, ErrInvalidSchema},
+		{`{"$id":"http://x/a#f"}`, ErrInvalidSchema},
+		{`{"$anchor":"1a"}`, ErrInvalidSchema},
+		{`{"$ref":1}`, ErrInvalidSchema},
+		{`{"$ref":"#/$defs/missing"}`, ErrUnresolvedRef},
+		{`{"$ref":"#missing"}`, ErrUnresolvedRef},
+		{`{"$ref":"https://json-schema.org/draft/2020-12/schema"}`, ErrUnresolvedRef},
+		{`{"$dynamicRef":"#missing"}`, ErrUnresolvedRef},
+	}
+	for _, c := range cases {
+		_, err := Compile(parseNode(t, c.schema))
+		if !errors.Is(err, c.err) {
+			t.Fatalf("expected error %v for %s, got %v", c.err, c.schema, err)
+		}
+	}
+	_, err := Compile(parseNode(t, `{"properties":{"a/b":{"minLength":"1"}}}`))
+	assertEqual(t, "invalid schema: minLength must be non negative integer at #/properties/a~1b/minLength", err.Error())
+	_, err = Compile(nil)
+	if !errors.Is(err, xtjson.ErrNilNode) {
+		t.Fatal("expected error ErrNilNode")
+	}
+}
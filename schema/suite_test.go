@@ -0,0 +1,109 @@
+package schema
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/webzak/xtjson"
+)
+
+const (
+	skipRemote = "needs remote schema, Compile resolves references within the document only"
+	skipFormat = "format is not implemented"
+)
+
+// suiteSkips lists suite files and groups as file/description which can not pass,
+// an entry matching nothing fails the test
+var suiteSkips = map[string]string{
+	"refRemote": skipRemote,
+	"defs/validate definition against metaschema":                                               skipRemote,
+	"ref/remote ref, containing refs itself":                                                    skipRemote,
+	"dynamicRef/strict-tree schema, guards against misspelled properties":                       skipRemote,
+	"dynamicRef/tests for implementation dynamic anchor and reference link":                     skipRemote,
+	"dynamicRef/$ref and $dynamicAnchor are independent of order - $defs first":                 skipRemote,
+	"dynamicRef/$ref and $dynamicAnchor are independent of order - $ref first":                  skipRemote,
+	"dynamicRef/$ref to $dynamicRef finds detached $dynamicAnchor":                              skipRemote,
+	"vocabulary/schema that uses custom metaschema with with no validation vocabulary":          skipRemote,
+	"optional/cross-draft/refs to historic drafts are processed as historic drafts":             skipRemote,
+	"optional/ecmascript-regex/\\a is not an ECMA 262 control escape":                           skipRemote,
+	"optional/format-assertion/schema that uses custom metaschema with format-assertion: false": skipRemote,
+	"optional/format-assertion/schema that uses custom metaschema with format-assertion: true":  skipRemote,
+	"optional/format/idn-email":                                                                 skipFormat,
+	"optional/format/idn-hostname":                                                              skipFormat,
+}
+
+// TestSuite runs cases of JSON Schema Test Suite (https://github.com/json-schema-org/JSON-Schema-Test-Suite)
+// vendored under fixtures at the commit recorded in its README, cases listed in suiteSkips are skipped.
+// Cases under optional/format are run with format assertion enabled.
+func TestSuite(t *testing.T) {
+	root := "fixtures/draft2020-12"
+	files := map[string]bool{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+		name, _ := filepath.Rel(root, path)
+		opt := &Options{AssertFormat: strings.HasPrefix(name, "optional/format/")}
+		name = strings.TrimSuffix(name, ".json")
+		files[name] = true
+		t.Run(name, func(t *testing.T) {
+			if reason, ok := suiteSkips[name]; ok {
+				t.Skip(reason)
+			}
+			runSuiteFile(t, path, name, opt)
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for skip := range suiteSkips {
+		if !matchesSuiteFile(skip, files) {
+			t.Errorf("skip %q matches no suite file", skip)
+		}
+	}
+}
+
+// matchesSuiteFile reports whether skip names a file or a group within a file
+func matchesSuiteFile(skip string, files map[string]bool) bool {
+	for name := range files {
+		if skip == name || strings.HasPrefix(skip, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func runSuiteFile(t *testing.T, path, name string, opt *Options) {
+	groups, err := xtjson.ParseFile(path)
+	assertNil(t, err)
+	matched := map[string]bool{}
+	for _, group := range groups.Children() {
+		description, _ := group.Key("description").String()
+		if reason, ok := suiteSkips[name+"/"+description]; ok {
+			matched[name+"/"+description] = true
+			t.Logf("skip %s: %s", description, reason)
+			continue
+		}
+		s, err := Compile(group.Key("schema"), opt)
+		if err != nil {
+			t.Errorf("%s: %v", description, err)
+			continue
+		}
+		for _, test := range group.Key("tests").Children() {
+			name, _ := test.Key("description").String()
+			valid, _ := test.Key("valid").Bool()
+			errs := s.Validate(test.Key("data"))
+			if valid != (len(errs) == 0) {
+				t.Errorf("%s / %s: expected valid %v, errors: %v", description, name, valid, errs)
+			}
+		}
+	}
+	for skip := range suiteSkips {
+		if strings.HasPrefix(skip, name+"/") && !matched[skip] {
+			t.Errorf("skip %q matches no group", skip)
+		}
+	}
+}
@@ -0,0 +1,421 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/webzak/xtjson"
+)
+
+// ValidationError describes the instance which failed the schema keyword
+type ValidationError struct {
+	Path            string `json:"path"`            // json path of the instance like $.items[0]
+	Pointer         string `json:"pointer"`         // json pointer of the instance like /items/0
+	KeywordLocation string `json:"keywordLocation"` // json pointer of the keyword by evaluation path like /properties/a/$ref/type
+	Message         string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s (%s)", e.Path, e.Message, e.KeywordLocation)
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// result contains errors and annotations about properties and items evaluated by the schema
+type result struct {
+	errs  []ValidationError
+	props map[string]bool
+	items map[int]bool
+}
+
+func (r *result) valid() bool {
+	return len(r.errs) == 0
+}
+
+func (r *result) prop(key string) {
+	if r.props == nil {
+		r.props = make(map[string]bool)
+	}
+	r.props[key] = true
+}
+
+func (r *result) item(idx int) {
+	if r.items == nil {
+		r.items = make(map[int]bool)
+	}
+	r.items[idx] = true
+}
+
+// merge adds errors of the schema applied to the same instance,
+// annotations are collected only from valid schemas
+func (r *result) merge(sub *result) {
+	r.errs = append(r.errs, sub.errs...)
+	if !sub.valid() {
+		return
+	}
+	for key := range sub.props {
+		r.prop(key)
+	}
+	for idx := range sub.items {
+		r.item(idx)
+	}
+}
+
+type visit struct {
+	schema *schema
+	inst   *xtjson.Node
+}
+
+type validator struct {
+	doc      *xtjson.Node
+	opt      Options
+	visiting map[visit]bool
+}
+
+// Validate returns the errors of doc validation, the document is valid when nothing is returned
+func (s *Schema) Validate(doc *xtjson.Node) []ValidationError {
+	if !doc.Exists() {
+		return []ValidationError{{Message: "instance does not exist"}}
+	}
+	v := &validator{doc: doc, opt: s.opt, visiting: make(map[visit]bool)}
+	return v.validate(s.root, doc, "", nil).errs
+}
+
+// IsValid reports whether doc is valid
+func (s *Schema) IsValid(doc *xtjson.Node) bool {
+	return len(s.Validate(doc)) == 0
+}
+
+func (v *validator) fail(r *result, inst *xtjson.Node, location string, format string, args ...any) {
+	r.errs = append(r.errs, ValidationError{
+		Path:            xtjson.RelativePath(v.doc, inst),
+		Pointer:         xtjson.RelativePointer(v.doc, inst),
+		KeywordLocation: location,
+		Message:         fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) validate(s *schema, inst *xtjson.Node, loc string, scope []*resource) *result {
+	r := &result{}
+	if s.boolean != nil {
+		if !*s.boolean {
+			v.fail(r, inst, loc, "false schema does not allow any value")
+		}
+		return r
+	}
+	key := visit{schema: s, inst: inst}
+	if v.visiting[key] {
+		v.fail(r, inst, loc, "infinite reference loop")
+		return r
+	}
+	v.visiting[key] = true
+	defer delete(v.visiting, key)
+	if len(scope) == 0 || scope[len(scope)-1] != s.res {
+		scope = append(slices.Clip(scope), s.res)
+	}
+
+	if s.refTarget != nil {
+		r.merge(v.validate(s.refTarget, inst, loc+"/$ref", scope))
+	}
+	if s.dynTarget != nil {
+		target := s.dynTarget
+		if s.dynAnchor != "" {
+			for _, res := range scope {
+				if anchored, ok := res.dynamicAnchors[s.dynAnchor]; ok {
+					target = anchored
+					break
+				}
+			}
+		}
+		r.merge(v.validate(target, inst, loc+"/$dynamicRef", scope))
+	}
+	v.validateAssertions(r, s, inst, loc)
+	switch {
+	case inst.IsArray():
+		v.validateArray(r, s, inst, loc, scope)
+	case inst.IsObject():
+		v.validateObject(r, s, inst, loc, scope)
+	}
+	v.validateCombinators(r, s, inst, loc, scope)
+
+	if s.unevaluatedItems != nil && inst.IsArray() {
+		for i, child := range inst.Children() {
+			if !r.items[i] {
+				r.errs = append(r.errs, v.validate(s.unevaluatedItems, child, loc+"/unevaluatedItems", scope).errs...)
+			}
+		}
+		for i := range inst.ChildrenLength() {
+			r.item(i)
+		}
+	}
+	if s.unevaluatedProperties != nil && inst.IsObject() {
+		for _, child := range inst.Children() {
+			if !r.props[child.SelfKey()] {
+				r.errs = append(r.errs, v.validate(s.unevaluatedProperties, child, loc+"/unevaluatedProperties", scope).errs...)
+			}
+		}
+		for _, key := range inst.ChildrenKeys() {
+			r.prop(key)
+		}
+	}
+	return r
+}
+
+func typeMatches(t string, inst *xtjson.Node) bool {
+	switch t {
+	case "null":
+		return inst.IsNull()
+	case "boolean":
+		return inst.IsBool()
+	case "object":
+		return inst.IsObject()
+	case "array":
+		return inst.IsArray()
+	case "string":
+		return inst.IsString()
+	case "number":
+		return inst.IsNumber()
+	case "integer":
+		n, err := inst.Number()
+		return err == nil && n == math.Trunc(n) && !math.IsInf(n, 0)
+	}
+	return false
+}
+
+func typeName(inst *xtjson.Node) string {
+	switch inst.Type() {
+	case xtjson.Null:
+		return "null"
+	case xtjson.Bool:
+		return "boolean"
+	case xtjson.Number:
+		return "number"
+	case xtjson.String:
+		return "string"
+	case xtjson.Array:
+		return "array"
+	}
+	return "object"
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func (v *validator) validateAssertions(r *result, s *schema, inst *xtjson.Node, loc string) {
+	if s.types != nil && !slices.ContainsFunc(s.types, func(t string) bool { return typeMatches(t, inst) }) {
+		v.fail(r, inst, loc+"/type", "expected %s, got %s", strings.Join(s.types, " or "), typeName(inst))
+	}
+	if s.enum != nil && !slices.ContainsFunc(s.enum, func(node *xtjson.Node) bool { return xtjson.Equal(node, inst) }) {
+		v.fail(r, inst, loc+"/enum", "value is not one of enum values")
+	}
+	if s.constant != nil && !xtjson.Equal(s.constant, inst) {
+		v.fail(r, inst, loc+"/const", "value does not match const")
+	}
+	if n, err := inst.Number(); err == nil {
+		if s.multipleOf != nil && !math.IsInf(n, 0) {
+			if q := rational(n); !q.Quo(q, s.multipleOf).IsInt() {
+				v.fail(r, inst, loc+"/multipleOf", "%s is not multiple of %s", formatNumber(n), s.multipleOf.FloatString(10))
+			}
+		}
+		if s.maximum != nil && n > *s.maximum {
+			v.fail(r, inst, loc+"/maximum", "%s is greater than %s", formatNumber(n), formatNumber(*s.maximum))
+		}
+		if s.exclMaximum != nil && n >= *s.exclMaximum {
+			v.fail(r, inst, loc+"/exclusiveMaximum", "%s is not less than %s", formatNumber(n), formatNumber(*s.exclMaximum))
+		}
+		if s.minimum != nil && n < *s.minimum {
+			v.fail(r, inst, loc+"/minimum", "%s is less than %s", formatNumber(n), formatNumber(*s.minimum))
+		}
+		if s.exclMinimum != nil && n <= *s.exclMinimum {
+			v.fail(r, inst, loc+"/exclusiveMinimum", "%s is not greater than %s", formatNumber(n), formatNumber(*s.exclMinimum))
+		}
+	}
+	if str, err := inst.String(); err == nil {
+		length := utf8.RuneCountInString(str)
+		if s.maxLength >= 0 && length > s.maxLength {
+			v.fail(r, inst, loc+"/maxLength", "string length %d is greater than %d", length, s.maxLength)
+		}
+		if s.minLength >= 0 && length < s.minLength {
+			v.fail(r, inst, loc+"/minLength", "string length %d is less than %d", length, s.minLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(str) {
+			v.fail(r, inst, loc+"/pattern", "string does not match pattern %q", s.pattern.String())
+		}
+		if s.format != "" && v.opt.AssertFormat {
+			if check, ok := formats[s.format]; ok && !check(str) {
+				v.fail(r, inst, loc+"/format", "string is not valid %s", s.format)
+			}
+		}
+	}
+}
+
+func (v *validator) validateArray(r *result, s *schema, inst *xtjson.Node, loc string, scope []*resource) {
+	children := inst.Children()
+	if s.maxItems >= 0 && len(children) > s.maxItems {
+		v.fail(r, inst, loc+"/maxItems", "array length %d is greater than %d", len(children), s.maxItems)
+	}
+	if s.minItems >= 0 && len(children) < s.minItems {
+		v.fail(r, inst, loc+"/minItems", "array length %d is less than %d", len(children), s.minItems)
+	}
+	if s.uniqueItems {
+	unique:
+		for i := range children {
+			for j := i + 1; j < len(children); j++ {
+				if xtjson.Equal(children[i], children[j]) {
+					v.fail(r, inst, loc+"/uniqueItems", "items %d and %d are equal", i, j)
+					break unique
+				}
+			}
+		}
+	}
+	for i, sub := range s.prefixItems {
+		if i >= len(children) {
+			break
+		}
+		r.errs = append(r.errs, v.validate(sub, children[i], loc+"/prefixItems/"+strconv.Itoa(i), scope).errs...)
+		r.item(i)
+	}
+	if s.items != nil {
+		for i := len(s.prefixItems); i < len(children); i++ {
+			r.errs = append(r.errs, v.validate(s.items, children[i], loc+"/items", scope).errs...)
+			r.item(i)
+		}
+	}
+	if s.contains == nil {
+		return
+	}
+	matched := 0
+	for i, child := range children {
+		if v.validate(s.contains, child, loc+"/contains", scope).valid() {
+			matched++
+			r.item(i)
+		}
+	}
+	switch {
+	case s.minContains < 0 && matched == 0:
+		v.fail(r, inst, loc+"/contains", "array does not contain matching items")
+	case s.minContains >= 0 && matched < s.minContains:
+		v.fail(r, inst, loc+"/minContains", "array contains %d matching items, at least %d expected", matched, s.minContains)
+	}
+	if s.maxContains >= 0 && matched > s.maxContains {
+		v.fail(r, inst, loc+"/maxContains", "array contains %d matching items, at most %d expected", matched, s.maxContains)
+	}
+}
+
+func (v *validator) validateObject(r *result, s *schema, inst *xtjson.Node, loc string, scope []*resource) {
+	children := inst.Children()
+	if s.maxProperties >= 0 && len(children) > s.maxProperties {
+		v.fail(r, inst, loc+"/maxProperties", "object has %d properties, at most %d expected", len(children), s.maxProperties)
+	}
+	if s.minProperties >= 0 && len(children) < s.minProperties {
+		v.fail(r, inst, loc+"/minProperties", "object has %d properties, at least %d expected", len(children), s.minProperties)
+	}
+	for _, name := range s.required {
+		if !inst.Key(name).Exists() {
+			v.fail(r, inst, loc+"/required", "required property %q is missing", name)
+		}
+	}
+	for _, dep := range s.dependentReq {
+		if !inst.Key(dep.name).Exists() {
+			continue
+		}
+		for _, name := range dep.names {
+			if !inst.Key(name).Exists() {
+				v.fail(r, inst, loc+"/"+dep.keyword+"/"+pointerEscaper.Replace(dep.name),
+					"property %q is required by %q", name, dep.name)
+			}
+		}
+	}
+	for _, p := range s.properties {
+		if child := inst.Key(p.name); child.Exists() {
+			r.errs = append(r.errs, v.validate(p.schema, child, loc+"/properties/"+pointerEscaper.Replace(p.name), scope).errs...)
+			r.prop(p.name)
+		}
+	}
+	for _, child := range children {
+		key := child.SelfKey()
+		matched := slices.ContainsFunc(s.properties, func(p namedSchema) bool { return p.name == key })
+		for _, p := range s.patternProperties {
+			if p.pattern.MatchString(key) {
+				matched = true
+				location := loc + "/patternProperties/" + pointerEscaper.Replace(p.pattern.String())
+				r.errs = append(r.errs, v.validate(p.schema, child, location, scope).errs...)
+				r.prop(key)
+			}
+		}
+		if !matched && s.additionalProperties != nil {
+			r.errs = append(r.errs, v.validate(s.additionalProperties, child, loc+"/additionalProperties", scope).errs...)
+			r.prop(key)
+		}
+	}
+	if s.propertyNames != nil {
+		for _, key := range inst.ChildrenKeys() {
+			if !v.validate(s.propertyNames, xtjson.NewString(key), loc+"/propertyNames", scope).valid() {
+				v.fail(r, inst, loc+"/propertyNames", "property name %q is invalid", key)
+			}
+		}
+	}
+	for _, dep := range s.dependentSchemas {
+		if inst.Key(dep.name).Exists() {
+			r.merge(v.validate(dep.schema, inst, loc+"/dependentSchemas/"+pointerEscaper.Replace(dep.name), scope))
+		}
+	}
+	for _, dep := range s.dependencies {
+		if inst.Key(dep.name).Exists() {
+			r.merge(v.validate(dep.schema, inst, loc+"/dependencies/"+pointerEscaper.Replace(dep.name), scope))
+		}
+	}
+}
+
+func (v *validator) validateCombinators(r *result, s *schema, inst *xtjson.Node, loc string, scope []*resource) {
+	for i, sub := range s.allOf {
+		r.merge(v.validate(sub, inst, loc+"/allOf/"+strconv.Itoa(i), scope))
+	}
+	if s.anyOf != nil {
+		valid := 0
+		for i, sub := range s.anyOf {
+			if res := v.validate(sub, inst, loc+"/anyOf/"+strconv.Itoa(i), scope); res.valid() {
+				valid++
+				r.merge(res)
+			}
+		}
+		if valid == 0 {
+			v.fail(r, inst, loc+"/anyOf", "value does not match any schema")
+		}
+	}
+	if s.oneOf != nil {
+		var matched []*result
+		for i, sub := range s.oneOf {
+			if res := v.validate(sub, inst, loc+"/oneOf/"+strconv.Itoa(i), scope); res.valid() {
+				matched = append(matched, res)
+			}
+		}
+		switch len(matched) {
+		case 0:
+			v.fail(r, inst, loc+"/oneOf", "value does not match any schema")
+		case 1:
+			r.merge(matched[0])
+		default:
+			v.fail(r, inst, loc+"/oneOf", "value matches %d schemas, exactly one expected", len(matched))
+		}
+	}
+	if s.not != nil && v.validate(s.not, inst, loc+"/not", scope).valid() {
+		v.fail(r, inst, loc+"/not", "value must not match schema")
+	}
+	if s.ifSchema == nil {
+		return
+	}
+	if res := v.validate(s.ifSchema, inst, loc+"/if", scope); res.valid() {
+		r.merge(res)
+		if s.thenSchema != nil {
+			r.merge(v.validate(s.thenSchema, inst, loc+"/then", scope))
+		}
+	} else if s.elseSchema != nil {
+		r.merge(v.validate(s.elseSchema, inst, loc+"/else", scope))
+	}
+}
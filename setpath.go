@@ -0,0 +1,270 @@
+package xtjson
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var (
+	ErrBadPath = errors.New("bad path syntax")
+)
+
+// SetPathOptions provides settings for path based setters
+type SetPathOptions struct {
+	// CreateMissing creates missing intermediate objects and arrays,
+	// arrays are padded with null values up to requested index
+	CreateMissing bool
+}
+
+type pathToken struct {
+	key   string
+	idx   int
+	isIdx bool
+}
+
+// parsePath splits path like $.a.b[2].c['d.e'] to tokens
+func parsePath(path string) ([]pathToken, error) {
+	if len(path) == 0 || path[0] != '$' {
+		return nil, ErrBadPath
+	}
+	tokens := []pathToken{}
+	rs := []rune(path[1:])
+	for i := 0; i < len(rs); {
+		switch rs[i] {
+		case '.':
+			j := i + 1
+			for j < len(rs) && rs[j] != '.' && rs[j] != '[' && rs[j] != ']' {
+				j++
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("%w: empty key in %s", ErrBadPath, path)
+			}
+			tokens = append(tokens, pathToken{key: string(rs[i+1 : j])})
+			i = j
+		case '[':
+			if i+1 < len(rs) && rs[i+1] == '\'' {
+				key, next, err := parseQuotedKey(rs, i+2)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %s in %s", ErrBadPath, err.Error(), path)
+				}
+				tokens = append(tokens, pathToken{key: key})
+				i = next
+				continue
+			}
+			j := i + 1
+			for j < len(rs) && rs[j] != ']' {
+				j++
+			}
+			if j == len(rs) {
+				return nil, fmt.Errorf("%w: unclosed bracket in %s", ErrBadPath, path)
+			}
+			idx, err := strconv.Atoi(string(rs[i+1 : j]))
+			if err != nil || idx < 0 {
+				return nil, fmt.Errorf("%w: invalid index in %s", ErrBadPath, path)
+			}
+			tokens = append(tokens, pathToken{idx: idx, isIdx: true})
+			i = j + 1
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrBadPath, path)
+		}
+	}
+	return tokens, nil
+}
+
+// parseQuotedKey reads key in bracket notation starting after the opening quote,
+// backslash escapes the following rune, position after closing bracket is returned
+func parseQuotedKey(rs []rune, i int) (string, int, error) {
+	var key []rune
+	for ; i < len(rs); i++ {
+		switch rs[i] {
+		case '\\':
+			i++
+			if i == len(rs) {
+				return "", 0, errors.New("unclosed quote")
+			}
+			key = append(key, rs[i])
+		case '\'':
+			if i+1 == len(rs) || rs[i+1] != ']' {
+				return "", 0, errors.New("unclosed bracket")
+			}
+			return string(key), i + 2, nil
+		default:
+			key = append(key, rs[i])
+		}
+	}
+	return "", 0, errors.New("unclosed quote")
+}
+
+func (t pathToken) newContainer() *Node {
+	if t.isIdx {
+		return NewArray()
+	}
+	return NewObject()
+}
+
+// child returns existing child of node referenced by token or undef
+func (t pathToken) child(node *Node) *Node {
+	if t.isIdx {
+		return node.Idx(t.idx)
+	}
+	return node.Key(t.key)
+}
+
+// link puts node to the position referenced by token, padding arrays when allowed
+func (t pathToken) link(parent *Node, node *Node, create bool) error {
+	if !t.isIdx {
+		if !parent.IsObject() {
+			return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "set path key")
+		}
+		if !parent.Key(t.key).Exists() && !create {
+			return fmt.Errorf("%w: %s", ErrNodeDoesNotExist, t.key)
+		}
+		return parent.Set(t.key, node)
+	}
+	if !parent.IsArray() {
+		return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "set path index")
+	}
+	if t.idx < len(parent.children) {
+		return parent.ReplaceIdx(t.idx, node)
+	}
+	if !create {
+		return fmt.Errorf("%w %d", ErrInvalidIndex, t.idx)
+	}
+	for len(parent.children) < t.idx {
+		if err := parent.AppendNull(); err != nil {
+			return err
+		}
+	}
+	return parent.Append(node)
+}
+
+// lookupPath returns the parent of the node referenced by path and the last path token,
+// missing intermediate nodes are created when allowed
+func (n *Node) lookupPath(path string, create bool) (*Node, pathToken, error) {
+	var last pathToken
+	tokens, err := parsePath(path)
+	if err != nil {
+		return nil, last, err
+	}
+	if len(tokens) == 0 {
+		return nil, last, fmt.Errorf("%w: root can not be set", ErrInvalidNodeForOperation)
+	}
+	if create {
+		if err = validatePath(n, tokens); err != nil {
+			return nil, last, err
+		}
+	}
+	node := n
+	for i, token := range tokens[:len(tokens)-1] {
+		next := token.child(node)
+		if !next.Exists() {
+			if !create {
+				return nil, last, fmt.Errorf("%w: %s", ErrNodeDoesNotExist, path)
+			}
+			next = tokens[i+1].newContainer()
+			if err = token.link(node, next, true); err != nil {
+				return nil, last, err
+			}
+		}
+		node = next
+	}
+	return node, tokens[len(tokens)-1], nil
+}
+
+// validatePath checks that nodes missing in path can be created under the existing ones,
+// so the tree is left untouched when the path can not be set
+func validatePath(node *Node, tokens []pathToken) error {
+	for _, token := range tokens {
+		if token.isIdx && !node.IsArray() {
+			return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "set path index")
+		}
+		if !token.isIdx && !node.IsObject() {
+			return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "set path key")
+		}
+		if node = token.child(node); !node.Exists() {
+			return nil
+		}
+	}
+	return nil
+}
+
+// SetPath sets node to position referenced by path like $.a.b[2].c
+// the existing node is replaced, missing nodes are created only if CreateMissing option is set
+func (n *Node) SetPath(path string, node *Node, opt *SetPathOptions) error {
+	if n == nil || n == undef {
+		return ErrNilNode
+	}
+	if node.parent != nil {
+		return fmt.Errorf("%w %s", ErrNodeHasParent, "attemt to set path node linked to another parent")
+	}
+	create := opt != nil && opt.CreateMissing
+	parent, token, err := n.lookupPath(path, create)
+	if err != nil {
+		return err
+	}
+	return token.link(parent, node, create)
+}
+
+// SetPathString sets string node to position referenced by path
+func (n *Node) SetPathString(path string, value string, opt *SetPathOptions) error {
+	return n.SetPath(path, NewString(value), opt)
+}
+
+// SetPathBool sets bool node to position referenced by path
+func (n *Node) SetPathBool(path string, value bool, opt *SetPathOptions) error {
+	return n.SetPath(path, NewBool(value), opt)
+}
+
+// SetPathNumber sets numeric node to position referenced by path
+func (n *Node) SetPathNumber(path string, value float64, opt *SetPathOptions) error {
+	return n.SetPath(path, NewNumber(value), opt)
+}
+
+// SetPathInt sets numeric integer node to position referenced by path
+func (n *Node) SetPathInt(path string, value int, opt *SetPathOptions) error {
+	return n.SetPath(path, NewInt(value), opt)
+}
+
+// SetPathNull sets null node to position referenced by path
+func (n *Node) SetPathNull(path string, opt *SetPathOptions) error {
+	return n.SetPath(path, NewNull(), opt)
+}
+
+// GetOrCreate returns the node referenced by path, if the one does not exist
+// the provided node is set to this position creating all missing intermediate nodes
+func (n *Node) GetOrCreate(path string, node *Node) (*Node, error) {
+	if n == nil || n == undef {
+		return nil, ErrNilNode
+	}
+	if found := n.Path(path); found.Exists() {
+		return found, nil
+	}
+	if node.parent != nil {
+		return nil, fmt.Errorf("%w %s", ErrNodeHasParent, "attemt to create path node linked to another parent")
+	}
+	parent, token, err := n.lookupPath(path, true)
+	if err != nil {
+		return nil, err
+	}
+	if err = token.link(parent, node, true); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// RemovePath unlinks the node referenced by path from its parent
+func (n *Node) RemovePath(path string) error {
+	if n == nil || n == undef {
+		return ErrNilNode
+	}
+	parent, token, err := n.lookupPath(path, false)
+	if err != nil {
+		return err
+	}
+	node := token.child(parent)
+	if !node.Exists() {
+		return fmt.Errorf("%w: %s", ErrNodeDoesNotExist, path)
+	}
+	return node.Remove()
+}
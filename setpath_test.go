@@ -0,0 +1,120 @@
+package xtjson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tokens, err := parsePath("$.a.b[2].c")
+	assertNil(t, err)
+	assertEqual(t, []pathToken{{key: "a"}, {key: "b"}, {idx: 2, isIdx: true}, {key: "c"}}, tokens)
+
+	tokens, err = parsePath("$")
+	assertNil(t, err)
+	assertEqual(t, 0, len(tokens))
+
+	for _, path := range []string{"", "a", "$a", "$..a", "$[x]", "$[-1]", "$[1", "$.a]"} {
+		_, err = parsePath(path)
+		if !errors.Is(err, ErrBadPath) {
+			t.Fatalf("expected error ErrBadPath for %s", path)
+		}
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	root := NewObject()
+	err := root.SetPath("$.a.b[2].c", NewInt(1), &SetPathOptions{CreateMissing: true})
+	assertNil(t, err)
+	assertEqual(t, `{"a":{"b":[null,null,{"c":1}]}}`, root.Stringify())
+	assertEqual(t, "$.a.b[2].c", root.Path("$.a.b[2].c").SelfPath())
+
+	err = root.SetPath("$.a.b[0]", NewString("x"), nil)
+	assertNil(t, err)
+	assertEqual(t, `{"a":{"b":["x",null,{"c":1}]}}`, root.Stringify())
+
+	err = root.SetPath("$.a.b[2].c", NewBool(true), nil)
+	assertNil(t, err)
+	assertEqual(t, `{"a":{"b":["x",null,{"c":true}]}}`, root.Stringify())
+
+	err = root.SetPath("$.a.x.y", NewNull(), nil)
+	if !errors.Is(err, ErrNodeDoesNotExist) {
+		t.Fatal("expected error ErrNodeDoesNotExist")
+	}
+	err = root.SetPath("$.a.d", NewNull(), nil)
+	if !errors.Is(err, ErrNodeDoesNotExist) {
+		t.Fatal("expected error ErrNodeDoesNotExist")
+	}
+	err = root.SetPath("$.a.b[5]", NewNull(), nil)
+	if !errors.Is(err, ErrInvalidIndex) {
+		t.Fatal("expected error ErrInvalidIndex")
+	}
+	err = root.SetPath("$.a.b.c", NewNull(), &SetPathOptions{CreateMissing: true})
+	if !errors.Is(err, ErrInvalidNodeForOperation) {
+		t.Fatal("expected error ErrInvalidNodeForOperation")
+	}
+	err = root.SetPath("$", NewNull(), nil)
+	if !errors.Is(err, ErrInvalidNodeForOperation) {
+		t.Fatal("expected error ErrInvalidNodeForOperation")
+	}
+	err = root.SetPath("$.a.z", root.Key("a").Key("b"), &SetPathOptions{CreateMissing: true})
+	if !errors.Is(err, ErrNodeHasParent) {
+		t.Fatal("expected error ErrNodeHasParent")
+	}
+
+	root = NewArray()
+	err = root.SetPath("$[1][1]", NewInt(5), &SetPathOptions{CreateMissing: true})
+	assertNil(t, err)
+	assertEqual(t, `[null,[null,5]]`, root.Stringify())
+}
+
+func TestSetPathByType(t *testing.T) {
+	opt := &SetPathOptions{CreateMissing: true}
+	root := NewObject()
+	assertNil(t, root.SetPathString("$.s.v", "str", opt))
+	assertNil(t, root.SetPathBool("$.b[0]", true, opt))
+	assertNil(t, root.SetPathNumber("$.n.v", 1.5, opt))
+	assertNil(t, root.SetPathInt("$.i.v", 3, opt))
+	assertNil(t, root.SetPathNull("$.z", opt))
+	assertEqual(t, `{"s":{"v":"str"},"b":[true],"n":{"v":1.5},"i":{"v":3},"z":null}`, root.Stringify())
+}
+
+func TestGetOrCreate(t *testing.T) {
+	root, err := ParseString(`{"a":{"b":1}}`)
+	assertParsed(t, root, err)
+
+	node, err := root.GetOrCreate("$.a.b", NewInt(2))
+	assertNil(t, err)
+	assertInt(t, 1, node)
+
+	node, err = root.GetOrCreate("$.a.c.items", NewArray())
+	assertNil(t, err)
+	assertNil(t, node.AppendInt(10))
+	assertEqual(t, `{"a":{"b":1,"c":{"items":[10]}}}`, root.Stringify())
+
+	_, err = root.GetOrCreate("$.a.b.c", NewNull())
+	if !errors.Is(err, ErrInvalidNodeForOperation) {
+		t.Fatal("expected error ErrInvalidNodeForOperation")
+	}
+}
+
+func TestSetPathFailureKeepsTree(t *testing.T) {
+	const src = `{"a":{"b":1,"l":[0]}}`
+	root, err := ParseString(src)
+	assertParsed(t, root, err)
+	opt := &SetPathOptions{CreateMissing: true}
+	for _, path := range []string{"$.a.b.c", "$.a.b[0]", "$.a.l.k", "$.a[0].x", "$['a'].l[0].k[2]"} {
+		err = root.SetPath(path, NewNull(), opt)
+		if !errors.Is(err, ErrInvalidNodeForOperation) {
+			t.Fatalf("expected error ErrInvalidNodeForOperation for %s", path)
+		}
+		assertEqual(t, src, root.Stringify())
+	}
+
+	// the node linked to another parent is detected before missing nodes are created
+	_, err = root.GetOrCreate("$.x.y[2].z", root.Key("a").Key("b"))
+	if !errors.Is(err, ErrNodeHasParent) {
+		t.Fatal("expected error ErrNodeHasParent")
+	}
+	assertEqual(t, src, root.Stringify())
+}
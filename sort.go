@@ -0,0 +1,128 @@
+package xtjson
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// SortOptions defines array sorting by value
+type SortOptions struct {
+	Descending bool `json:"descending,omitempty"`
+	Stable     bool `json:"stable,omitempty"` // keep the order of equal elements
+}
+
+// SortArray sorts array node children with less function, the sort is stable
+func (n *Node) SortArray(less func(a, b *Node) bool) error {
+	if !n.IsArray() {
+		return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "sort array")
+	}
+	before := n.observedOrder()
+	sort.SliceStable(n.children, func(i, j int) bool {
+		return less(n.children[i], n.children[j])
+	})
+	n.reordered(before)
+	return nil
+}
+
+// SortArrayBy sorts array node children by values found by path relative to each child,
+// "$" sorts by children themselves. Values are ordered by type first: missing, null, bool,
+// number, string, array, object and then by value.
+func (n *Node) SortArrayBy(path string, opt *SortOptions) error {
+	if !n.IsArray() {
+		return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "sort array")
+	}
+	if opt == nil {
+		opt = &SortOptions{}
+	}
+	compare := func(a, b *Node) int {
+		c := compareNodes(a.Path(path), b.Path(path))
+		if opt.Descending {
+			return -c
+		}
+		return c
+	}
+	before := n.observedOrder()
+	if opt.Stable {
+		slices.SortStableFunc(n.children, compare)
+	} else {
+		slices.SortFunc(n.children, compare)
+	}
+	n.reordered(before)
+	return nil
+}
+
+// reordered renumbers array children after sorting
+func (n *Node) reordered(before []*Node) {
+	if len(n.children) == 0 {
+		return
+	}
+	n.renumber(0, len(n.children))
+	notify(mutation{op: opReorder, parent: n, before: before})
+}
+
+// Dedupe removes array node children deeply equal to preceding ones
+func (n *Node) Dedupe() error {
+	return n.DedupeBy("$")
+}
+
+// DedupeBy removes array node children which values found by path relative to the child
+// are deeply equal to preceding ones. Children without value on path are kept.
+func (n *Node) DedupeBy(path string) error {
+	if !n.IsArray() {
+		return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "dedupe")
+	}
+	values := make([]*Node, len(n.children))
+	order := make([]int, 0, len(n.children))
+	for i, child := range n.children {
+		values[i] = child.Path(path)
+		if values[i].Exists() {
+			order = append(order, i)
+		}
+	}
+	// equal values are adjacent after stable sort, the first of them occurs first in array
+	slices.SortStableFunc(order, func(a, b int) int {
+		return compareNodes(values[a], values[b])
+	})
+	var duplicates []int
+	for i := 1; i < len(order); i++ {
+		if equalNodes(values[order[i-1]], values[order[i]]) {
+			duplicates = append(duplicates, order[i])
+			order[i] = order[i-1]
+		}
+	}
+	slices.Sort(duplicates)
+	for _, idx := range slices.Backward(duplicates) {
+		if err := n.RemoveIdx(idx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SortTreeArrays sorts children of current and all nested array nodes with less function
+func (n *Node) SortTreeArrays(less func(a, b *Node) bool) error {
+	return n.applyTree(Array, func(node *Node) error {
+		return node.SortArray(less)
+	})
+}
+
+// SortTreeArraysBy sorts children of current and all nested array nodes by path values
+func (n *Node) SortTreeArraysBy(path string, opt *SortOptions) error {
+	return n.applyTree(Array, func(node *Node) error {
+		return node.SortArrayBy(path, opt)
+	})
+}
+
+// DedupeTree removes duplicates from current and all nested array nodes,
+// nested arrays are deduplicated before their parents are compared
+func (n *Node) DedupeTree() error {
+	return n.applyTree(Array, (*Node).Dedupe)
+}
+
+// DedupeTreeBy removes duplicates by path values from current and all nested array nodes
+func (n *Node) DedupeTreeBy(path string) error {
+	return n.applyTree(Array, func(node *Node) error {
+		return node.DedupeBy(path)
+	})
+}
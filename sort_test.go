@@ -0,0 +1,82 @@
+package xtjson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSortArray(t *testing.T) {
+	root, err := ParseString(`[{"n":3},{"n":1},{"n":2,"x":true},{"n":1,"x":false}]`)
+	assertParsed(t, root, err)
+	assertNil(t, root.SortArray(func(a, b *Node) bool {
+		return a.Key("n").value.(float64) < b.Key("n").value.(float64)
+	}))
+	assertEqual(t, `[{"n":1},{"n":1,"x":false},{"n":2,"x":true},{"n":3}]`, root.Stringify())
+	assertChildren(t, root)
+
+	err = NewObject().SortArray(nil)
+	if !errors.Is(err, ErrInvalidNodeForOperation) {
+		t.Fatal("expected error ErrInvalidNodeForOperation")
+	}
+}
+
+func TestSortArrayBy(t *testing.T) {
+	root, err := ParseString(`[{"v":"b"},{"v":[1]},{"v":2},{},{"v":{"a":1}},{"v":null},{"v":true},{"v":"a"},{"v":false},{"v":-1}]`)
+	assertParsed(t, root, err)
+	assertNil(t, root.SortArrayBy("$.v", nil))
+	assertEqual(t, `[{},{"v":null},{"v":false},{"v":true},{"v":-1},{"v":2},{"v":"a"},{"v":"b"},{"v":[1]},{"v":{"a":1}}]`, root.Stringify())
+	assertChildren(t, root)
+
+	root, err = ParseString(`[{"k":1,"i":0},{"k":2,"i":1},{"k":1,"i":2},{"k":2,"i":3}]`)
+	assertParsed(t, root, err)
+	assertNil(t, root.SortArrayBy("$.k", &SortOptions{Descending: true, Stable: true}))
+	assertEqual(t, `[{"k":2,"i":1},{"k":2,"i":3},{"k":1,"i":0},{"k":1,"i":2}]`, root.Stringify())
+
+	root, err = ParseString(`[3,"x",1,null]`)
+	assertParsed(t, root, err)
+	assertNil(t, root.SortArrayBy("$", nil))
+	assertEqual(t, `[null,1,3,"x"]`, root.Stringify())
+}
+
+func TestDedupe(t *testing.T) {
+	root, err := ParseString(`[1,{"a":1,"b":[2]},"1",1,{"b":[2],"a":1},null,[1],null,1.0,[1,2]]`)
+	assertParsed(t, root, err)
+	removed := root.Idx(3)
+	assertNil(t, root.Dedupe())
+	assertEqual(t, `[1,{"a":1,"b":[2]},"1",null,[1],[1,2]]`, root.Stringify())
+	assertChildren(t, root)
+	assertEqual(t, false, removed.Parent().Exists())
+
+	err = NewString("x").Dedupe()
+	if !errors.Is(err, ErrInvalidNodeForOperation) {
+		t.Fatal("expected error ErrInvalidNodeForOperation")
+	}
+}
+
+func TestDedupeBy(t *testing.T) {
+	root, err := ParseString(`[{"id":1,"v":"a"},{"v":"x"},{"id":2,"v":"b"},{"id":1,"v":"c"},{"v":"y"},{"id":{"k":1},"v":"d"},{"id":{"k":1},"v":"e"}]`)
+	assertParsed(t, root, err)
+	assertNil(t, root.DedupeBy("$.id"))
+	assertEqual(t, `[{"id":1,"v":"a"},{"v":"x"},{"id":2,"v":"b"},{"v":"y"},{"id":{"k":1},"v":"d"}]`, root.Stringify())
+	assertChildren(t, root)
+}
+
+func TestTreeArrays(t *testing.T) {
+	root, err := ParseString(`{"a":[3,1,[2,1]],"b":{"c":[[1,1],[1],[1]]}}`)
+	assertParsed(t, root, err)
+	assertNil(t, root.DedupeTree())
+	assertEqual(t, `{"a":[3,1,[2,1]],"b":{"c":[[1]]}}`, root.Stringify())
+
+	assertNil(t, root.SortTreeArraysBy("$", nil))
+	assertEqual(t, `{"a":[1,3,[1,2]],"b":{"c":[[1]]}}`, root.Stringify())
+
+	assertNil(t, root.SortTreeArrays(func(a, b *Node) bool {
+		return compareNodes(a, b) > 0
+	}))
+	assertEqual(t, `{"a":[[2,1],3,1],"b":{"c":[[1]]}}`, root.Stringify())
+
+	root, err = ParseString(`[{"id":1,"tags":[{"id":1},{"id":1}]},{"id":1}]`)
+	assertParsed(t, root, err)
+	assertNil(t, root.DedupeTreeBy("$.id"))
+	assertEqual(t, `[{"id":1,"tags":[{"id":1}]}]`, root.Stringify())
+}
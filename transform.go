@@ -0,0 +1,1971 @@
+package xtjson
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrBadTransform = errors.New("bad transform syntax")
+	ErrTransform    = errors.New("transform error")
+)
+
+// TransformError describes the error of transform expression at position, it wraps
+// ErrBadTransform for syntax errors or Err like ErrTransform for evaluation errors
+type TransformError struct {
+	Pos int
+	Msg string
+	Err error
+}
+
+func (e *TransformError) Error() string {
+	return fmt.Sprintf("%s: %s at position %d", e.Unwrap(), e.Msg, e.Pos)
+}
+
+func (e *TransformError) Unwrap() error {
+	if e.Err != nil {
+		return e.Err
+	}
+	return ErrBadTransform
+}
+
+// CompiledTransform is the parsed transform expression,
+// it is immutable and safe for concurrent use by multiple goroutines
+type CompiledTransform struct {
+	expr string
+	root tfExpr
+}
+
+// CompileTransform parses jq-like transform expression.
+//
+// The language supports identity ., fields .a."b", indexes .[0], slices .[1:3],
+// iteration .[], recursion .., pipes |, commas, array [...] and object {a, b: .c, (.k): .v}
+// construction, string interpolation "\(.a)", arithmetic, comparisons, and, or,
+// alternative //, if-then-elif-else-end, variables . as $x | ..., optional ? and
+// builtin functions like map, select, length, keys, has, add, sort_by, group_by
+// and query which applies JSONPath expression using Query syntax
+func CompileTransform(expr string) (*CompiledTransform, error) {
+	p, err := newTfParser(expr, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	root, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tkEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+	return &CompiledTransform{expr: expr, root: root}, nil
+}
+
+// Run applies transformation to node and returns all produced results as new trees
+func (ct *CompiledTransform) Run(node *Node) (Nodes, error) {
+	if node == nil || node == undef {
+		return nil, ErrNilNode
+	}
+	outs, err := ct.root.eval(nil, node)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(Nodes, len(outs))
+	for i, out := range outs {
+		ret[i] = detach(out)
+	}
+	return ret, nil
+}
+
+// String returns the source expression of transformation
+func (ct *CompiledTransform) String() string {
+	return ct.expr
+}
+
+// Transform applies jq-like expression to node and returns the new tree,
+// the expression must produce exactly one result, use TransformAll otherwise
+func Transform(node *Node, expr string) (*Node, error) {
+	outs, err := TransformAll(node, expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(outs) != 1 {
+		return nil, fmt.Errorf("%w: expression produced %d results", ErrTransform, len(outs))
+	}
+	return outs[0], nil
+}
+
+// TransformAll applies jq-like expression to node and returns all results as new trees
+func TransformAll(node *Node, expr string) (Nodes, error) {
+	ct, err := CompileTransform(expr)
+	if err != nil {
+		return nil, err
+	}
+	return ct.Run(node)
+}
+
+// detach returns the copy of node which is not linked to any tree
+func detach(node *Node) *Node {
+	ret := node.Copy()
+	ret.idx = 0
+	ret.key = ""
+	return ret
+}
+
+type tfTokenKind int
+
+const (
+	tkEOF tfTokenKind = iota
+	tkPunct
+	tkField
+	tkIdent
+	tkVar
+	tkNumber
+	tkString
+)
+
+type tfToken struct {
+	kind  tfTokenKind
+	text  string
+	pos   int
+	parts []tfStrPart
+}
+
+func (t tfToken) String() string {
+	switch t.kind {
+	case tkEOF:
+		return "end of expression"
+	case tkField:
+		return "field ." + t.text
+	case tkVar:
+		return "variable $" + t.text
+	case tkString:
+		return "string"
+	}
+	return "'" + t.text + "'"
+}
+
+// tfStrPart is either literal part of string or interpolated expression source
+type tfStrPart struct {
+	lit    string
+	expr   string
+	pos    int
+	isExpr bool
+}
+
+type tfLexer struct {
+	src    []rune
+	pos    int
+	offset int
+}
+
+var tfPuncts = []string{"..", "//", "==", "!=", "<=", ">=", ".", "[", "]", "{", "}", "(", ")", "|", ",", ":", ";", "?", "<", ">", "+", "-", "*", "/", "%"}
+
+func isIdentStart(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_'
+}
+
+func isIdentChar(r rune) bool {
+	return isIdentStart(r) || isDigit(r)
+}
+
+func (l *tfLexer) errorf(pos int, format string, args ...any) error {
+	return &TransformError{Pos: l.offset + pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (l *tfLexer) peekAt(i int) rune {
+	if i >= len(l.src) {
+		return 0
+	}
+	return l.src[i]
+}
+
+func (l *tfLexer) next() (tfToken, error) {
+	for l.pos < len(l.src) && strings.ContainsRune(" \t\n\r", l.src[l.pos]) {
+		l.pos++
+	}
+	start := l.pos
+	tok := tfToken{pos: l.offset + start}
+	if l.pos >= len(l.src) {
+		return tok, nil
+	}
+	r := l.src[l.pos]
+	switch {
+	case r == '.' && isIdentStart(l.peekAt(l.pos+1)):
+		l.pos++
+		for l.pos < len(l.src) && isIdentChar(l.src[l.pos]) {
+			l.pos++
+		}
+		tok.kind, tok.text = tkField, string(l.src[start+1:l.pos])
+		return tok, nil
+	case r == '.' && l.peekAt(l.pos+1) == '"':
+		l.pos++
+		parts, err := l.scanString()
+		if err != nil {
+			return tok, err
+		}
+		if len(parts) > 0 && parts[0].isExpr || len(parts) > 1 {
+			return tok, l.errorf(start, "interpolation is not allowed in field name")
+		}
+		tok.kind = tkField
+		if len(parts) > 0 {
+			tok.text = parts[0].lit
+		}
+		return tok, nil
+	case r == '$' && isIdentStart(l.peekAt(l.pos+1)):
+		l.pos++
+		for l.pos < len(l.src) && isIdentChar(l.src[l.pos]) {
+			l.pos++
+		}
+		tok.kind, tok.text = tkVar, string(l.src[start+1:l.pos])
+		return tok, nil
+	case isIdentStart(r):
+		for l.pos < len(l.src) && isIdentChar(l.src[l.pos]) {
+			l.pos++
+		}
+		tok.kind, tok.text = tkIdent, string(l.src[start:l.pos])
+		return tok, nil
+	case isDigit(r):
+		for isDigit(l.peekAt(l.pos)) {
+			l.pos++
+		}
+		if l.peekAt(l.pos) == '.' && isDigit(l.peekAt(l.pos+1)) {
+			l.pos++
+			for isDigit(l.peekAt(l.pos)) {
+				l.pos++
+			}
+		}
+		if e := l.peekAt(l.pos); e == 'e' || e == 'E' {
+			l.pos++
+			if s := l.peekAt(l.pos); s == '+' || s == '-' {
+				l.pos++
+			}
+			if !isDigit(l.peekAt(l.pos)) {
+				return tok, l.errorf(l.pos, "invalid number")
+			}
+			for isDigit(l.peekAt(l.pos)) {
+				l.pos++
+			}
+		}
+		tok.kind, tok.text = tkNumber, string(l.src[start:l.pos])
+		return tok, nil
+	case r == '"':
+		parts, err := l.scanString()
+		if err != nil {
+			return tok, err
+		}
+		tok.kind, tok.parts = tkString, parts
+		return tok, nil
+	}
+	for _, p := range tfPuncts {
+		if strings.HasPrefix(string(l.src[l.pos:min(l.pos+2, len(l.src))]), p) {
+			l.pos += len(p)
+			tok.kind, tok.text = tkPunct, p
+			return tok, nil
+		}
+	}
+	return tok, l.errorf(start, "unexpected character %q", r)
+}
+
+// scanString reads double quoted string starting at current position,
+// interpolated expressions \(...) are returned as source parts
+func (l *tfLexer) scanString() ([]tfStrPart, error) {
+	start := l.pos
+	l.pos++
+	var parts []tfStrPart
+	var sb strings.Builder
+	flush := func() {
+		if sb.Len() > 0 {
+			parts = append(parts, tfStrPart{lit: sb.String()})
+			sb.Reset()
+		}
+	}
+	for {
+		if l.pos >= len(l.src) {
+			return nil, l.errorf(start, "unterminated string")
+		}
+		r := l.src[l.pos]
+		l.pos++
+		if r == '"' {
+			flush()
+			return parts, nil
+		}
+		if r != '\\' {
+			sb.WriteRune(r)
+			continue
+		}
+		esc := l.peekAt(l.pos)
+		l.pos++
+		switch esc {
+		case '"', '\\', '/':
+			sb.WriteRune(esc)
+		case 'b':
+			sb.WriteRune('\b')
+		case 'f':
+			sb.WriteRune('\f')
+		case 'n':
+			sb.WriteRune('\n')
+		case 'r':
+			sb.WriteRune('\r')
+		case 't':
+			sb.WriteRune('\t')
+		case 'u':
+			if l.pos+4 > len(l.src) {
+				return nil, l.errorf(l.pos-2, "invalid unicode escape")
+			}
+			v, err := strconv.ParseUint(string(l.src[l.pos:l.pos+4]), 16, 32)
+			if err != nil {
+				return nil, l.errorf(l.pos-2, "invalid unicode escape")
+			}
+			sb.WriteRune(rune(v))
+			l.pos += 4
+		case '(':
+			flush()
+			exprStart := l.pos
+			if err := l.skipInterpolation(); err != nil {
+				return nil, err
+			}
+			parts = append(parts, tfStrPart{
+				expr:   string(l.src[exprStart : l.pos-1]),
+				pos:    l.offset + exprStart,
+				isExpr: true,
+			})
+		default:
+			return nil, l.errorf(l.pos-2, "invalid escape sequence")
+		}
+	}
+}
+
+// skipInterpolation moves position after the parenthesis closing interpolation
+func (l *tfLexer) skipInterpolation() error {
+	start := l.pos
+	depth := 1
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				l.pos++
+				return nil
+			}
+		case '"':
+			if _, err := l.scanString(); err != nil {
+				return err
+			}
+			continue
+		}
+		l.pos++
+	}
+	return l.errorf(start-2, "unterminated interpolation")
+}
+
+type tfParser struct {
+	lex  *tfLexer
+	tok  tfToken
+	vars []string // names of variables in scope
+}
+
+func newTfParser(src string, offset int, vars []string) (*tfParser, error) {
+	p := &tfParser{lex: &tfLexer{src: []rune(src), offset: offset}, vars: slices.Clone(vars)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *tfParser) errorf(format string, args ...any) error {
+	return &TransformError{Pos: p.tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *tfParser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// checkVar reports variable which is not bound by enclosing . as $name | ...
+func (p *tfParser) checkVar(tok tfToken) error {
+	if !slices.Contains(p.vars, tok.text) {
+		return &TransformError{Pos: tok.pos, Msg: fmt.Sprintf("$%s is not defined", tok.text)}
+	}
+	return nil
+}
+
+func (p *tfParser) is(kind tfTokenKind, text string) bool {
+	return p.tok.kind == kind && p.tok.text == text
+}
+
+func (p *tfParser) expect(text string) error {
+	if !p.is(tkPunct, text) {
+		return p.errorf("expected '%s' but found %s", text, p.tok)
+	}
+	return p.advance()
+}
+
+func (p *tfParser) expectKeyword(text string) error {
+	if !p.is(tkIdent, text) {
+		return p.errorf("expected '%s' but found %s", text, p.tok)
+	}
+	return p.advance()
+}
+
+func (p *tfParser) parsePipe() (tfExpr, error) {
+	left, err := p.parseComma()
+	if err != nil {
+		return nil, err
+	}
+	if p.is(tkIdent, "as") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tkVar {
+			return nil, p.errorf("expected variable but found %s", p.tok)
+		}
+		name := p.tok.text
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		if err = p.expect("|"); err != nil {
+			return nil, err
+		}
+		p.vars = append(p.vars, name)
+		body, err := p.parsePipe()
+		p.vars = p.vars[:len(p.vars)-1]
+		if err != nil {
+			return nil, err
+		}
+		return &tfBind{source: left, name: name, body: body}, nil
+	}
+	if p.is(tkPunct, "|") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return &tfPipe{left, right}, nil
+	}
+	return left, nil
+}
+
+func (p *tfParser) parseComma() (tfExpr, error) {
+	left, err := p.parseAlt()
+	if err != nil {
+		return nil, err
+	}
+	for p.is(tkPunct, ",") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAlt()
+		if err != nil {
+			return nil, err
+		}
+		left = &tfComma{left, right}
+	}
+	return left, nil
+}
+
+func (p *tfParser) parseAlt() (tfExpr, error) {
+	left, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.is(tkPunct, "//") {
+		return left, nil
+	}
+	if err = p.advance(); err != nil {
+		return nil, err
+	}
+	right, err := p.parseAlt()
+	if err != nil {
+		return nil, err
+	}
+	return &tfAlt{left, right}, nil
+}
+
+func (p *tfParser) parseOr() (tfExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.is(tkIdent, "or") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &tfLogic{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *tfParser) parseAnd() (tfExpr, error) {
+	left, err := p.parseCompare()
+	if err != nil {
+		return nil, err
+	}
+	for p.is(tkIdent, "and") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseCompare()
+		if err != nil {
+			return nil, err
+		}
+		left = &tfLogic{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *tfParser) parseCompare() (tfExpr, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tkPunct || !slices.Contains([]string{"==", "!=", "<", "<=", ">", ">="}, p.tok.text) {
+		return left, nil
+	}
+	op, pos := p.tok.text, p.tok.pos
+	if err = p.advance(); err != nil {
+		return nil, err
+	}
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	return &tfBinary{op: op, pos: pos, left: left, right: right}, nil
+}
+
+func (p *tfParser) parseAdditive() (tfExpr, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.is(tkPunct, "+") || p.is(tkPunct, "-") {
+		op, pos := p.tok.text, p.tok.pos
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &tfBinary{op: op, pos: pos, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *tfParser) parseMultiplicative() (tfExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.is(tkPunct, "*") || p.is(tkPunct, "/") || p.is(tkPunct, "%") {
+		op, pos := p.tok.text, p.tok.pos
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &tfBinary{op: op, pos: pos, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *tfParser) parseUnary() (tfExpr, error) {
+	if !p.is(tkPunct, "-") {
+		return p.parsePostfix()
+	}
+	pos := p.tok.pos
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	expr, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &tfBinary{op: "-", pos: pos, left: &tfLiteral{NewInt(0)}, right: expr}, nil
+}
+
+func (p *tfParser) parsePostfix() (tfExpr, error) {
+	expr, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.tok.kind == tkField:
+			expr = &tfIndex{target: expr, key: &tfLiteral{NewString(p.tok.text)}}
+			err = p.advance()
+		case p.is(tkPunct, "."):
+			// .[...] suffix like .a.[0]
+			if err = p.advance(); err != nil {
+				return nil, err
+			}
+			if !p.is(tkPunct, "[") {
+				return nil, p.errorf("expected '[' but found %s", p.tok)
+			}
+			expr, err = p.parseBracketSuffix(expr)
+		case p.is(tkPunct, "["):
+			expr, err = p.parseBracketSuffix(expr)
+		case p.is(tkPunct, "?"):
+			expr = &tfTry{expr}
+			err = p.advance()
+		default:
+			return expr, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *tfParser) parseBracketSuffix(target tfExpr) (tfExpr, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.is(tkPunct, "]") {
+		return &tfIterate{target}, p.advance()
+	}
+	var from, to tfExpr
+	var err error
+	if !p.is(tkPunct, ":") {
+		if from, err = p.parsePipe(); err != nil {
+			return nil, err
+		}
+		if p.is(tkPunct, "]") {
+			return &tfIndex{target: target, key: from}, p.advance()
+		}
+	}
+	if err = p.expect(":"); err != nil {
+		return nil, err
+	}
+	if !p.is(tkPunct, "]") {
+		if to, err = p.parsePipe(); err != nil {
+			return nil, err
+		}
+	}
+	if err = p.expect("]"); err != nil {
+		return nil, err
+	}
+	return &tfSlice{target: target, from: from, to: to}, nil
+}
+
+func (p *tfParser) parsePrimary() (tfExpr, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tkField:
+		return &tfIndex{target: tfIdentity{}, key: &tfLiteral{NewString(tok.text)}}, p.advance()
+	case tkVar:
+		if err := p.checkVar(tok); err != nil {
+			return nil, err
+		}
+		return &tfVar{tok.text}, p.advance()
+	case tkNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %s", tok.text)
+		}
+		return &tfLiteral{NewNumber(v)}, p.advance()
+	case tkString:
+		expr, err := p.stringExpr(tok)
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.advance()
+	case tkIdent:
+		return p.parseIdent()
+	case tkPunct:
+		switch tok.text {
+		case ".":
+			return tfIdentity{}, p.advance()
+		case "..":
+			return tfRecurse{}, p.advance()
+		case "(":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			expr, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			return expr, p.expect(")")
+		case "[":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if p.is(tkPunct, "]") {
+				return &tfArray{}, p.advance()
+			}
+			expr, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			return &tfArray{expr}, p.expect("]")
+		case "{":
+			return p.parseObject()
+		}
+	}
+	return nil, p.errorf("unexpected %s", tok)
+}
+
+func (p *tfParser) stringExpr(tok tfToken) (tfExpr, error) {
+	if len(tok.parts) == 0 {
+		return &tfLiteral{NewString("")}, nil
+	}
+	if len(tok.parts) == 1 && !tok.parts[0].isExpr {
+		return &tfLiteral{NewString(tok.parts[0].lit)}, nil
+	}
+	ret := &tfString{}
+	for _, part := range tok.parts {
+		if !part.isExpr {
+			ret.parts = append(ret.parts, &tfLiteral{NewString(part.lit)})
+			continue
+		}
+		sub, err := newTfParser(part.expr, part.pos, p.vars)
+		if err != nil {
+			return nil, err
+		}
+		expr, err := sub.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		if sub.tok.kind != tkEOF {
+			return nil, sub.errorf("unexpected %s", sub.tok)
+		}
+		ret.parts = append(ret.parts, expr)
+	}
+	return ret, nil
+}
+
+func (p *tfParser) parseIdent() (tfExpr, error) {
+	tok := p.tok
+	switch tok.text {
+	case "true", "false":
+		return &tfLiteral{NewBool(tok.text == "true")}, p.advance()
+	case "null":
+		return &tfLiteral{NewNull()}, p.advance()
+	case "if":
+		return p.parseIf()
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var args []tfExpr
+	if p.is(tkPunct, "(") {
+		for {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			arg, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if !p.is(tkPunct, ";") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	fn, ok := tfBuiltins[tok.text+"/"+strconv.Itoa(len(args))]
+	if !ok {
+		return nil, &TransformError{Pos: tok.pos, Msg: fmt.Sprintf("unknown function %s/%d", tok.text, len(args))}
+	}
+	call := &tfCall{name: tok.text, pos: tok.pos, fn: fn, args: args}
+	if tok.text == "query" {
+		if err := call.precompileQuery(tok.pos); err != nil {
+			return nil, err
+		}
+	}
+	return call, nil
+}
+
+func (p *tfParser) parseIf() (tfExpr, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	cond, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expectKeyword("then"); err != nil {
+		return nil, err
+	}
+	then, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	ret := &tfIf{cond: cond, then: then}
+	switch {
+	case p.is(tkIdent, "elif"):
+		ret.otherwise, err = p.parseIf()
+		return ret, err
+	case p.is(tkIdent, "else"):
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		if ret.otherwise, err = p.parsePipe(); err != nil {
+			return nil, err
+		}
+	}
+	return ret, p.expectKeyword("end")
+}
+
+func (p *tfParser) parseObject() (tfExpr, error) {
+	ret := &tfObject{}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	for !p.is(tkPunct, "}") {
+		if len(ret.entries) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		var entry tfEntry
+		var err error
+		tok := p.tok
+		switch tok.kind {
+		case tkIdent:
+			entry.key = &tfLiteral{NewString(tok.text)}
+			entry.value = &tfIndex{target: tfIdentity{}, key: entry.key}
+			err = p.advance()
+		case tkVar:
+			if err = p.checkVar(tok); err != nil {
+				return nil, err
+			}
+			entry.key = &tfLiteral{NewString(tok.text)}
+			entry.value = &tfVar{tok.text}
+			if err = p.advance(); err != nil {
+				return nil, err
+			}
+			ret.entries = append(ret.entries, entry)
+			continue
+		case tkString:
+			if entry.key, err = p.stringExpr(tok); err != nil {
+				return nil, err
+			}
+			entry.value = &tfIndex{target: tfIdentity{}, key: entry.key}
+			err = p.advance()
+		case tkPunct:
+			if tok.text != "(" {
+				return nil, p.errorf("unexpected %s in object key", tok)
+			}
+			if err = p.advance(); err != nil {
+				return nil, err
+			}
+			if entry.key, err = p.parsePipe(); err != nil {
+				return nil, err
+			}
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			if !p.is(tkPunct, ":") {
+				return nil, p.errorf("expected ':' but found %s", p.tok)
+			}
+		default:
+			return nil, p.errorf("unexpected %s in object key", tok)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if p.is(tkPunct, ":") {
+			if err = p.advance(); err != nil {
+				return nil, err
+			}
+			if entry.value, err = p.parseObjectValue(); err != nil {
+				return nil, err
+			}
+		}
+		ret.entries = append(ret.entries, entry)
+	}
+	return ret, p.advance()
+}
+
+// parseObjectValue parses value of object entry, commas are not allowed without parentheses
+func (p *tfParser) parseObjectValue() (tfExpr, error) {
+	left, err := p.parseAlt()
+	if err != nil {
+		return nil, err
+	}
+	for p.is(tkPunct, "|") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAlt()
+		if err != nil {
+			return nil, err
+		}
+		left = &tfPipe{left, right}
+	}
+	return left, nil
+}
+
+// tfEnv is the chain of variables bindings
+type tfEnv struct {
+	parent *tfEnv
+	name   string
+	value  *Node
+}
+
+func (e *tfEnv) lookup(name string) (*Node, bool) {
+	for ; e != nil; e = e.parent {
+		if e.name == name {
+			return e.value, true
+		}
+	}
+	return nil, false
+}
+
+type tfExpr interface {
+	eval(env *tfEnv, input *Node) (Nodes, error)
+}
+
+func typeName(node *Node) string {
+	switch node.Type() {
+	case Null:
+		return "null"
+	case Bool:
+		return "boolean"
+	case Number:
+		return "number"
+	case String:
+		return "string"
+	case Array:
+		return "array"
+	case Object:
+		return "object"
+	}
+	return "undefined"
+}
+
+func truthy(node *Node) bool {
+	if node.IsNull() {
+		return false
+	}
+	v, ok := node.value.(bool)
+	return !ok || v
+}
+
+type tfIdentity struct{}
+
+func (tfIdentity) eval(env *tfEnv, input *Node) (Nodes, error) {
+	return Nodes{input}, nil
+}
+
+type tfRecurse struct{}
+
+func (tfRecurse) eval(env *tfEnv, input *Node) (Nodes, error) {
+	return descendants(input, nil), nil
+}
+
+type tfLiteral struct {
+	node *Node
+}
+
+func (e *tfLiteral) eval(env *tfEnv, input *Node) (Nodes, error) {
+	return Nodes{e.node}, nil
+}
+
+type tfVar struct {
+	name string
+}
+
+func (e *tfVar) eval(env *tfEnv, input *Node) (Nodes, error) {
+	value, ok := env.lookup(e.name)
+	if !ok {
+		return nil, fmt.Errorf("%w: $%s is not defined", ErrTransform, e.name)
+	}
+	return Nodes{value}, nil
+}
+
+type tfBind struct {
+	source tfExpr
+	name   string
+	body   tfExpr
+}
+
+func (e *tfBind) eval(env *tfEnv, input *Node) (Nodes, error) {
+	values, err := e.source.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(Nodes, 0)
+	for _, value := range values {
+		outs, err := e.body.eval(&tfEnv{parent: env, name: e.name, value: value}, input)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, outs...)
+	}
+	return ret, nil
+}
+
+type tfPipe struct {
+	left, right tfExpr
+}
+
+func (e *tfPipe) eval(env *tfEnv, input *Node) (Nodes, error) {
+	lefts, err := e.left.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(Nodes, 0)
+	for _, left := range lefts {
+		outs, err := e.right.eval(env, left)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, outs...)
+	}
+	return ret, nil
+}
+
+type tfComma struct {
+	left, right tfExpr
+}
+
+func (e *tfComma) eval(env *tfEnv, input *Node) (Nodes, error) {
+	lefts, err := e.left.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	rights, err := e.right.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	return append(lefts, rights...), nil
+}
+
+type tfAlt struct {
+	left, right tfExpr
+}
+
+func (e *tfAlt) eval(env *tfEnv, input *Node) (Nodes, error) {
+	lefts, _ := e.left.eval(env, input)
+	ret := make(Nodes, 0)
+	for _, left := range lefts {
+		if truthy(left) {
+			ret = append(ret, left)
+		}
+	}
+	if len(ret) > 0 {
+		return ret, nil
+	}
+	return e.right.eval(env, input)
+}
+
+type tfTry struct {
+	expr tfExpr
+}
+
+func (e *tfTry) eval(env *tfEnv, input *Node) (Nodes, error) {
+	outs, err := e.expr.eval(env, input)
+	if err != nil {
+		return make(Nodes, 0), nil
+	}
+	return outs, nil
+}
+
+type tfLogic struct {
+	op          string
+	left, right tfExpr
+}
+
+func (e *tfLogic) eval(env *tfEnv, input *Node) (Nodes, error) {
+	lefts, err := e.left.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(Nodes, 0)
+	for _, left := range lefts {
+		lv := truthy(left)
+		if e.op == "and" && !lv || e.op == "or" && lv {
+			ret = append(ret, NewBool(lv))
+			continue
+		}
+		rights, err := e.right.eval(env, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, right := range rights {
+			ret = append(ret, NewBool(truthy(right)))
+		}
+	}
+	return ret, nil
+}
+
+type tfIf struct {
+	cond, then, otherwise tfExpr
+}
+
+func (e *tfIf) eval(env *tfEnv, input *Node) (Nodes, error) {
+	conds, err := e.cond.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(Nodes, 0)
+	for _, cond := range conds {
+		var outs Nodes
+		switch {
+		case truthy(cond):
+			outs, err = e.then.eval(env, input)
+		case e.otherwise != nil:
+			outs, err = e.otherwise.eval(env, input)
+		default:
+			outs = Nodes{input}
+		}
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, outs...)
+	}
+	return ret, nil
+}
+
+type tfIndex struct {
+	target, key tfExpr
+}
+
+func (e *tfIndex) eval(env *tfEnv, input *Node) (Nodes, error) {
+	targets, err := e.target.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(Nodes, 0)
+	for _, target := range targets {
+		keys, err := e.key.eval(env, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			node, err := indexNode(target, key)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, node)
+		}
+	}
+	return ret, nil
+}
+
+func indexNode(target, key *Node) (*Node, error) {
+	switch {
+	case target.IsNull() && (key.IsString() || key.IsNumber()):
+		return NewNull(), nil
+	case target.IsObject() && key.IsString():
+		if child := target.Key(key.value.(string)); child.Exists() {
+			return child, nil
+		}
+		return NewNull(), nil
+	case target.IsArray() && key.IsNumber():
+		idx := int(math.Floor(key.value.(float64)))
+		if idx < 0 {
+			idx += len(target.children)
+		}
+		if child := target.Idx(idx); child.Exists() {
+			return child, nil
+		}
+		return NewNull(), nil
+	}
+	return nil, fmt.Errorf("%w: cannot index %s with %s", ErrTransform, typeName(target), typeName(key))
+}
+
+type tfSlice struct {
+	target, from, to tfExpr
+}
+
+func (e *tfSlice) bound(env *tfEnv, input *Node, expr tfExpr, def int, length int) (int, error) {
+	if expr == nil {
+		return def, nil
+	}
+	outs, err := expr.eval(env, input)
+	if err != nil {
+		return 0, err
+	}
+	if len(outs) != 1 || !outs[0].IsNumber() && !outs[0].IsNull() {
+		return 0, fmt.Errorf("%w: slice bounds must be numbers", ErrTransform)
+	}
+	if outs[0].IsNull() {
+		return def, nil
+	}
+	v := int(math.Floor(outs[0].value.(float64)))
+	if v < 0 {
+		v += length
+	}
+	return min(max(v, 0), length), nil
+}
+
+func (e *tfSlice) eval(env *tfEnv, input *Node) (Nodes, error) {
+	targets, err := e.target.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(Nodes, 0)
+	for _, target := range targets {
+		var length int
+		switch {
+		case target.IsNull():
+			ret = append(ret, NewNull())
+			continue
+		case target.IsArray():
+			length = len(target.children)
+		case target.IsString():
+			length = utf8.RuneCountInString(target.value.(string))
+		default:
+			return nil, fmt.Errorf("%w: cannot slice %s", ErrTransform, typeName(target))
+		}
+		from, err := e.bound(env, input, e.from, 0, length)
+		if err != nil {
+			return nil, err
+		}
+		to, err := e.bound(env, input, e.to, length, length)
+		if err != nil {
+			return nil, err
+		}
+		to = max(from, to)
+		if target.IsString() {
+			ret = append(ret, NewString(string([]rune(target.value.(string))[from:to])))
+			continue
+		}
+		ret = append(ret, newArray(target.children[from:to]))
+	}
+	return ret, nil
+}
+
+// newArray creates array node containing copies of nodes
+func newArray(nodes Nodes) *Node {
+	ret := NewArray()
+	for _, node := range nodes {
+		if err := ret.Append(detach(node)); err != nil {
+			panic(err)
+		}
+	}
+	return ret
+}
+
+type tfIterate struct {
+	target tfExpr
+}
+
+func (e *tfIterate) eval(env *tfEnv, input *Node) (Nodes, error) {
+	targets, err := e.target.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(Nodes, 0)
+	for _, target := range targets {
+		if !target.IsParent() {
+			return nil, fmt.Errorf("%w: cannot iterate over %s", ErrTransform, typeName(target))
+		}
+		ret = append(ret, target.children...)
+	}
+	return ret, nil
+}
+
+type tfString struct {
+	parts []tfExpr
+}
+
+func (e *tfString) eval(env *tfEnv, input *Node) (Nodes, error) {
+	ret := Nodes{NewString("")}
+	for _, part := range e.parts {
+		outs, err := part.eval(env, input)
+		if err != nil {
+			return nil, err
+		}
+		next := make(Nodes, 0, len(ret)*len(outs))
+		for _, prefix := range ret {
+			for _, out := range outs {
+				next = append(next, NewString(prefix.value.(string)+toString(out)))
+			}
+		}
+		ret = next
+	}
+	return ret, nil
+}
+
+func toString(node *Node) string {
+	if v, ok := node.value.(string); ok {
+		return v
+	}
+	return node.Stringify()
+}
+
+type tfArray struct {
+	expr tfExpr
+}
+
+func (e *tfArray) eval(env *tfEnv, input *Node) (Nodes, error) {
+	if e.expr == nil {
+		return Nodes{NewArray()}, nil
+	}
+	outs, err := e.expr.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	return Nodes{newArray(outs)}, nil
+}
+
+type tfEntry struct {
+	key, value tfExpr
+}
+
+type tfObject struct {
+	entries []tfEntry
+}
+
+func (e *tfObject) eval(env *tfEnv, input *Node) (Nodes, error) {
+	ret := Nodes{NewObject()}
+	for _, entry := range e.entries {
+		keys, err := entry.key.eval(env, input)
+		if err != nil {
+			return nil, err
+		}
+		values, err := entry.value.eval(env, input)
+		if err != nil {
+			return nil, err
+		}
+		next := make(Nodes, 0, len(ret)*len(keys)*len(values))
+		for _, obj := range ret {
+			for _, key := range keys {
+				k, err := key.String()
+				if err != nil {
+					return nil, fmt.Errorf("%w: object keys must be strings, got %s", ErrTransform, typeName(key))
+				}
+				for _, value := range values {
+					o := obj
+					if len(keys)*len(values) > 1 {
+						o = detach(obj)
+					}
+					if err = o.Set(k, detach(value)); err != nil {
+						return nil, err
+					}
+					next = append(next, o)
+				}
+			}
+		}
+		ret = next
+	}
+	return ret, nil
+}
+
+type tfBinary struct {
+	op          string
+	pos         int
+	left, right tfExpr
+}
+
+func (e *tfBinary) eval(env *tfEnv, input *Node) (Nodes, error) {
+	rights, err := e.right.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	lefts, err := e.left.eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(Nodes, 0, len(lefts)*len(rights))
+	for _, right := range rights {
+		for _, left := range lefts {
+			out, err := binaryOp(e.op, left, right)
+			if err != nil {
+				return nil, err
+			}
+			if !isFinite(out) {
+				return nil, &TransformError{Pos: e.pos, Msg: fmt.Sprintf("%s overflows number range", e.op), Err: ErrTransform}
+			}
+			ret = append(ret, out)
+		}
+	}
+	return ret, nil
+}
+
+func binaryOp(op string, left, right *Node) (*Node, error) {
+	switch op {
+	case "==":
+		return NewBool(equalNodes(left, right)), nil
+	case "!=":
+		return NewBool(!equalNodes(left, right)), nil
+	case "<":
+		return NewBool(compareNodes(left, right) < 0), nil
+	case "<=":
+		return NewBool(compareNodes(left, right) <= 0), nil
+	case ">":
+		return NewBool(compareNodes(left, right) > 0), nil
+	case ">=":
+		return NewBool(compareNodes(left, right) >= 0), nil
+	}
+	lt, rt := left.Type(), right.Type()
+	fail := func() (*Node, error) {
+		return nil, fmt.Errorf("%w: %s and %s cannot be used with %s", ErrTransform, typeName(left), typeName(right), op)
+	}
+	if lt == Number && rt == Number {
+		l, r := left.value.(float64), right.value.(float64)
+		switch op {
+		case "+":
+			return NewNumber(l + r), nil
+		case "-":
+			return NewNumber(l - r), nil
+		case "*":
+			return NewNumber(l * r), nil
+		case "/":
+			if r == 0 {
+				return nil, fmt.Errorf("%w: division by zero", ErrTransform)
+			}
+			return NewNumber(l / r), nil
+		case "%":
+			if int(r) == 0 {
+				return nil, fmt.Errorf("%w: division by zero", ErrTransform)
+			}
+			return NewInt(int(l) % int(r)), nil
+		}
+	}
+	switch op {
+	case "+":
+		switch {
+		case lt == Null:
+			return right, nil
+		case rt == Null:
+			return left, nil
+		case lt == String && rt == String:
+			return NewString(left.value.(string) + right.value.(string)), nil
+		case lt == Array && rt == Array:
+			return newArray(append(left.Children(), right.children...)), nil
+		case lt == Object && rt == Object:
+			ret := detach(left)
+			for _, child := range right.children {
+				if err := ret.Set(child.key, detach(child)); err != nil {
+					return nil, err
+				}
+			}
+			return ret, nil
+		}
+	case "-":
+		if lt == Array && rt == Array {
+			ret := make(Nodes, 0)
+			for _, child := range left.children {
+				if !slices.ContainsFunc(right.children, func(n *Node) bool { return equalNodes(n, child) }) {
+					ret = append(ret, child)
+				}
+			}
+			return newArray(ret), nil
+		}
+	case "/":
+		if lt == String && rt == String {
+			return splitString(left, right), nil
+		}
+	}
+	return fail()
+}
+
+// isFinite reports if node is not the infinite number which can not be written as json
+func isFinite(node *Node) bool {
+	v, ok := node.value.(float64)
+	return !ok || !math.IsInf(v, 0) && !math.IsNaN(v)
+}
+
+func splitString(s, sep *Node) *Node {
+	ret := NewArray()
+	for _, part := range strings.Split(s.value.(string), sep.value.(string)) {
+		ret.AppendString(part)
+	}
+	return ret
+}
+
+// tfBuiltin implements builtin function, args are not evaluated in advance
+type tfBuiltin func(call *tfCall, env *tfEnv, input *Node) (Nodes, error)
+
+type tfCall struct {
+	name  string
+	pos   int
+	fn    tfBuiltin
+	args  []tfExpr
+	query *CompiledQuery
+}
+
+func (e *tfCall) eval(env *tfEnv, input *Node) (Nodes, error) {
+	return e.fn(e, env, input)
+}
+
+// precompileQuery compiles literal JSONPath expression of query function
+func (e *tfCall) precompileQuery(pos int) error {
+	literal, ok := e.args[0].(*tfLiteral)
+	if !ok {
+		return nil
+	}
+	expr, err := literal.node.String()
+	if err != nil {
+		return &TransformError{Pos: pos, Msg: "query expects string argument"}
+	}
+	if e.query, err = CompileQuery(expr); err != nil {
+		msg := strings.TrimPrefix(err.Error(), ErrBadQuery.Error()+": ")
+		return &TransformError{Pos: pos, Msg: fmt.Sprintf("invalid query %q (%s)", expr, msg)}
+	}
+	return nil
+}
+
+// eachArg evaluates each argument output for each input producing the results of fn
+func eachArg(call *tfCall, env *tfEnv, input *Node, fn func(arg *Node) (*Node, error)) (Nodes, error) {
+	args, err := call.args[0].eval(env, input)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(Nodes, 0, len(args))
+	for _, arg := range args {
+		out, err := fn(arg)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, out)
+	}
+	return ret, nil
+}
+
+func builtinError(name string, node *Node) error {
+	return fmt.Errorf("%w: %s (%s) is not valid input for %s", ErrTransform, typeName(node), node.Stringify(), name)
+}
+
+// sortKeys evaluates sort key for each array element
+func sortKeys(call *tfCall, env *tfEnv, input *Node) (Nodes, Nodes, error) {
+	if !input.IsArray() {
+		return nil, nil, builtinError(call.name, input)
+	}
+	items := input.Children()
+	keys := make(Nodes, len(items))
+	for i, item := range items {
+		keys[i] = item
+		if len(call.args) == 0 {
+			continue
+		}
+		outs, err := call.args[0].eval(env, item)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys[i] = newArray(outs)
+	}
+	return items, keys, nil
+}
+
+type keyed struct {
+	item, key *Node
+}
+
+func sortedByKeys(items, keys Nodes) []keyed {
+	pairs := make([]keyed, len(items))
+	for i := range items {
+		pairs[i] = keyed{items[i], keys[i]}
+	}
+	slices.SortStableFunc(pairs, func(a, b keyed) int {
+		return compareNodes(a.key, b.key)
+	})
+	return pairs
+}
+
+func builtinSort(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+	items, keys, err := sortKeys(call, env, input)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(Nodes, 0, len(items))
+	for _, pair := range sortedByKeys(items, keys) {
+		ret = append(ret, pair.item)
+	}
+	return Nodes{newArray(ret)}, nil
+}
+
+func builtinGroup(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+	items, keys, err := sortKeys(call, env, input)
+	if err != nil {
+		return nil, err
+	}
+	ret := NewArray()
+	var group Nodes
+	var last *Node
+	for _, pair := range sortedByKeys(items, keys) {
+		if last != nil && !equalNodes(last, pair.key) {
+			ret.Append(newArray(group))
+			group = nil
+		}
+		group = append(group, pair.item)
+		last = pair.key
+	}
+	if len(group) > 0 {
+		ret.Append(newArray(group))
+	}
+	return Nodes{ret}, nil
+}
+
+func builtinUnique(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+	items, keys, err := sortKeys(call, env, input)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(Nodes, 0)
+	var last *Node
+	for _, pair := range sortedByKeys(items, keys) {
+		if last == nil || !equalNodes(last, pair.key) {
+			ret = append(ret, pair.item)
+		}
+		last = pair.key
+	}
+	return Nodes{newArray(ret)}, nil
+}
+
+func builtinExtreme(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+	items, keys, err := sortKeys(call, env, input)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return Nodes{NewNull()}, nil
+	}
+	pairs := sortedByKeys(items, keys)
+	if strings.HasPrefix(call.name, "min") {
+		return Nodes{pairs[0].item}, nil
+	}
+	return Nodes{pairs[len(pairs)-1].item}, nil
+}
+
+var tfBuiltins map[string]tfBuiltin
+
+func init() {
+	tfBuiltins = map[string]tfBuiltin{
+		"empty/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			return make(Nodes, 0), nil
+		},
+		"not/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			return Nodes{NewBool(!truthy(input))}, nil
+		},
+		"type/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			return Nodes{NewString(typeName(input))}, nil
+		},
+		"length/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			switch input.Type() {
+			case Null:
+				return Nodes{NewInt(0)}, nil
+			case Number:
+				return Nodes{NewNumber(math.Abs(input.value.(float64)))}, nil
+			case String:
+				return Nodes{NewInt(utf8.RuneCountInString(input.value.(string)))}, nil
+			case Array, Object:
+				return Nodes{NewInt(len(input.children))}, nil
+			}
+			return nil, builtinError(call.name, input)
+		},
+		"keys/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			ret := NewArray()
+			switch {
+			case input.IsObject():
+				keys := input.ChildrenKeys()
+				slices.Sort(keys)
+				for _, key := range keys {
+					ret.AppendString(key)
+				}
+			case input.IsArray():
+				for i := range input.children {
+					ret.AppendInt(i)
+				}
+			default:
+				return nil, builtinError(call.name, input)
+			}
+			return Nodes{ret}, nil
+		},
+		"has/1": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			return eachArg(call, env, input, func(key *Node) (*Node, error) {
+				switch {
+				case input.IsObject() && key.IsString():
+					return NewBool(input.Key(key.value.(string)).Exists()), nil
+				case input.IsArray() && key.IsNumber():
+					v := key.value.(float64)
+					return NewBool(v >= 0 && v < float64(len(input.children))), nil
+				}
+				return nil, builtinError(call.name, input)
+			})
+		},
+		"map/1": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			if !input.IsParent() {
+				return nil, builtinError(call.name, input)
+			}
+			ret := make(Nodes, 0)
+			for _, child := range input.children {
+				outs, err := call.args[0].eval(env, child)
+				if err != nil {
+					return nil, err
+				}
+				ret = append(ret, outs...)
+			}
+			return Nodes{newArray(ret)}, nil
+		},
+		"select/1": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			conds, err := call.args[0].eval(env, input)
+			if err != nil {
+				return nil, err
+			}
+			ret := make(Nodes, 0)
+			for _, cond := range conds {
+				if truthy(cond) {
+					ret = append(ret, input)
+				}
+			}
+			return ret, nil
+		},
+		"add/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			if !input.IsParent() {
+				return nil, builtinError(call.name, input)
+			}
+			acc := NewNull()
+			for _, child := range input.children {
+				var err error
+				if acc, err = binaryOp("+", acc, child); err != nil {
+					return nil, err
+				}
+				if !isFinite(acc) {
+					return nil, &TransformError{Pos: call.pos, Msg: "add overflows number range", Err: ErrTransform}
+				}
+			}
+			return Nodes{acc}, nil
+		},
+		"tostring/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			return Nodes{NewString(toString(input))}, nil
+		},
+		"tonumber/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			switch v := input.value.(type) {
+			case float64:
+				return Nodes{input}, nil
+			case string:
+				f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				if err == nil {
+					return Nodes{NewNumber(f)}, nil
+				}
+			}
+			return nil, builtinError(call.name, input)
+		},
+		"tojson/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			return Nodes{NewString(input.Stringify())}, nil
+		},
+		"fromjson/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			s, err := input.String()
+			if err != nil {
+				return nil, builtinError(call.name, input)
+			}
+			node, err := ParseString(s)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrTransform, err.Error())
+			}
+			return Nodes{node}, nil
+		},
+		"sort/0":      builtinSort,
+		"sort_by/1":   builtinSort,
+		"group_by/1":  builtinGroup,
+		"unique/0":    builtinUnique,
+		"unique_by/1": builtinUnique,
+		"min/0":       builtinExtreme,
+		"max/0":       builtinExtreme,
+		"min_by/1":    builtinExtreme,
+		"max_by/1":    builtinExtreme,
+		"reverse/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			switch {
+			case input.IsNull():
+				return Nodes{NewArray()}, nil
+			case input.IsString():
+				rs := []rune(input.value.(string))
+				slices.Reverse(rs)
+				return Nodes{NewString(string(rs))}, nil
+			case input.IsArray():
+				items := input.Children()
+				slices.Reverse(items)
+				return Nodes{newArray(items)}, nil
+			}
+			return nil, builtinError(call.name, input)
+		},
+		"first/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			node, err := indexNode(input, NewInt(0))
+			return Nodes{node}, err
+		},
+		"last/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			node, err := indexNode(input, NewInt(-1))
+			return Nodes{node}, err
+		},
+		"any/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			if !input.IsParent() {
+				return nil, builtinError(call.name, input)
+			}
+			return Nodes{NewBool(slices.ContainsFunc(input.children, truthy))}, nil
+		},
+		"all/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			if !input.IsParent() {
+				return nil, builtinError(call.name, input)
+			}
+			return Nodes{NewBool(!slices.ContainsFunc(input.children, func(n *Node) bool { return !truthy(n) }))}, nil
+		},
+		"to_entries/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			if !input.IsObject() {
+				return nil, builtinError(call.name, input)
+			}
+			ret := NewArray()
+			for _, child := range input.children {
+				entry := NewObject()
+				entry.SetString("key", child.key)
+				entry.Set("value", detach(child))
+				ret.Append(entry)
+			}
+			return Nodes{ret}, nil
+		},
+		"from_entries/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			if !input.IsArray() {
+				return nil, builtinError(call.name, input)
+			}
+			ret := NewObject()
+			for _, entry := range input.children {
+				key := entry.Key("key")
+				for _, name := range []string{"k", "name", "Name", "Key"} {
+					if !key.Exists() {
+						key = entry.Key(name)
+					}
+				}
+				var k string
+				switch v := key.value.(type) {
+				case string:
+					k = v
+				case float64, bool:
+					k = key.Stringify()
+				default:
+					return nil, fmt.Errorf("%w: entry key must be string", ErrTransform)
+				}
+				value := entry.Key("value")
+				if !value.Exists() {
+					value = entry.Key("v")
+				}
+				if !value.Exists() {
+					value = NewNull()
+				}
+				ret.Set(k, detach(value))
+			}
+			return Nodes{ret}, nil
+		},
+		"join/1": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			if !input.IsArray() {
+				return nil, builtinError(call.name, input)
+			}
+			return eachArg(call, env, input, func(sep *Node) (*Node, error) {
+				s, err := sep.String()
+				if err != nil {
+					return nil, builtinError(call.name, sep)
+				}
+				items := make([]string, len(input.children))
+				for i, child := range input.children {
+					switch {
+					case child.IsNull():
+					case child.IsParent():
+						return nil, builtinError(call.name, child)
+					default:
+						items[i] = toString(child)
+					}
+				}
+				return NewString(strings.Join(items, s)), nil
+			})
+		},
+		"split/1": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			return eachArg(call, env, input, func(sep *Node) (*Node, error) {
+				if !input.IsString() || !sep.IsString() {
+					return nil, builtinError(call.name, input)
+				}
+				return splitString(input, sep), nil
+			})
+		},
+		"ascii_downcase/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			s, err := input.String()
+			if err != nil {
+				return nil, builtinError(call.name, input)
+			}
+			return Nodes{NewString(strings.ToLower(s))}, nil
+		},
+		"ascii_upcase/0": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			s, err := input.String()
+			if err != nil {
+				return nil, builtinError(call.name, input)
+			}
+			return Nodes{NewString(strings.ToUpper(s))}, nil
+		},
+		"startswith/1": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			return eachArg(call, env, input, func(arg *Node) (*Node, error) {
+				if !input.IsString() || !arg.IsString() {
+					return nil, builtinError(call.name, input)
+				}
+				return NewBool(strings.HasPrefix(input.value.(string), arg.value.(string))), nil
+			})
+		},
+		"endswith/1": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			return eachArg(call, env, input, func(arg *Node) (*Node, error) {
+				if !input.IsString() || !arg.IsString() {
+					return nil, builtinError(call.name, input)
+				}
+				return NewBool(strings.HasSuffix(input.value.(string), arg.value.(string))), nil
+			})
+		},
+		"test/1": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			return eachArg(call, env, input, func(arg *Node) (*Node, error) {
+				if !input.IsString() || !arg.IsString() {
+					return nil, builtinError(call.name, input)
+				}
+				re, err := regexp.Compile(arg.value.(string))
+				if err != nil {
+					return nil, fmt.Errorf("%w: %s", ErrInvalidRegexp, err.Error())
+				}
+				return NewBool(re.MatchString(input.value.(string))), nil
+			})
+		},
+		"query/1": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			if call.query != nil {
+				return call.query.Eval(input), nil
+			}
+			exprs, err := call.args[0].eval(env, input)
+			if err != nil {
+				return nil, err
+			}
+			ret := make(Nodes, 0)
+			for _, expr := range exprs {
+				s, err := expr.String()
+				if err != nil {
+					return nil, builtinError(call.name, expr)
+				}
+				ns, err := input.Query(s)
+				if err != nil {
+					return nil, err
+				}
+				ret = append(ret, ns...)
+			}
+			return ret, nil
+		},
+		"with_entries/1": func(call *tfCall, env *tfEnv, input *Node) (Nodes, error) {
+			entries, err := tfBuiltins["to_entries/0"](call, env, input)
+			if err != nil {
+				return nil, err
+			}
+			mapped, err := tfBuiltins["map/1"](call, env, entries[0])
+			if err != nil {
+				return nil, err
+			}
+			return tfBuiltins["from_entries/0"](call, env, mapped[0])
+		},
+	}
+}
@@ -0,0 +1,190 @@
+package xtjson
+
+import (
+	"errors"
+	"testing"
+)
+
+const transformJson = `{"name":"shop","items":[{"id":1,"title":"pen","price":2.5,"tags":["office"]},{"id":2,"title":"book","price":12,"tags":[]},{"id":3,"title":"lamp","price":30,"tags":["home","office"]}],"owner":null}`
+
+func transformAll(t *testing.T, expr string) string {
+	t.Helper()
+	root, err := ParseString(transformJson)
+	assertParsed(t, root, err)
+	ns, err := TransformAll(root, expr)
+	assertNil(t, err)
+	return ns.ToArray().Stringify()
+}
+
+func TestTransform(t *testing.T) {
+	root, err := ParseString(transformJson)
+	assertParsed(t, root, err)
+	node, err := Transform(root, ".items | map({id, name: .title})")
+	assertNil(t, err)
+	assertEqual(t, `[{"id":1,"name":"pen"},{"id":2,"name":"book"},{"id":3,"name":"lamp"}]`, node.Stringify())
+	assertEqual(t, false, node.Parent().Exists())
+
+	// the source tree stays untouched
+	node.Idx(0).Set("id", NewInt(100))
+	assertEqual(t, 1.0, root.Key("items").Idx(0).Key("id").value.(float64))
+
+	_, err = Transform(root, ".items[]")
+	if !errors.Is(err, ErrTransform) {
+		t.Fatal("expected error ErrTransform")
+	}
+	_, err = Transform(nil, ".")
+	if !errors.Is(err, ErrNilNode) {
+		t.Fatal("expected error ErrNilNode")
+	}
+}
+
+func TestTransformPaths(t *testing.T) {
+	assertEqual(t, `["shop"]`, transformAll(t, ".name"))
+	assertEqual(t, `["shop"]`, transformAll(t, `."name"`))
+	assertEqual(t, `[null]`, transformAll(t, ".missing"))
+	assertEqual(t, `[null]`, transformAll(t, ".owner.name"))
+	assertEqual(t, `["pen"]`, transformAll(t, ".items[0].title"))
+	assertEqual(t, `["lamp"]`, transformAll(t, ".items[-1].title"))
+	assertEqual(t, `["lamp"]`, transformAll(t, ".items.[2].title"))
+	assertEqual(t, `[[2,3]]`, transformAll(t, "[.items[1:].[].id]"))
+	assertEqual(t, `["ho"]`, transformAll(t, `.items[2].tags[0][:2]`))
+	assertEqual(t, `["office","home","office"]`, transformAll(t, ".items[].tags[]"))
+	assertEqual(t, `[1,2,3]`, transformAll(t, `.items[] | .["id"]`))
+	assertEqual(t, `[1,2,3]`, transformAll(t, `[..|.id?|select(type == "number")] | .[]`))
+	assertEqual(t, `[]`, transformAll(t, ".name[]?"))
+}
+
+func TestTransformConstruction(t *testing.T) {
+	assertEqual(t, `[{"shop":3}]`, transformAll(t, "{(.name): (.items | length)}"))
+	assertEqual(t, `[{"title":"pen"},{"title":"book"}]`, transformAll(t, "{title: .items[0,1].title}"))
+	assertEqual(t, `[{"n":"shop","k":"shop"}]`, transformAll(t, `.name as $n | {$n, "k": $n}`))
+	assertEqual(t, `[[]]`, transformAll(t, "[]"))
+	assertEqual(t, `["shop has 3 items"]`, transformAll(t, `"\(.name) has \(.items | length) items"`))
+	assertEqual(t, `["a\"b"]`, transformAll(t, `"a\"\("b")"`))
+}
+
+func TestTransformOperators(t *testing.T) {
+	assertEqual(t, `[44.5]`, transformAll(t, "[.items[].price] | add"))
+	assertEqual(t, `[5,-1,6,1.5,1]`, transformAll(t, "2 + 3, 2 - 3, 2 * 3, 3 / 2, 7 % 3"))
+	assertEqual(t, `[-2.5]`, transformAll(t, "-.items[0].price"))
+	assertEqual(t, `["ab",[1,2],{"a":1,"b":3}]`, transformAll(t, `"a" + "b", [1] + [2], {a:1,b:2} + {b:3}`))
+	assertEqual(t, `[[1,3]]`, transformAll(t, "[1,2,3,2] - [2]"))
+	assertEqual(t, `[true,false,true,true]`, transformAll(t, `1 == 1.0, "a" != "a", null < false, [1] >= [0,5]`))
+	assertEqual(t, `[false,true,true]`, transformAll(t, "true and null, false or 1, (false | not)"))
+	assertEqual(t, `["none",7]`, transformAll(t, `.owner // "none", (null, 7) // 0`))
+	assertEqual(t, `["cheap","mid","high"]`, transformAll(t, `.items[] | if .price < 5 then "cheap" elif .price < 20 then "mid" else "high" end`))
+	assertEqual(t, `[1,{"id":2},3]`, transformAll(t, `.items[] | {id} | if .id == 2 then . end | if .id != 2 then .id else . end`))
+}
+
+func TestTransformBuiltins(t *testing.T) {
+	assertEqual(t, `[["book","lamp"]]`, transformAll(t, `[.items[] | select(.price > 10) | .title]`))
+	assertEqual(t, `[["items","name","owner"],[0,1]]`, transformAll(t, "keys, (.items[0].tags + [1] | keys)"))
+	assertEqual(t, `[true,false,true]`, transformAll(t, `has("name"), has("x"), (.items | has(2))`))
+	assertEqual(t, `[["book","lamp","pen"]]`, transformAll(t, `[.items[].title] | sort`))
+	assertEqual(t, `[[3,2,1]]`, transformAll(t, `.items | sort_by(-.price) | map(.id)`))
+	assertEqual(t, `[[[2],[1,3]]]`, transformAll(t, `.items | group_by(.tags | length > 0) | map(map(.id))`))
+	assertEqual(t, `[["home","office"]]`, transformAll(t, `[.items[].tags[]] | unique`))
+	assertEqual(t, `[1,3,"book"]`, transformAll(t, `(.items | min_by(.price).id), (.items | max_by(.price).id), ([.items[].title] | min)`))
+	assertEqual(t, `["string","object","null","number"]`, transformAll(t, ".name, ., .owner, .items[0].id | type"))
+	assertEqual(t, `["12",12,"{\"a\":1}",{"a":1}]`, transformAll(t, `(.items[1].price | tostring), ("12" | tonumber), ({a:1} | tojson), ("{\"a\":1}" | fromjson)`))
+	assertEqual(t, `["pen-book-lamp",["a","b"]]`, transformAll(t, `([.items[].title] | join("-")), ("a,b" | split(","))`))
+	assertEqual(t, `["SHOP",true,true,false]`, transformAll(t, `(.name | ascii_upcase), (.name | startswith("sh")), (.name | test("^s.o")), (.name | endswith("x"))`))
+	assertEqual(t, `[{"NAME":"shop"}]`, transformAll(t, `{name} | with_entries({key: (.key | ascii_upcase), value})`))
+	assertEqual(t, `[[{"key":"a","value":1}],{"a":1}]`, transformAll(t, `({a:1} | to_entries), ([{k:"a",v:1}] | from_entries)`))
+	assertEqual(t, `[{"1":"a","true":"b","x":null}]`, transformAll(t, `[{k:1,v:"a"},{name:true,value:"b"},{Key:"x"}] | from_entries`))
+	assertEqual(t, `[1,3,[3,2,1],"cba"]`, transformAll(t, `(.items | first.id, last.id), ([.items[].id] | reverse), ("abc" | reverse)`))
+	assertEqual(t, `[true,false,4]`, transformAll(t, `([.items[].price, null] | any, all), ("lamp" | length)`))
+	assertEqual(t, `[]`, transformAll(t, "empty"))
+}
+
+func TestTransformQuery(t *testing.T) {
+	assertEqual(t, `[["pen","lamp"]]`, transformAll(t, `[query("$.items[?@.tags[-1] == 'office'].title")]`))
+	assertEqual(t, `[3]`, transformAll(t, `"$..id" as $q | [query($q)] | length`))
+
+	_, err := CompileTransform(`query("$.items[")`)
+	if !errors.Is(err, ErrBadTransform) {
+		t.Fatal("expected error ErrBadTransform")
+	}
+}
+
+func TestTransformRuntimeErrors(t *testing.T) {
+	root, err := ParseString(transformJson)
+	assertParsed(t, root, err)
+	for _, expr := range []string{".name.x", ".items.x", ".name[]", "1 / 0", `{(1): 2}`, `"a" - 1`, ".name | keys", `"x" | tonumber`} {
+		_, err := TransformAll(root, expr)
+		if !errors.Is(err, ErrTransform) {
+			t.Fatalf("expected error ErrTransform for %s, got %v", expr, err)
+		}
+	}
+}
+
+func TestTransformSyntaxErrors(t *testing.T) {
+	for expr, msg := range map[string]string{
+		".items | map(":      "bad transform syntax: unexpected end of expression at position 13",
+		".a ]":               "bad transform syntax: unexpected ']' at position 3",
+		"{a: 1":              "bad transform syntax: expected ',' but found end of expression at position 5",
+		"foo(1)":             "bad transform syntax: unknown function foo/1 at position 0",
+		`"abc`:               "bad transform syntax: unterminated string at position 0",
+		`"x\(.a | )"`:        "bad transform syntax: unexpected end of expression at position 9",
+		"if . then 1":        "bad transform syntax: expected 'end' but found end of expression at position 11",
+		". as x | .":         "bad transform syntax: expected variable but found 'x' at position 5",
+		"1 # 2":              "bad transform syntax: unexpected character '#' at position 2",
+		`query("$[?@.a=1]")`: `bad transform syntax: invalid query "$[?@.a=1]" (expected , or ] at position 6) at position 0`,
+	} {
+		_, err := CompileTransform(expr)
+		var te *TransformError
+		if !errors.As(err, &te) || !errors.Is(err, ErrBadTransform) {
+			t.Fatalf("expected TransformError for %s, got %v", expr, err)
+		}
+		assertEqual(t, msg, err.Error())
+	}
+}
+
+func TestTransformVariableScope(t *testing.T) {
+	assertEqual(t, `[[1,1]]`, transformAll(t, `1 as $x | [$x, ("\($x)" | tonumber)]`))
+	assertEqual(t, `[{"x":2}]`, transformAll(t, `2 as $x | {$x}`))
+	for expr, msg := range map[string]string{
+		"$x":                      "bad transform syntax: $x is not defined at position 0",
+		"{$x}":                    "bad transform syntax: $x is not defined at position 1",
+		`(1 as $x | $x) | $x`:     "bad transform syntax: $x is not defined at position 17",
+		`"\($y)"`:                 "bad transform syntax: $y is not defined at position 3",
+		`.items[] as $i | $i, $j`: "bad transform syntax: $j is not defined at position 21",
+	} {
+		_, err := CompileTransform(expr)
+		var te *TransformError
+		if !errors.As(err, &te) || !errors.Is(err, ErrBadTransform) {
+			t.Fatalf("expected TransformError for %s, got %v", expr, err)
+		}
+		assertEqual(t, msg, err.Error())
+	}
+}
+
+func TestTransformOverflow(t *testing.T) {
+	root, err := ParseString(`{"a":[1,2],"b":[1e308,1e308]}`)
+	assertParsed(t, root, err)
+	for expr, msg := range map[string]string{
+		"[.a[] | . * 1e308 * 10]": "transform error: * overflows number range at position 18",
+		"-1e308 - 1e308":          "transform error: - overflows number range at position 7",
+		".b | add":                "transform error: add overflows number range at position 5",
+	} {
+		_, err := TransformAll(root, expr)
+		var te *TransformError
+		if !errors.As(err, &te) || !errors.Is(err, ErrTransform) || errors.Is(err, ErrBadTransform) {
+			t.Fatalf("expected TransformError for %s, got %v", expr, err)
+		}
+		assertEqual(t, msg, err.Error())
+	}
+}
+
+func TestCompiledTransform(t *testing.T) {
+	ct, err := CompileTransform(".items[] | {id, total: (.price * 2)}")
+	assertNil(t, err)
+	assertEqual(t, ".items[] | {id, total: (.price * 2)}", ct.String())
+	root, err := ParseString(transformJson)
+	assertParsed(t, root, err)
+	for range 2 {
+		ns, err := ct.Run(root)
+		assertNil(t, err)
+		assertEqual(t, `[{"id":1,"total":5},{"id":2,"total":24},{"id":3,"total":60}]`, ns.ToArray().Stringify())
+	}
+}
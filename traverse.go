@@ -3,14 +3,7 @@ package xtjson
 import (
 	"errors"
 	"strconv"
-)
-
-type state int
-
-const (
-	enter state = iota
-	object
-	array
+	"strings"
 )
 
 var (
@@ -28,53 +21,139 @@ const (
 	WalkDone
 )
 
+// WalkOrder defines the order nodes are returned by Walker
+type WalkOrder int
+
+const (
+	// WalkDepthFirst returns parents with WalkEnter before and WalkExit after their children
+	WalkDepthFirst WalkOrder = iota
+	// WalkBreadthFirst returns nodes level by level, parents are returned once with WalkEnter
+	WalkBreadthFirst
+	// WalkPostOrder returns every node once with WalkPass after its children
+	WalkPostOrder
+	// WalkLeaves returns with WalkPass only scalars, empty containers and nodes on deep limit
+	WalkLeaves
+)
+
+// WalkOptions
+type WalkOptions struct {
+	Order   WalkOrder `json:"order"`
+	Reverse bool      `json:"reverse,omitempty"` // visit siblings from the last one to the first one
+}
+
+// walkItem is the node with its level waiting in breadth first queue or post order stack
+type walkItem struct {
+	node    *Node
+	level   int
+	pos     int    // number of children already pushed to post order stack
+	path    string // breadth first path and pointer of the node
+	pointer string
+}
+
 // Walker provides an api for traversing tree nodes
 type Walker struct {
 	root      *Node
+	rootLevel int
 	deepLimit int
+	opt       WalkOptions
 	next      *Node
 	nextState WalkState
+	queue     []walkItem
+	pending   *walkItem // breadth first node which children are not queued yet
+	current   *Node     // the node returned by the last Next call
+	cursor    walkCursor
+	popCursor bool // post order cursor still points to the node removed from stack
 }
 
 // NewWalker creates Walker instance
 // root is a start and end point
 // deepLimit 0 means unlimited
-func NewWalker(root *Node, deepLimit int) (*Walker, error) {
+// optional WalkOptions define the order of walking, depth first is default
+func NewWalker(root *Node, deepLimit int, opts ...*WalkOptions) (*Walker, error) {
 	if root == nil {
 		return nil, ErrNilNode
 	}
+	level := root.Level()
 	if deepLimit > 0 {
-		deepLimit += root.Level()
+		deepLimit += level
 	} else {
 		deepLimit = 0
 	}
 	ret := Walker{
 		root:      root,
+		rootLevel: level,
 		deepLimit: deepLimit,
 		next:      root,
 		nextState: WalkEnter,
 	}
+	if len(opts) > 0 && opts[0] != nil {
+		ret.opt = *opts[0]
+	}
 	if root.IsScalar() {
 		ret.nextState = WalkPass
 	}
+	switch ret.opt.Order {
+	case WalkBreadthFirst:
+		ret.queue = []walkItem{{node: root, level: level, path: root.SelfPath(), pointer: pointerOf(root)}}
+	case WalkPostOrder, WalkLeaves:
+		ret.queue = []walkItem{{node: root, level: level}}
+		ret.cursor.reset(root)
+	}
 	return &ret, nil
 }
 
+// child returns child on position counted according to walk direction
+func (w *Walker) child(node *Node, pos int) *Node {
+	if w.opt.Reverse {
+		return node.children[len(node.children)-1-pos]
+	}
+	return node.children[pos]
+}
+
+// sibling returns the next sibling according to walk direction or nil
+func (w *Walker) sibling(node *Node) *Node {
+	parent := node.parent
+	idx := node.idx + 1
+	if w.opt.Reverse {
+		idx = node.idx - 1
+	}
+	if idx < 0 || idx >= len(parent.children) {
+		return nil
+	}
+	return parent.children[idx]
+}
+
+// expandable checks if children of node on level are walked
+func (w *Walker) expandable(node *Node, level int) bool {
+	return len(node.children) > 0 && (w.deepLimit == 0 || level < w.deepLimit)
+}
+
 // Next returns next node in walk
 func (w *Walker) Next() (*Node, WalkState) {
-	if w == nil || w.nextState == WalkDone {
+	if w == nil {
+		return nil, WalkDone
+	}
+	if w.nextState == WalkDone {
+		w.current = nil
 		return nil, WalkDone
 	}
+	switch w.opt.Order {
+	case WalkBreadthFirst:
+		return w.nextBreadthFirst()
+	case WalkPostOrder, WalkLeaves:
+		return w.nextPostOrder()
+	}
 	node := w.next
 	state := w.nextState
+	w.moveTo(node)
 
 	if state == WalkEnter {
-		if len(node.children) == 0 || w.deepLimit > 0 && node.Level() >= w.deepLimit {
+		if len(node.children) == 0 || w.deepLimit > 0 && w.cursor.depth >= w.deepLimit {
 			w.next = node
 			w.nextState = WalkExit
 			return node, state
 		}
-		w.next = node.children[0]
+		w.next = w.child(node, 0)
 		w.nextState = WalkPass
 		if w.next.IsParent() {
 			w.nextState = WalkEnter
@@ -88,8 +167,8 @@ func (w *Walker) Next() (*Node, WalkState) {
 			w.nextState = WalkDone
 			return node, state
 		}
-		if len(parent.children)-1 > node.idx {
-			w.next = parent.children[node.idx+1]
+		if sibling := w.sibling(node); sibling != nil {
+			w.next = sibling
 			w.nextState = WalkPass
 			if w.next.IsParent() {
 				w.nextState = WalkEnter
@@ -102,11 +181,127 @@ func (w *Walker) Next() (*Node, WalkState) {
 	return node, state
 }
 
+// moveTo updates depth first cursor from the current node to the next one
+func (w *Walker) moveTo(node *Node) {
+	prev := w.current
+	w.current = node
+	switch {
+	case prev == node:
+	case prev == nil:
+		w.cursor.reset(node)
+	case node.parent == prev:
+		w.cursor.push(node)
+	case prev.parent == node:
+		w.cursor.pop()
+	case prev.parent != nil && node.parent == prev.parent:
+		w.cursor.pop()
+		w.cursor.push(node)
+	default:
+		w.cursor.reset(node)
+	}
+}
+
+func (w *Walker) nextBreadthFirst() (*Node, WalkState) {
+	if item := w.pending; item != nil {
+		for i := range item.node.children {
+			child := w.child(item.node, i)
+			w.queue = append(w.queue, walkItem{
+				node:    child,
+				level:   item.level + 1,
+				path:    item.path + child.pathSegment(),
+				pointer: item.pointer + "/" + child.pointerToken(),
+			})
+		}
+		w.pending = nil
+	}
+	if len(w.queue) == 0 {
+		w.current = nil
+		w.nextState = WalkDone
+		return nil, WalkDone
+	}
+	item := w.queue[0]
+	w.queue[0] = walkItem{}
+	w.queue = w.queue[1:]
+	w.current = item.node
+	w.cursor.set(item)
+	if !item.node.IsParent() {
+		return item.node, WalkPass
+	}
+	if w.expandable(item.node, item.level) {
+		w.pending = &item
+	}
+	return item.node, WalkEnter
+}
+
+func (w *Walker) nextPostOrder() (*Node, WalkState) {
+	if w.popCursor {
+		w.cursor.pop()
+		w.popCursor = false
+	}
+	for len(w.queue) > 0 {
+		top := &w.queue[len(w.queue)-1]
+		if w.expandable(top.node, top.level) && top.pos < len(top.node.children) {
+			child := w.child(top.node, top.pos)
+			top.pos++
+			w.queue = append(w.queue, walkItem{node: child, level: top.level + 1})
+			w.cursor.push(child)
+			continue
+		}
+		item := *top
+		w.queue = w.queue[:len(w.queue)-1]
+		w.popCursor = true
+		if w.opt.Order == WalkLeaves && item.pos > 0 {
+			w.cursor.pop()
+			w.popCursor = false
+			continue
+		}
+		w.current = item.node
+		return item.node, WalkPass
+	}
+	w.current = nil
+	w.nextState = WalkDone
+	return nil, WalkDone
+}
+
+// Path returns json path of the node returned by the last Next call
+func (w *Walker) Path() string {
+	if w == nil || w.current == nil {
+		return ""
+	}
+	return string(w.cursor.path)
+}
+
+// Pointer returns json pointer (RFC 6901) of the node returned by the last Next call
+func (w *Walker) Pointer() string {
+	if w == nil || w.current == nil {
+		return ""
+	}
+	return string(w.cursor.pointer)
+}
+
+// Depth returns the level of the node returned by the last Next call, root of the tree has 0 level
+func (w *Walker) Depth() int {
+	if w == nil || w.current == nil {
+		return 0
+	}
+	return w.cursor.depth
+}
+
 // Skip method can be called to bypass going deep to current node children
 // This method will have effect if called right after Walker returned WalkEnter state,
 // otherwise it returns the error. Even after error the walker is able to continue.
 func (w *Walker) Skip() error {
-	if w.next == nil || w.next.idx != 0 || w.nextState == WalkExit || w.nextState == WalkDone {
+	if w.opt.Order == WalkBreadthFirst {
+		if w.pending == nil {
+			return ErrWalkSkip
+		}
+		w.pending = nil
+		return nil
+	}
+	if w.opt.Order != WalkDepthFirst || w.next == nil || w.nextState == WalkExit || w.nextState == WalkDone {
+		return ErrWalkSkip
+	}
+	if w.next.parent != nil && w.next != w.child(w.next.parent, 0) || w.next.parent == nil && w.next.idx != 0 {
 		return ErrWalkSkip
 	}
 	parent := w.next.upper()
@@ -120,82 +315,26 @@ func (w *Walker) Skip() error {
 	return nil
 }
 
-// Path returns the node in the tree referenced by json path
+// Path returns the node in the tree referenced by json path like $.a.b[2]['c.d']
 func (n *Node) Path(path string) *Node {
-	if n == nil || path == "" {
+	if n == nil {
 		return undef
 	}
-	if path[0] != '$' {
+	tokens, err := parsePath(path)
+	if err != nil {
 		return undef
 	}
-	if path == "$" {
-		return n
-	}
 	node := n
-	var mode state
-	var token []rune
-	path = path[1:]
-
-	for _, v := range path {
-		if mode == enter {
-			switch v {
-			case '.':
-				mode = object
-			case '[':
-				mode = array
-			default:
-				return undef
-			}
-			token = nil
-			continue
-		}
-		if v != '.' && v != '[' && v != ']' {
-			token = append(token, v)
-			continue
-		}
-		if mode == object {
-			if v == ']' {
-				return undef
-			}
-			node = node.Key(string(token))
-			if node == undef {
-				return undef
-			}
-			token = nil
-			if v == '[' {
-				mode = array
-			}
-			continue
-		}
-		if mode == array {
-			if !(v == ']' && len(token) > 0) {
-				return undef
-			}
-			idx, err := strconv.Atoi(string(token))
-			if err != nil || idx < 0 {
-				return nil
-			}
-			node = node.Idx(idx)
-			if node == undef {
-				return undef
-			}
-			token = nil
-			mode = enter
-		}
-	}
-	if len(token) > 0 {
-		if mode == array || mode == enter {
+	for _, token := range tokens {
+		if node = token.child(node); node == undef {
 			return undef
 		}
-
-		if mode == object {
-			node = node.Key(string(token))
-		}
 	}
 	return node
 }
 
-// SelfPath returs json path of current node
+// SelfPath returs json path of current node, keys which are empty or contain . [ or ]
+// are written in bracket notation like $['a.b'], before they were written as is
 func (n *Node) SelfPath() string {
 	if n == nil || n == undef {
 		return ""
@@ -208,18 +347,34 @@ func (n *Node) SelfPath() string {
 			ret = "$" + ret
 			break
 		}
-		if parent.IsArray() {
-			ret = "[" + strconv.Itoa(node.idx) + "]" + ret
-		} else if parent.IsObject() {
-			ret = "." + node.key + ret
-		} else {
-			panic("parent is neither array nor object")
-		}
+		ret = node.pathSegment() + ret
 		node = parent
 	}
 	return ret
 }
 
+// pathSegment returns the part of json path addressing node in its parent
+func (n *Node) pathSegment() string {
+	if n.parent.IsArray() {
+		return "[" + strconv.Itoa(n.idx) + "]"
+	}
+	if n.parent.IsObject() {
+		return keySegment(n.key, ".")
+	}
+	panic("parent is neither array nor object")
+}
+
+var keyEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// keySegment returns object key prefixed by separator, bracket notation like ['a.b']
+// is used for empty keys and keys containing separator or brackets
+func keySegment(key, sep string) string {
+	if key != "" && !strings.ContainsAny(key, "[]") && !strings.Contains(key, sep) {
+		return sep + key
+	}
+	return "['" + keyEscaper.Replace(key) + "']"
+}
+
 // Parent returns the parent of node
 func (n *Node) Parent() *Node {
 	if n == nil || n.parent == nil {
@@ -245,6 +400,110 @@ func (n *Node) IsAncestorOf(node *Node) bool {
 	return false
 }
 
+// Root returns the topmost ancestor of node or node itself when it has no parent
+func (n *Node) Root() *Node {
+	if n == nil || n == undef {
+		return undef
+	}
+	for n.parent != nil {
+		n = n.parent
+	}
+	return n
+}
+
+// siblingAt returns the child of node parent shifted by offset from node position
+func (n *Node) siblingAt(offset int) *Node {
+	if n == nil || n.parent == nil {
+		return undef
+	}
+	idx := n.idx + offset
+	if idx < 0 || idx >= len(n.parent.children) {
+		return undef
+	}
+	return n.parent.children[idx]
+}
+
+// NextSibling returns the next child of node parent
+func (n *Node) NextSibling() *Node {
+	return n.siblingAt(1)
+}
+
+// PrevSibling returns the previous child of node parent
+func (n *Node) PrevSibling() *Node {
+	return n.siblingAt(-1)
+}
+
+// Ancestors returns node ancestors starting from the parent up to the root
+func (n *Node) Ancestors() Nodes {
+	ret := make(Nodes, 0)
+	if n == nil {
+		return ret
+	}
+	for node := n.parent; node != nil; node = node.parent {
+		ret = append(ret, node)
+	}
+	return ret
+}
+
+// Descendants returns all node descendants in document order
+func (n *Node) Descendants() Nodes {
+	if n == nil || len(n.children) == 0 {
+		return make(Nodes, 0)
+	}
+	return descendants(n, nil)[1:]
+}
+
+// Closest returns the node itself or its nearest ancestor matched by matcher
+func (n *Node) Closest(matcher NodeMatcher) *Node {
+	if n == nil || n == undef {
+		return undef
+	}
+	for node := n; node != nil; node = node.parent {
+		if matcher.Match(node) {
+			return node
+		}
+	}
+	return undef
+}
+
+// CommonAncestor returns the deepest node which is ancestor of both nodes,
+// a node is considered to be ancestor of itself, so CommonAncestor(a, a) is a
+func CommonAncestor(a, b *Node) *Node {
+	if !a.Exists() || !b.Exists() {
+		return undef
+	}
+	la, lb := a.Level(), b.Level()
+	for ; la > lb; la-- {
+		a = a.parent
+	}
+	for ; lb > la; lb-- {
+		b = b.parent
+	}
+	for a != b {
+		if a.parent == nil {
+			return undef
+		}
+		a, b = a.parent, b.parent
+	}
+	return a
+}
+
+// RelativePath returns json path of to node relative to from node, so from.Path(path)
+// returns to node. Empty string is returned when to is not descendant of from.
+func RelativePath(from, to *Node) string {
+	if !from.Exists() || !to.Exists() {
+		return ""
+	}
+	ret := ""
+	for node := to; node != from; node = node.parent {
+		if node.parent == nil {
+			return ""
+		}
+		ret = node.pathSegment() + ret
+	}
+	return "$" + ret
+}
+
 // Children returns node children
 func (n *Node) Children() []*Node {
 	if n == nil {
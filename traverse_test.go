@@ -21,18 +21,58 @@ func TestPath(t *testing.T) {
 	assertEqual(t, "v1", node.Path("$.k1[0]").value)
 	assertEqual(t, Object, node.Path("$.k1[2]").Type())
 	assertEqual(t, "vv1", node.Path("$.k1[2].kk1").value)
+	assertEqual(t, "foo", node.Path("$['k2']").value)
+	assertEqual(t, "vv1", node.Path("$['k1'][2]['kk1']").value)
+	assertEqual(t, undef, node.Path("$['k2"))
+	assertEqual(t, undef, node.Path("$.k1[x]"))
 }
 
 func TestSelfPath(t *testing.T) {
 	node, err := ParseString(`[20, ["v1", {"k1": "ov1", "k2": {"kk1": true}}]]`)
 	assertParsed(t, node, err)
 	assertEqual(t, "$[1][1].k2.kk1", node.Idx(1).Idx(1).Key("k2").Key("kk1").SelfPath())
+
+	node, err = ParseString(`{"a.b": {"[c]": [{"": 1, "d'e\\": 2}]}}`)
+	assertParsed(t, node, err)
+	leaf := node.Key("a.b").Key("[c]").Idx(0)
+	assertEqual(t, `$['a.b']['[c]'][0]['']`, leaf.Key("").SelfPath())
+	assertEqual(t, `$['a.b']['[c]'][0].d'e\`, leaf.Key(`d'e\`).SelfPath())
+	assertEqual(t, leaf.Key(""), node.Path(leaf.Key("").SelfPath()))
+	assertEqual(t, leaf, node.Path(`$['a.b']['\[c\]'][0]`))
 	node = nil
 	assertEqual(t, "", node.SelfPath())
 	node = undef
 	assertEqual(t, "", node.SelfPath())
 }
 
+func TestSelfPathUnambiguousKeys(t *testing.T) {
+	// keys without dots and brackets keep the dot notation used before bracket notation was added
+	keys := []string{"k", "with space", "it's", `back\slash`, "a-b", "$x", "0", "ключ", "*", "@"}
+	node, err := ParseString(`{"k":{"with space":{"it's":{"back\\slash":{"a-b":{"$x":{"0":{"ключ":{"*":{"@":[[1]]}}}}}}}}}}`)
+	assertParsed(t, node, err)
+	path := "$"
+	leaf := node
+	for _, key := range keys {
+		path += "." + key
+		leaf = leaf.Key(key)
+		assertEqual(t, path, leaf.SelfPath())
+		assertEqual(t, leaf, node.Path(path))
+	}
+	path += "[0][0]"
+	assertEqual(t, path, leaf.Idx(0).Idx(0).SelfPath())
+	assertEqual(t, leaf.Idx(0).Idx(0), node.Path(path))
+
+	walker, err := NewWalker(node, 0)
+	assertNil(t, err)
+	var walked string
+	for n, state := walker.Next(); state != WalkDone; n, state = walker.Next() {
+		if n.IsScalar() {
+			walked = walker.Path()
+		}
+	}
+	assertEqual(t, path, walked)
+}
+
 func TestParent(t *testing.T) {
 	node, err := ParseString(`[20]`)
 	assertParsed(t, node, err)
@@ -55,6 +95,77 @@ func TestIsAncestorOf(t *testing.T) {
 	assertEqual(t, true, root.IsAncestorOf(x))
 }
 
+func TestSiblingsAndRoot(t *testing.T) {
+	root, err := ParseString(`{"a":[1,2,3],"b":{"c":true}}`)
+	assertParsed(t, root, err)
+	two := root.Path("$.a[1]")
+	assertEqual(t, root, two.Root())
+	assertEqual(t, root, root.Root())
+	assertEqual(t, "$.a[2]", two.NextSibling().SelfPath())
+	assertEqual(t, "$.a[0]", two.PrevSibling().SelfPath())
+	assertEqual(t, undef, two.NextSibling().NextSibling())
+	assertEqual(t, undef, root.Path("$.a[0]").PrevSibling())
+	assertEqual(t, "$.b", root.Key("a").NextSibling().SelfPath())
+	assertEqual(t, undef, root.NextSibling())
+	var node *Node
+	assertEqual(t, undef, node.Root())
+	assertEqual(t, undef, node.NextSibling().PrevSibling())
+	assertEqual(t, undef, undef.Root())
+}
+
+func TestAncestorsAndDescendants(t *testing.T) {
+	root, err := ParseString(`{"a":[1,{"x":"y"}],"b":{"c":true}}`)
+	assertParsed(t, root, err)
+	assertEqual(t, []string{"$.a[1]", "$.a", "$"}, paths(root.Path("$.a[1].x").Ancestors()))
+	assertEqual(t, 0, len(root.Ancestors()))
+	assertEqual(t, []string{"$.a", "$.a[0]", "$.a[1]", "$.a[1].x", "$.b", "$.b.c"}, paths(root.Descendants()))
+	assertEqual(t, 0, len(root.Path("$.b.c").Descendants()))
+	var node *Node
+	assertEqual(t, 0, len(node.Ancestors()))
+	assertEqual(t, 0, len(node.Descendants()))
+}
+
+func TestClosest(t *testing.T) {
+	root, err := ParseString(`{"a":[1,{"x":{"y":2}}]}`)
+	assertParsed(t, root, err)
+	y := root.Path("$.a[1].x.y")
+	assertEqual(t, "$.a", y.Closest(TypeIs(Array)).SelfPath())
+	assertEqual(t, "$.a[1].x.y", y.Closest(TypeIs(Number)).SelfPath())
+	assertEqual(t, "$.a[1]", y.Closest(HasKeys("x")).SelfPath())
+	assertEqual(t, undef, y.Closest(TypeIs(String)))
+	assertEqual(t, undef, y.Key("z").Closest(TypeIs(Object)))
+}
+
+func TestCommonAncestor(t *testing.T) {
+	root, err := ParseString(`{"a":[1,{"x":{"y":2}}],"b":{"c":true}}`)
+	assertParsed(t, root, err)
+	y := root.Path("$.a[1].x.y")
+	assertEqual(t, root, CommonAncestor(y, root.Path("$.b.c")))
+	assertEqual(t, root.Key("a"), CommonAncestor(root.Path("$.a[0]"), y))
+	assertEqual(t, root.Path("$.a[1]"), CommonAncestor(y, root.Path("$.a[1]")))
+	assertEqual(t, y, CommonAncestor(y, y))
+	other, err := ParseString(`{"a":1}`)
+	assertParsed(t, other, err)
+	assertEqual(t, undef, CommonAncestor(y, other.Key("a")))
+	assertEqual(t, undef, CommonAncestor(nil, y))
+	assertEqual(t, undef, CommonAncestor(y, root.Key("z")))
+}
+
+func TestRelativePath(t *testing.T) {
+	root, err := ParseString(`{"a":[1,{"x":{"y":2}}],"b":{"c":true}}`)
+	assertParsed(t, root, err)
+	a := root.Key("a")
+	y := root.Path("$.a[1].x.y")
+	path := RelativePath(a, y)
+	assertEqual(t, "$[1].x.y", path)
+	assertEqual(t, y, a.Path(path))
+	assertEqual(t, "$", RelativePath(a, a))
+	assertEqual(t, y.SelfPath(), RelativePath(root, y))
+	assertEqual(t, "", RelativePath(y, a))
+	assertEqual(t, "", RelativePath(root.Key("b"), y))
+	assertEqual(t, "", RelativePath(nil, y))
+}
+
 func TestChildren(t *testing.T) {
 	node, err := ParseString(`[20, 21]`)
 	assertParsed(t, node, err)
@@ -182,6 +293,87 @@ func TestWalkerSkip(t *testing.T) {
 	assertEqual(t, WalkPass, state)
 }
 
+// walkTrace returns paths and states of all walked nodes
+func walkTrace(t *testing.T, root *Node, deepLimit int, opt *WalkOptions, skip string) []string {
+	t.Helper()
+	walker, err := NewWalker(root, deepLimit, opt)
+	assertNil(t, err)
+	var ret []string
+	for {
+		node, state := walker.Next()
+		if state == WalkDone {
+			return ret
+		}
+		ret = append(ret, node.SelfPath()+[]string{"", ">", "<"}[state])
+		if state == WalkEnter && node.SelfPath() == skip {
+			assertNil(t, walker.Skip())
+		}
+	}
+}
+
+func TestWalkerOrders(t *testing.T) {
+	root, err := ParseString(`{"a":{"b":1,"c":[2,3]},"d":[],"e":4}`)
+	assertParsed(t, root, err)
+
+	assertEqual(t, []string{"$>", "$.a>", "$.a.b", "$.a.c>", "$.a.c[0]", "$.a.c[1]", "$.a.c<", "$.a<", "$.d>", "$.d<", "$.e", "$<"},
+		walkTrace(t, root, 0, nil, ""))
+	assertEqual(t, []string{"$>", "$.e", "$.d>", "$.d<", "$.a>", "$.a.c>", "$.a.c[1]", "$.a.c[0]", "$.a.c<", "$.a.b", "$.a<", "$<"},
+		walkTrace(t, root, 0, &WalkOptions{Reverse: true}, ""))
+	assertEqual(t, []string{"$>", "$.e", "$.d>", "$.d<", "$.a>", "$.a<", "$<"},
+		walkTrace(t, root, 0, &WalkOptions{Reverse: true}, "$.a"))
+
+	assertEqual(t, []string{"$>", "$.a>", "$.d>", "$.e", "$.a.b", "$.a.c>", "$.a.c[0]", "$.a.c[1]"},
+		walkTrace(t, root, 0, &WalkOptions{Order: WalkBreadthFirst}, ""))
+	assertEqual(t, []string{"$>", "$.e", "$.d>", "$.a>", "$.a.c>", "$.a.b", "$.a.c[1]", "$.a.c[0]"},
+		walkTrace(t, root, 0, &WalkOptions{Order: WalkBreadthFirst, Reverse: true}, ""))
+	assertEqual(t, []string{"$>", "$.a>", "$.d>", "$.e", "$.a.b", "$.a.c>"},
+		walkTrace(t, root, 2, &WalkOptions{Order: WalkBreadthFirst}, ""))
+	assertEqual(t, []string{"$>", "$.a>", "$.d>", "$.e"},
+		walkTrace(t, root, 0, &WalkOptions{Order: WalkBreadthFirst}, "$.a"))
+
+	assertEqual(t, []string{"$.a.b", "$.a.c[0]", "$.a.c[1]", "$.a.c", "$.a", "$.d", "$.e", "$"},
+		walkTrace(t, root, 0, &WalkOptions{Order: WalkPostOrder}, ""))
+	assertEqual(t, []string{"$.e", "$.d", "$.a.c", "$.a.b", "$.a", "$"},
+		walkTrace(t, root, 2, &WalkOptions{Order: WalkPostOrder, Reverse: true}, ""))
+
+	assertEqual(t, []string{"$.a.b", "$.a.c[0]", "$.a.c[1]", "$.d", "$.e"},
+		walkTrace(t, root, 0, &WalkOptions{Order: WalkLeaves}, ""))
+	assertEqual(t, []string{"$.e", "$.d", "$.a"},
+		walkTrace(t, root, 1, &WalkOptions{Order: WalkLeaves, Reverse: true}, ""))
+
+	scalar := NewInt(1)
+	for _, order := range []WalkOrder{WalkDepthFirst, WalkBreadthFirst, WalkPostOrder, WalkLeaves} {
+		assertEqual(t, []string{"$"}, walkTrace(t, scalar, 0, &WalkOptions{Order: order}, ""))
+	}
+
+	walker, err := NewWalker(root, 0, &WalkOptions{Order: WalkPostOrder})
+	assertNil(t, err)
+	walker.Next()
+	assertEqual(t, ErrWalkSkip, walker.Skip())
+	walker, err = NewWalker(root, 0, &WalkOptions{Order: WalkBreadthFirst})
+	assertNil(t, err)
+	assertEqual(t, ErrWalkSkip, walker.Skip())
+}
+
+func TestSearchOrder(t *testing.T) {
+	root, err := ParseString(`{"a":{"id":1,"b":{"id":2}},"id":3,"c":[{"id":4}]}`)
+	assertParsed(t, root, err)
+	search := func(opt *SearchOptions) string {
+		ns, err := root.SearchKey("id", opt)
+		assertNil(t, err)
+		return ns.ToArray().Stringify()
+	}
+	assertEqual(t, `[1,2,3,4]`, search(&SearchOptions{}))
+	assertEqual(t, `[3,1,2,4]`, search(&SearchOptions{Order: WalkBreadthFirst}))
+	assertEqual(t, `[3,1]`, search(&SearchOptions{Order: WalkBreadthFirst, MaxResults: 2}))
+	assertEqual(t, `[4,3,2,1]`, search(&SearchOptions{Reverse: true}))
+	assertEqual(t, `[4,3,2,1]`, search(&SearchOptions{Order: WalkPostOrder, Reverse: true}))
+
+	ns, err := root.SearchParallel(&keyMatcher{"id"}, &SearchOptions{Order: WalkBreadthFirst}, 4)
+	assertNil(t, err)
+	assertEqual(t, `[3,1,2,4]`, ns.ToArray().Stringify())
+}
+
 func TestWalkerWithDeepLevel(t *testing.T) {
 	root, err := ParseString(`{"ka":"va", "kb":{"kkb1":"kkv1", "kkb2":[1,2]}, "kkb3":[]}`)
 	assertParsed(t, root, err)
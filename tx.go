@@ -0,0 +1,310 @@
+package xtjson
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+var (
+	ErrTxDone       = errors.New("transaction is already finished")
+	ErrTxInProgress = errors.New("transaction is in progress")
+	ErrHistoryEmpty = errors.New("history is empty")
+)
+
+// operation is the recorded mutation with the data required to revert and repeat it
+type operation struct {
+	op     mutationOp
+	parent *Node
+	old    *Node
+	node   *Node
+	idx    int    // position of changed child
+	key    string // key of changed child, new key of renamed one
+	oldKey string
+	before []*Node // children order before reorder
+	after  []*Node // children order after reorder
+	path   string  // json pointer of changed child or reordered parent
+	from   string  // json pointer of renamed child before rename
+	value  *Node   // copy of added or replacing node
+}
+
+func newOperation(root *Node, m mutation) operation {
+	ret := operation{op: m.op, parent: m.parent, old: m.old, node: m.node, oldKey: m.oldKey}
+	prefix := relativePointer(root, m.parent)
+	switch m.op {
+	case opAdd, opReplace, opRename:
+		ret.idx, ret.key = m.node.idx, m.node.key
+		ret.path = prefix + "/" + m.node.pointerToken()
+		if m.op == opRename {
+			ret.from = prefix + "/" + pointerEscaper.Replace(m.oldKey)
+		} else {
+			// the copy keeps the value for the patch as later mutations can change the node
+			ret.value = detach(m.node)
+		}
+	case opRemove:
+		// removed node is unlinked already, its idx and key still point to the former place
+		ret.idx, ret.key = m.old.idx, m.old.key
+		ret.path = prefix + "/" + pointerEscaper.Replace(m.old.key)
+		if m.parent.IsArray() {
+			ret.path = prefix + "/" + strconv.Itoa(m.old.idx)
+		}
+	case opReorder:
+		ret.before = m.before
+		ret.after = slices.Clone(m.parent.children)
+		ret.path = prefix
+	}
+	return ret
+}
+
+// undo reverts the operation
+func (op *operation) undo() error {
+	p := op.parent
+	switch op.op {
+	case opAdd:
+		if p.IsArray() {
+			return p.RemoveIdx(op.idx)
+		}
+		return p.RemoveKey(op.key)
+	case opReplace:
+		if p.IsArray() {
+			return p.ReplaceIdx(op.idx, op.old)
+		}
+		return p.Set(op.key, op.old)
+	case opRemove:
+		if p.IsArray() {
+			return p.InsertIdx(op.idx, op.old)
+		}
+		return p.InsertKeyAt(op.idx, op.key, op.old)
+	case opReorder:
+		return p.setOrder(op.before)
+	case opRename:
+		return p.RenameKey(op.key, op.oldKey)
+	}
+	return nil
+}
+
+// redo repeats reverted operation
+func (op *operation) redo() error {
+	p := op.parent
+	switch op.op {
+	case opAdd:
+		if p.IsArray() {
+			return p.InsertIdx(op.idx, op.node)
+		}
+		return p.InsertKeyAt(op.idx, op.key, op.node)
+	case opReplace:
+		if p.IsArray() {
+			return p.ReplaceIdx(op.idx, op.node)
+		}
+		return p.Set(op.key, op.node)
+	case opRemove:
+		if p.IsArray() {
+			return p.RemoveIdx(op.idx)
+		}
+		return p.RemoveKey(op.key)
+	case opReorder:
+		return p.setOrder(op.after)
+	case opRename:
+		return p.RenameKey(op.oldKey, op.key)
+	}
+	return nil
+}
+
+// setOrder puts the same children in another order
+func (n *Node) setOrder(order []*Node) error {
+	if len(order) != len(n.children) {
+		return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "children were changed")
+	}
+	for _, child := range order {
+		if child.parent != n {
+			return fmt.Errorf("%w %s", ErrInvalidNodeForOperation, "children were changed")
+		}
+	}
+	before := n.observedOrder()
+	n.children = slices.Clone(order)
+	if n.IsObject() {
+		n.renumberKeys(0, len(n.children))
+	} else {
+		n.renumber(0, len(n.children))
+	}
+	notify(mutation{op: opReorder, parent: n, before: before})
+	return nil
+}
+
+// patchEntries appends RFC 6902 operations equal to the recorded one
+func (op *operation) patchEntries(ret *Node) {
+	entry := func(name, from, path string, value *Node) {
+		node := NewObject()
+		_ = node.SetString("op", name)
+		if from != "" || name == "move" {
+			_ = node.SetString("from", from)
+		}
+		_ = node.SetString("path", path)
+		if value != nil {
+			_ = node.Set("value", value.Copy())
+		}
+		_ = ret.Append(node)
+	}
+	switch op.op {
+	case opAdd:
+		entry("add", "", op.path, op.value)
+	case opReplace:
+		entry("replace", "", op.path, op.value)
+	case opRemove:
+		entry("remove", "", op.path, nil)
+	case opRename:
+		entry("move", op.from, op.path, nil)
+	case opReorder:
+		// object keys order is not significant in json, arrays are reordered by moves
+		if !op.parent.IsArray() {
+			return
+		}
+		work := slices.Clone(op.before)
+		for i, node := range op.after {
+			j := slices.Index(work, node)
+			if j == i {
+				continue
+			}
+			entry("move", op.path+"/"+strconv.Itoa(j), op.path+"/"+strconv.Itoa(i), nil)
+			work = slices.Insert(slices.Delete(work, j, j+1), i, node)
+		}
+	}
+}
+
+func patchOf(ops []operation) *Node {
+	ret := NewArray()
+	for i := range ops {
+		ops[i].patchEntries(ret)
+	}
+	return ret
+}
+
+// recorder collects mutations made under the root
+type recorder struct {
+	root     *Node
+	sub      *observer
+	ops      []operation
+	paused   bool
+	onRecord func()
+}
+
+func newRecorder(root *Node) *recorder {
+	r := &recorder{root: root}
+	r.sub = observe(root, r.record)
+	return r
+}
+
+func (r *recorder) record(m mutation) {
+	if r.paused {
+		return
+	}
+	r.ops = append(r.ops, newOperation(r.root, m))
+	if r.onRecord != nil {
+		r.onRecord()
+	}
+}
+
+// take returns recorded operations and starts recording from scratch
+func (r *recorder) take() []operation {
+	ret := r.ops
+	r.ops = nil
+	return ret
+}
+
+// revert undoes operations in reverse order without recording them
+func (r *recorder) revert(ops []operation) error {
+	r.paused = true
+	defer func() { r.paused = false }()
+	for i := len(ops) - 1; i >= 0; i-- {
+		if err := ops[i].undo(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replay repeats operations without recording them
+func (r *recorder) replay(ops []operation) error {
+	r.paused = true
+	defer func() { r.paused = false }()
+	for i := range ops {
+		if err := ops[i].redo(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tx records mutations made under the node to commit or revert them together.
+// Mutations are recorded by the goroutine making them, so tree must be modified by
+// one goroutine at a time. Changes of nodes detached from the tree are not recorded.
+// Every recorded add or replace copies the added subtree for Patch, so the cost of
+// such mutation is proportional to the size of added value while transaction is active.
+type Tx struct {
+	rec     *recorder
+	history *History
+	ops     []operation
+	done    bool
+	err     error
+}
+
+// Begin starts transaction recording all mutations made under receiver
+func (n *Node) Begin() *Tx {
+	if n == nil || n == undef {
+		return &Tx{err: ErrNilNode}
+	}
+	return &Tx{rec: newRecorder(n)}
+}
+
+func (tx *Tx) finish() error {
+	if tx.err != nil {
+		return tx.err
+	}
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	if tx.history != nil {
+		tx.history.tx = nil
+		tx.ops = tx.rec.take()
+		return nil
+	}
+	tx.rec.sub.stop()
+	tx.ops = tx.rec.ops
+	return nil
+}
+
+// Commit stops recording and keeps the changes, when transaction is started
+// by History the changes become one undo step
+func (tx *Tx) Commit() error {
+	if err := tx.finish(); err != nil {
+		return err
+	}
+	if tx.history != nil {
+		tx.history.push(tx.ops)
+	}
+	return nil
+}
+
+// Rollback reverts all mutations recorded by transaction
+func (tx *Tx) Rollback() error {
+	if err := tx.finish(); err != nil {
+		return err
+	}
+	ops := tx.ops
+	tx.ops = nil
+	return tx.rec.revert(ops)
+}
+
+// Patch returns recorded mutations as RFC 6902 json patch with paths relative to
+// transaction root. Reordering of object keys is not included as it is not significant in json.
+func (tx *Tx) Patch() *Node {
+	if tx.done {
+		return patchOf(tx.ops)
+	}
+	if tx.rec == nil {
+		return NewArray()
+	}
+	return patchOf(tx.rec.ops)
+}
@@ -0,0 +1,226 @@
+package xtjson
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// applyPatch applies RFC 6902 patch produced by Tx to the tree
+func applyPatch(t *testing.T, root *Node, patch *Node) {
+	t.Helper()
+	split := func(ptr string) (*Node, string) {
+		i := strings.LastIndex(ptr, "/")
+		parent, err := resolvePointer(root, ptr[:i])
+		assertNil(t, err)
+		return parent, pointerUnescaper.Replace(ptr[i+1:])
+	}
+	add := func(ptr string, node *Node) {
+		parent, token := split(ptr)
+		if parent.IsArray() {
+			idx, err := strconv.Atoi(token)
+			assertNil(t, err)
+			assertNil(t, parent.InsertIdx(idx, node))
+			return
+		}
+		assertNil(t, parent.Set(token, node))
+	}
+	for _, entry := range patch.Children() {
+		path := entry.Key("path").value.(string)
+		switch entry.Key("op").value.(string) {
+		case "add":
+			add(path, entry.Key("value").Copy())
+		case "replace":
+			node, err := resolvePointer(root, path)
+			assertNil(t, err)
+			assertNil(t, node.Replace(entry.Key("value").Copy()))
+		case "remove":
+			node, err := resolvePointer(root, path)
+			assertNil(t, err)
+			assertNil(t, node.Remove())
+		case "move":
+			node, err := resolvePointer(root, entry.Key("from").value.(string))
+			assertNil(t, err)
+			assertNil(t, node.Remove())
+			add(path, node)
+		default:
+			t.Fatalf("unexpected patch op %s", entry.Stringify())
+		}
+	}
+}
+
+const txJson = `{"name":"a","items":[1,2,3],"meta":{"x~y":true,"c/d":null}}`
+
+func TestTxRollback(t *testing.T) {
+	root, err := ParseString(txJson)
+	assertParsed(t, root, err)
+	tx := root.Begin()
+	items := root.Key("items")
+	assertNil(t, root.SetString("name", "b"))
+	assertNil(t, items.AppendInt(4))
+	assertNil(t, items.RemoveIdx(0))
+	assertNil(t, items.Prepend(NewNull()))
+	assertNil(t, items.SortArrayBy("$", &SortOptions{Descending: true}))
+	assertNil(t, root.Key("meta").RenameKey("x~y", "z"))
+	assertNil(t, root.Key("meta").SortKeys())
+	assertNil(t, root.RemoveKey("name"))
+	assertNil(t, items.Idx(0).ReplaceByString("s"))
+	_, err = items.Splice(1, 2, NewBool(false))
+	assertNil(t, err)
+	assertNil(t, root.InsertKeyAt(0, "first", NewObject()))
+	assertNil(t, root.MoveKey("items", 0))
+	assertEqual(t, `{"items":["s",false,null],"first":{},"meta":{"c/d":null,"z":true}}`, root.Stringify())
+
+	assertNil(t, tx.Rollback())
+	assertEqual(t, txJson, root.Stringify())
+	assertNil(t, root.Validate())
+	if !errors.Is(tx.Rollback(), ErrTxDone) {
+		t.Fatal("expected error ErrTxDone")
+	}
+	if !errors.Is(tx.Commit(), ErrTxDone) {
+		t.Fatal("expected error ErrTxDone")
+	}
+	// changes after the end of transaction are not recorded
+	assertNil(t, root.SetInt("n", 1))
+	assertEqual(t, `[]`, tx.Patch().Stringify())
+}
+
+func TestTxFailedStep(t *testing.T) {
+	root, err := ParseString(`{"a":1,"b":[]}`)
+	assertParsed(t, root, err)
+	update := func() error {
+		if err := root.SetInt("a", 2); err != nil {
+			return err
+		}
+		if err := root.Key("b").AppendInt(1); err != nil {
+			return err
+		}
+		return root.Key("a").AppendInt(3)
+	}
+	tx := root.Begin()
+	if err = update(); err != nil {
+		assertNil(t, tx.Rollback())
+	} else {
+		assertNil(t, tx.Commit())
+	}
+	if !errors.Is(err, ErrInvalidNodeForOperation) {
+		t.Fatal("expected error ErrInvalidNodeForOperation")
+	}
+	assertEqual(t, `{"a":1,"b":[]}`, root.Stringify())
+
+	var node *Node
+	if !errors.Is(node.Begin().Commit(), ErrNilNode) {
+		t.Fatal("expected error ErrNilNode")
+	}
+}
+
+func TestTxPatch(t *testing.T) {
+	root, err := ParseString(txJson)
+	assertParsed(t, root, err)
+	target, err := ParseString(txJson)
+	assertParsed(t, target, err)
+
+	tx := root.Begin()
+	items := root.Key("items")
+	assertNil(t, root.SetString("name", "b"))
+	assertNil(t, items.InsertInt(1, 9))
+	assertNil(t, items.RemoveIdx(0))
+	assertNil(t, items.SortArrayBy("$", &SortOptions{Descending: true}))
+	assertNil(t, items.MoveIdx(0, 2))
+	assertNil(t, root.Key("meta").RenameKey("x~y", "a/b"))
+	assertNil(t, root.Key("meta").SortKeys())
+	assertNil(t, root.Path("$.meta.c/d").ReplaceByInt(5))
+	added := NewObject()
+	assertNil(t, root.Set("new", added))
+	assertNil(t, added.SetBool("t", true))
+	assertNil(t, tx.Commit())
+
+	patch := tx.Patch()
+	assertEqual(t, `[{"op":"replace","path":"/name","value":"b"},{"op":"add","path":"/items/1","value":9},`+
+		`{"op":"remove","path":"/items/0"},{"op":"move","from":"/items/2","path":"/items/1"},`+
+		`{"op":"move","from":"/items/1","path":"/items/0"},{"op":"move","from":"/items/2","path":"/items/1"},`+
+		`{"op":"move","from":"/meta/x~0y","path":"/meta/a~1b"},{"op":"replace","path":"/meta/c~1d","value":5},`+
+		`{"op":"add","path":"/new","value":{}},{"op":"add","path":"/new/t","value":true}]`, patch.Stringify())
+	applyPatch(t, target, patch)
+	assertEqual(t, true, equalNodes(root, target))
+}
+
+func TestHistory(t *testing.T) {
+	root, err := ParseString(`{"a":[1]}`)
+	assertParsed(t, root, err)
+	h, err := NewHistory(root)
+	assertNil(t, err)
+	defer h.Close()
+	assertEqual(t, false, h.CanUndo())
+	if !errors.Is(h.Undo(), ErrHistoryEmpty) {
+		t.Fatal("expected error ErrHistoryEmpty")
+	}
+
+	a := root.Key("a")
+	assertNil(t, a.AppendInt(2))
+	tx := h.Begin()
+	if !errors.Is(h.Begin().Commit(), ErrTxInProgress) {
+		t.Fatal("expected error ErrTxInProgress")
+	}
+	if !errors.Is(h.Undo(), ErrTxInProgress) {
+		t.Fatal("expected error ErrTxInProgress")
+	}
+	assertNil(t, a.AppendInt(3))
+	assertNil(t, root.SetString("b", "x"))
+	assertNil(t, tx.Commit())
+	tx = h.Begin()
+	assertNil(t, root.SetString("c", "y"))
+	assertNil(t, tx.Rollback())
+	assertNil(t, a.Swap(0, 2))
+	assertEqual(t, `{"a":[3,2,1],"b":"x"}`, root.Stringify())
+
+	assertNil(t, h.Undo())
+	assertEqual(t, `{"a":[1,2,3],"b":"x"}`, root.Stringify())
+	assertNil(t, h.Undo())
+	assertEqual(t, `{"a":[1,2]}`, root.Stringify())
+	assertEqual(t, true, h.CanRedo())
+	assertNil(t, h.Redo())
+	assertEqual(t, `{"a":[1,2,3],"b":"x"}`, root.Stringify())
+	assertNil(t, h.Undo())
+	assertNil(t, h.Undo())
+	assertEqual(t, `{"a":[1]}`, root.Stringify())
+	assertEqual(t, false, h.CanUndo())
+	assertNil(t, h.Redo())
+	assertEqual(t, `[{"op":"add","path":"/a/1","value":2}]`, h.Patch().Stringify())
+
+	// new change clears redo steps
+	assertNil(t, root.SetNull("d"))
+	assertEqual(t, false, h.CanRedo())
+	if !errors.Is(h.Redo(), ErrHistoryEmpty) {
+		t.Fatal("expected error ErrHistoryEmpty")
+	}
+	assertNil(t, h.Undo())
+	assertNil(t, h.Undo())
+	assertEqual(t, `{"a":[1]}`, root.Stringify())
+	assertNil(t, root.Validate())
+
+	_, err = NewHistory(nil)
+	if !errors.Is(err, ErrNilNode) {
+		t.Fatal("expected error ErrNilNode")
+	}
+}
+
+func TestHistoryObservers(t *testing.T) {
+	root, err := ParseString(`{"items":[{"id":1}]}`)
+	assertParsed(t, root, err)
+	idx := BuildIndex(root, &IndexOptions{Live: true})
+	defer idx.Close()
+	h, err := NewHistory(root)
+	assertNil(t, err)
+	defer h.Close()
+
+	item := NewObject()
+	assertNil(t, item.SetInt("id", 2))
+	assertNil(t, root.Key("items").Append(item))
+	assertEqual(t, 2, len(idx.Lookup("id")))
+	assertNil(t, h.Undo())
+	assertEqual(t, 1, len(idx.Lookup("id")))
+	assertNil(t, h.Redo())
+	assertEqual(t, []string{"$.items[0].id", "$.items[1].id"}, paths(idx.Lookup("id")))
+}